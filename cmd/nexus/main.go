@@ -2,16 +2,23 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/nexusapi/nexus/pkg/ai"
+	"github.com/nexusapi/nexus/pkg/api"
 	"github.com/nexusapi/nexus/pkg/collab"
 	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
 	"github.com/nexusapi/nexus/pkg/mock"
 	"github.com/nexusapi/nexus/pkg/storage"
 	"github.com/nexusapi/nexus/pkg/tui"
@@ -26,10 +33,20 @@ func main() {
 	command := os.Args[1]
 
 	switch command {
+	case "init":
+		runInit()
 	case "tui":
 		runTUI()
 	case "run":
 		runCLI()
+	case "list":
+		runList()
+	case "search":
+		runSearch()
+	case "history":
+		runHistory()
+	case "diff":
+		runDiff()
 	case "load":
 		runLoadTest()
 	case "mock":
@@ -50,10 +67,27 @@ func main() {
 func printUsage() {
 	fmt.Println("Usage: nexus <command> [args]")
 	fmt.Println("\nCommands:")
+	fmt.Println("  init [dir] [--git]            - Set up a workspace, optionally under git")
 	fmt.Println("  tui <collection>              - Start terminal UI")
 	fmt.Println("  run <collection>              - Run collection from CLI")
-	fmt.Println("  load <collection>             - Run load test")
-	fmt.Println("  mock                          - Start mock server")
+	fmt.Println("  list                          - List the collections in the workspace")
+	fmt.Println("  search <query>                - Fuzzy-search the requests of every collection")
+	fmt.Println("  history <collection> [-n 20]  - List the commits that changed a collection")
+	fmt.Println("  diff <collection> [rev1] [rev2] - Show request-level changes (default: HEAD against the working copy)")
+	fmt.Println("  load <collection> [--vus n] [--duration d | --iterations n] - Run load test")
+	fmt.Println("  load <collection> --rate n --duration d - Run an open-model test at a fixed arrival rate")
+	fmt.Println("  load <collection> --tui [flags] - Run load test with a live dashboard")
+	fmt.Println("  load <collection> --report out.html,out.json - Also write an HTML report and a JSON summary")
+	fmt.Println("  load compare <base.json> <current.json> - Highlight regressions between two runs")
+	fmt.Println("  load coordinate <collection> --agents n [flags] - Split a load test across agents")
+	fmt.Println("  load agent --coordinator <url> - Generate load for a coordinator")
+	fmt.Println("  mock [port] [--config <file>] - Start mock server")
+	fmt.Println("  mock [port] --openapi <spec>  - Mock every operation in an OpenAPI 3 spec")
+	fmt.Println("  mock [port] --record --upstream <url> - Proxy and record traffic")
+	fmt.Println("  mock [port] --replay          - Serve recorded traffic")
+	fmt.Println("  mock [port] --admin-addr <addr> - Also serve the mock admin API on addr")
+	fmt.Println("  mock [port] --tls             - Serve HTTPS/HTTP2 with a self-signed certificate")
+	fmt.Println("  mock [port] --instances <file> - Run several named mock instances")
 	fmt.Println("  collab                        - Start collaboration server")
 	fmt.Println("\nAI Commands:")
 	fmt.Println("  ai generate-body <schema>     - Generate request body from schema")
@@ -66,6 +100,25 @@ func printUsage() {
 	fmt.Println("  --api-key <key>               - OpenAI API key (or use OPENAI_API_KEY env)")
 }
 
+func runInit() {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	git := fs.Bool("git", false, "also run git init unless the directory is already in a git work tree")
+	dir, args := ".", os.Args[2:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		dir, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+
+	if err := storage.Init(dir, *git); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("✅ Initialized Nexus workspace in %s\n", dir)
+	fmt.Printf("   %s chooses which files hold collections; .gitignore lists local state\n", storage.WorkspaceFile)
+	if *git {
+		fmt.Println("   Collections are versioned with git")
+	}
+}
+
 func runTUI() {
 	if len(os.Args) < 3 {
 		fmt.Println("Usage: nexus tui <collection>")
@@ -75,12 +128,17 @@ func runTUI() {
 	collectionPath := os.Args[2]
 	env := getEnv()
 
-	repo, err := storage.NewRepository(".")
-	if err != nil {
-		log.Fatal(err)
+	var coll *collection.Collection
+	var err error
+	if _, statErr := os.Stat(collectionPath); statErr == nil {
+		// A file outside the workspace is opened as it is.
+		coll, err = collection.NewParser().ParseFile(collectionPath)
+	} else {
+		var repo *storage.Repository
+		if repo, err = storage.NewRepository("."); err == nil {
+			coll, err = repo.LoadCollection(collectionPath)
+		}
 	}
-
-	coll, err := repo.LoadCollection(collectionPath)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -141,16 +199,312 @@ func runCLI() {
 	}
 }
 
-func runLoadTest() {
+func runList() {
+	repo, err := storage.NewRepository(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+	collections, err := repo.Index()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, c := range collections {
+		fmt.Printf("%-40s %-24s %d requests\n", c.Path, c.Name, len(c.Requests))
+	}
+}
+
+func runSearch() {
 	if len(os.Args) < 3 {
+		fmt.Println("Usage: nexus search <query>")
+		os.Exit(1)
+	}
+
+	repo, err := storage.NewRepository(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+	results, err := repo.Search(strings.Join(os.Args[2:], " "))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(results) == 0 {
+		fmt.Println("No matching requests")
+		os.Exit(1)
+	}
+	for _, r := range results {
+		fmt.Printf("%-32s %-7s %-40s %s\n", r.Request.Name, r.Request.Method, r.Request.URL, r.Collection)
+	}
+}
+
+func runHistory() {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	n := fs.Int("n", 20, "number of commits to show")
+	if len(os.Args) < 3 || strings.HasPrefix(os.Args[2], "-") {
+		fmt.Println("Usage: nexus history <collection> [-n 20]")
+		os.Exit(1)
+	}
+	name := os.Args[2]
+	fs.Parse(os.Args[3:])
+
+	repo, err := storage.NewRepository(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+	commits, err := repo.CollectionHistory(name, *n)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(commits) == 0 {
+		fmt.Printf("No commits touch %s\n", name)
+		return
+	}
+	for _, c := range commits {
+		fmt.Printf("%.8s  %s  %-20s %s\n", c.Hash, c.Date.Format("2006-01-02 15:04"), c.Author, c.Message)
+	}
+}
+
+func runDiff() {
+	args := os.Args[2:]
+	if len(args) < 1 || len(args) > 3 {
+		fmt.Println("Usage: nexus diff <collection> [rev1] [rev2]")
+		fmt.Println("  With no revisions, compares HEAD with the working copy; with one, that revision with the working copy.")
+		os.Exit(1)
+	}
+	from, to := "HEAD", ""
+	if len(args) > 1 {
+		from = args[1]
+	}
+	if len(args) > 2 {
+		to = args[2]
+	}
+
+	repo, err := storage.NewRepository(".")
+	if err != nil {
+		log.Fatal(err)
+	}
+	diff, err := repo.DiffCollection(args[0], from, to)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(diff)
+}
+
+func runLoadTest() {
+	if len(os.Args) > 2 && os.Args[2] == "agent" {
+		runLoadAgent()
+		return
+	}
+	if len(os.Args) > 2 && os.Args[2] == "compare" {
+		runLoadCompare()
+		return
+	}
+	args := os.Args[2:]
+	coordinate := len(args) > 0 && args[0] == "coordinate"
+	if coordinate {
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("load", flag.ExitOnError)
+	vus := fs.Int("vus", 10, "number of concurrent virtual users")
+	duration := fs.Duration("duration", 0, "how long to run, e.g. 30s (default: until --iterations are done)")
+	iterations := fs.Int("iterations", 0, "total scenario iterations across all virtual users")
+	rampUp := fs.Duration("ramp-up", 0, "spread virtual user start times over this period")
+	rampDown := fs.Duration("ramp-down", 0, "stop virtual users gradually over the end of --duration")
+	executor := fs.String("executor", "", "constant-vus, ramping-vus, constant-arrival-rate or ramping-arrival-rate (default: from the other flags)")
+	stages := fs.String("stages", "", "comma-separated duration:target stages, e.g. 30s:20,1m:20,30s:0 (VUs, or the rate for ramping-arrival-rate)")
+	rate := fs.Int("rate", 0, "iterations to start per --time-unit (arrival-rate executors)")
+	timeUnit := fs.Duration("time-unit", time.Second, "period --rate and arrival-rate stage targets are measured over")
+	preAllocated := fs.Int("pre-allocated-vus", 0, "VUs started up front for arrival-rate executors (default: --vus)")
+	maxVUs := fs.Int("max-vus", 0, "most VUs an arrival-rate executor may grow to (default: --pre-allocated-vus)")
+	requestNames := fs.String("request", "", "comma-separated requests to run in order (default: the whole collection)")
+	folders := fs.String("folder", "", "comma-separated folders, each run as its own scenario with an equal share of VUs")
+	thinkTime := fs.Duration("think-time", 0, "pause after each request")
+	dataPath := fs.String("data", "", "CSV or JSON file of rows set as variables on each iteration")
+	dataMode := fs.String("data-mode", string(load.DataShared), "shared: next row per iteration; per-vu: one row per virtual user")
+	envName := fs.String("env", getEnv(), "environment to use")
+	maxErrorRate := fs.Float64("max-error-rate", 0, "fail if more than this percentage of requests fail (0 disables)")
+	maxP95 := fs.Duration("max-p95", 0, "fail if the p95 latency exceeds this (0 disables)")
+	quiet := fs.Bool("quiet", false, "do not print live progress")
+	outs := fs.String("out", "", "comma-separated per-second outputs: json=<file>, csv=<file>, prometheus=<remote-write url>")
+	profilePath := fs.String("profile", "", "YAML load profile; flags given on the command line override it")
+	thresholds := fs.String("threshold", "", "comma-separated thresholds added to the collection's, e.g. \"p95<300ms,error_rate<1%\"")
+	agents := fs.Int("agents", 2, "coordinate: number of agents to wait for and split the load between")
+	listen := fs.String("listen", ":7070", "coordinate: address agents connect to")
+	checkRate := fs.Float64("check-rate", 1, "fraction of responses checked against the requests' tests and assertions (0 disables checks)")
+	failureSamples := fs.Int("failure-samples", 5, "failed requests of each kind kept for the summary (0 keeps none)")
+	reports := fs.String("report", "", "comma-separated report files written after the run: .html for a report with charts, .json for a summary")
+	dashboard := fs.Bool("tui", false, "show a live dashboard that can pause, abort or add VUs")
+	noKeepAlive := fs.Bool("no-keepalive", false, "open a new connection for every request")
+	perVU := fs.Bool("per-vu-connections", false, "give each VU its own connection pool instead of sharing one")
+	maxConns := fs.Int("max-conns", 0, "most connections per host in each pool (0 means no limit)")
+	protocol := fs.String("http", "", "force the HTTP version: 1.1 or 2 (default: negotiated)")
+	tlsResumption := fs.Bool("tls-resumption", false, "resume TLS sessions when reconnecting")
+	localAddrs := fs.String("local-addr", "", "comma-separated source IP addresses, handed out to VUs in turn")
+	insecure := fs.Bool("insecure", false, "skip TLS certificate verification")
+	gracefulStop := fs.Duration("graceful-stop", 30*time.Second, "how long iterations in flight at the end may take to finish (0 cuts them short)")
+	maxDuration := fs.Duration("max-duration", 0, "abort the run if it is still going after this long (0 disables)")
+	presetName := fs.String("preset", "", "built-in test: soak, spike or breakpoint, sized by --vus, --peak-vus, --rate and --duration")
+	peakVUs := fs.Int("peak-vus", 0, "spike: VUs at the top of the spike (default 10 × --vus)")
+	breakP95 := fs.Duration("break-p95", time.Second, "breakpoint: p95 above which a second counts as broken")
+	breakErrorRate := fs.Float64("break-error-rate", 5, "breakpoint: error rate (percent) above which a second counts as broken")
+	maxDrift := fs.Float64("max-drift", 20, "soak: percent latency, throughput or memory may drift over the steady phase")
+	memoryURL := fs.String("memory-url", "", "soak: target's Prometheus metrics URL to watch memory on")
+	memoryMetric := fs.String("memory-metric", "process_resident_memory_bytes", "soak: memory gauge read from --memory-url")
+
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
 		fmt.Println("Usage: nexus load <collection> [flags]")
+		fmt.Println("       nexus load <collection> --preset soak|spike|breakpoint [flags]")
+		fmt.Println("       nexus load coordinate <collection> --agents n [flags]")
+		fmt.Println("       nexus load agent --coordinator <url> [--name name]")
+		fs.PrintDefaults()
 		os.Exit(1)
 	}
+	collectionPath := args[0]
+	fs.Parse(args[1:])
 
-	fmt.Println("Load testing: Basic implementation")
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
 
-	collectionPath := os.Args[2]
-	env := getEnv()
+	var cfg load.Config
+	if *presetName != "" {
+		if *profilePath != "" {
+			log.Fatal("--preset and --profile cannot be combined")
+		}
+		preset, err := load.ParsePreset(*presetName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var opts load.PresetOptions
+		if set["vus"] {
+			opts.VUs = *vus
+		}
+		if set["rate"] {
+			opts.Rate = *rate
+		}
+		if set["duration"] {
+			opts.Duration = *duration
+		}
+		opts.Peak = *peakVUs
+		profile, err := preset.Profile(opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg = profile.Config()
+		cfg.Limits = load.PresetLimits{P95: *breakP95, ErrorRate: *breakErrorRate, Drift: *maxDrift}
+		if *memoryURL != "" {
+			cfg.Memory = load.NewMemoryProbe(*memoryURL, *memoryMetric)
+		}
+		// The preset has used these to build its stages.
+		delete(set, "vus")
+		delete(set, "rate")
+		delete(set, "duration")
+	}
+	if *profilePath != "" {
+		profile, err := load.LoadProfile(*profilePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg = profile.Config()
+		if !set["think-time"] {
+			*thinkTime = profile.ThinkTime
+		}
+	}
+	if set["executor"] {
+		cfg.Executor = load.Executor(*executor)
+	}
+	if set["duration"] {
+		cfg.Duration = *duration
+	}
+	if set["iterations"] {
+		cfg.Iterations = *iterations
+	}
+	if set["ramp-up"] {
+		cfg.RampUp = *rampUp
+	}
+	if set["ramp-down"] {
+		cfg.RampDown = *rampDown
+	}
+	if set["stages"] {
+		var err error
+		if cfg.Stages, err = parseStages(*stages); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if set["rate"] {
+		cfg.Rate = *rate
+	}
+	if set["time-unit"] || cfg.TimeUnit == 0 {
+		cfg.TimeUnit = *timeUnit
+	}
+	if set["pre-allocated-vus"] {
+		cfg.PreAllocatedVUs = *preAllocated
+	}
+	if set["max-vus"] {
+		cfg.MaxVUs = *maxVUs
+	}
+	if set["graceful-stop"] {
+		cfg.GracefulStop = *gracefulStop
+		if cfg.GracefulStop == 0 {
+			cfg.GracefulStop = -1
+		}
+	}
+	if set["max-duration"] {
+		cfg.MaxDuration = *maxDuration
+	}
+	if set["check-rate"] {
+		cfg.CheckRate, cfg.SkipChecks = *checkRate, *checkRate == 0
+	}
+	if set["failure-samples"] {
+		cfg.FailureSamples = *failureSamples
+		if cfg.FailureSamples == 0 {
+			cfg.FailureSamples = -1
+		}
+	}
+	if set["no-keepalive"] || set["per-vu-connections"] || set["max-conns"] || set["http"] ||
+		set["tls-resumption"] || set["local-addr"] || set["insecure"] {
+		if cfg.Transport == nil {
+			cfg.Transport = &load.Transport{}
+		}
+		t := cfg.Transport
+		if set["no-keepalive"] {
+			t.DisableKeepAlives = *noKeepAlive
+		}
+		if set["per-vu-connections"] {
+			t.PerVU = *perVU
+		}
+		if set["max-conns"] {
+			t.MaxConnsPerHost = *maxConns
+		}
+		if set["http"] {
+			switch *protocol {
+			case "1", "1.1":
+				t.Protocol = "http1"
+			case "2":
+				t.Protocol = "http2"
+			default:
+				log.Fatalf("--http must be 1.1 or 2, not %q", *protocol)
+			}
+		}
+		if set["tls-resumption"] {
+			t.TLSSessionResumption = *tlsResumption
+		}
+		if set["local-addr"] {
+			t.LocalAddrs = splitList(*localAddrs)
+		}
+		if set["insecure"] {
+			t.InsecureSkipVerify = *insecure
+		}
+	}
+	// Stages ramp up from zero VUs unless told otherwise.
+	if set["vus"] || cfg.VirtualUsers == 0 && (len(cfg.Stages) == 0 || cfg.Executor == load.ExecutorRampingArrivalRate) {
+		cfg.VirtualUsers = *vus
+	}
+	if len(cfg.Stages) == 0 && cfg.Duration <= 0 && cfg.Iterations <= 0 {
+		cfg.Iterations = cfg.VirtualUsers * 10
+	}
+	if cfg.RampDown > 0 && cfg.Duration <= 0 {
+		log.Fatal("--ramp-down requires --duration")
+	}
 
 	parser := collection.NewParser()
 	coll, err := parser.ParseFile(collectionPath)
@@ -158,74 +512,531 @@ func runLoadTest() {
 		log.Fatal(err)
 	}
 
-	runner := collection.NewRunner(env)
+	var scenarios []*load.Scenario
+	if *requestNames != "" {
+		reqs, err := selectRequests(coll, strings.Split(*requestNames, ","))
+		if err != nil {
+			log.Fatal(err)
+		}
+		scenarios = []*load.Scenario{{Name: coll.Name, Requests: reqs}}
+	} else {
+		var names []string
+		if *folders != "" {
+			names = strings.Split(*folders, ",")
+		}
+		scenarios, err = load.CollectionScenarios(coll, names...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	fmt.Println("Running 10 iterations...")
+	var data []map[string]string
+	if *dataPath != "" {
+		if data, err = load.LoadData(*dataPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, sc := range scenarios {
+		sc.ThinkTime = *thinkTime
+		sc.Data, sc.DataMode = data, load.DataMode(*dataMode)
+	}
 
-	for i := 0; i < 10; i++ {
-		results, err := runner.Run(coll)
-		if err != nil {
+	runner := collection.NewRunner(*envName)
+	runner.Resolver.LoadEnvironment(coll, *envName)
+
+	var outputs []load.Output
+	if *outs != "" {
+		for _, spec := range strings.Split(*outs, ",") {
+			out, err := load.OpenOutput(strings.TrimSpace(spec))
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer out.Close()
+			outputs = append(outputs, out)
+		}
+	}
+
+	cfg.Outputs = outputs
+	cfg.Thresholds = append(coll.Thresholds, cfg.Thresholds...)
+	if *thresholds != "" {
+		cfg.Thresholds = append(cfg.Thresholds, strings.Split(*thresholds, ",")...)
+	}
+	var feed *tui.LoadFeed
+	if *dashboard {
+		if coordinate {
+			log.Fatal("--tui is not supported with coordinate")
+		}
+		feed = tui.NewLoadFeed()
+		cfg.Outputs = append(cfg.Outputs, feed)
+	}
+
+	// The first interrupt aborts the run and still prints the results so
+	// far; a second one kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	limit := fmt.Sprintf("%d iterations", cfg.Iterations)
+	if cfg.Duration > 0 {
+		limit = cfg.Duration.String()
+	}
+	for _, sc := range scenarios {
+		fmt.Printf("Scenario %s: %d requests\n", sc.Name, len(sc.Requests))
+	}
+	switch {
+	case len(cfg.Stages) > 0:
+		fmt.Printf("Load testing through %d stages\n", len(cfg.Stages))
+	case cfg.Rate > 0:
+		fmt.Printf("Load testing at %d iterations per %v for %s\n", cfg.Rate, cfg.TimeUnit, limit)
+	default:
+		fmt.Printf("Load testing with %d VUs for %s\n", cfg.VirtualUsers, limit)
+	}
+
+	var (
+		run      func(context.Context) (*load.LoadTestResult, error)
+		progress progressReporter
+	)
+	if coordinate {
+		coord := load.NewCoordinator(&cfg, *agents, scenarios...)
+		coord.Variables = runner.Resolver.Variables()
+		srv := &http.Server{Addr: *listen, Handler: coord}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+		defer srv.Close()
+		fmt.Printf("Waiting for %d agents on %s\n", *agents, *listen)
+		run, progress = coord.Run, coord
+	} else {
+		engine := load.NewEngine(&cfg, runner)
+		run = func(ctx context.Context) (*load.LoadTestResult, error) {
+			return engine.RunScenarios(ctx, scenarios...)
+		}
+		progress = engine
+	}
+
+	var result *load.LoadTestResult
+	if feed != nil {
+		model := tui.NewLoadModel(coll.Name, progress.(*load.Engine), feed, func() (*load.LoadTestResult, error) {
+			return run(ctx)
+		})
+		final, tuiErr := tea.NewProgram(model, tea.WithAltScreen()).Run()
+		if tuiErr != nil {
+			log.Fatal(tuiErr)
+		}
+		result, err = final.(tui.LoadModel).Result()
+	} else {
+		done := make(chan struct{})
+		if !*quiet {
+			go printLoadProgress(progress, done)
+		}
+		result, err = run(ctx)
+		close(done)
+	}
+	if result == nil {
+		log.Fatal(err)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nwarning: %v\n", err)
+	}
+	if err := cfg.Memory.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "\nwarning: %v\n", err)
+	}
+
+	fmt.Println()
+	fmt.Println(result)
+
+	if *reports != "" {
+		fmt.Println()
+	}
+	for _, path := range splitList(*reports) {
+		if err := load.WriteReport(path, coll.Name, result); err != nil {
 			log.Fatal(err)
 		}
+		fmt.Printf("Report written to %s\n", path)
+	}
 
-		fmt.Printf("Iteration %d: %d requests completed\n", i+1, len(results))
+	var failures []string
+	if result.TotalRequests == 0 {
+		failures = append(failures, "no requests completed")
+	}
+	if rate := result.ErrorRate(); *maxErrorRate > 0 && rate > *maxErrorRate {
+		failures = append(failures, fmt.Sprintf("error rate %.2f%% exceeds %.2f%%", rate, *maxErrorRate))
+	}
+	if *maxP95 > 0 && result.P95Latency > *maxP95 {
+		failures = append(failures, fmt.Sprintf("p95 latency %v exceeds %v", result.P95Latency, *maxP95))
 	}
+	if len(failures) > 0 {
+		fmt.Println("\nFailed:")
+		for _, f := range failures {
+			fmt.Printf("  ❌ %s\n", f)
+		}
+	}
+	if !result.ThresholdsPassed() {
+		os.Exit(exitThresholdsFailed)
+	}
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
 
-	fmt.Println("Load test complete!")
+// exitThresholdsFailed lets CI tell a broken threshold from other failures.
+const exitThresholdsFailed = 99
+
+func selectRequests(coll *collection.Collection, names []string) ([]collection.Request, error) {
+	var reqs []collection.Request
+	for _, name := range names {
+		found := false
+		for _, req := range coll.Requests {
+			if strings.EqualFold(req.Name, strings.TrimSpace(name)) {
+				reqs = append(reqs, req)
+				found = true
+				break
+			}
+		}
+		if !found {
+			available := make([]string, 0, len(coll.Requests))
+			for _, req := range coll.Requests {
+				available = append(available, req.Name)
+			}
+			return nil, fmt.Errorf("request %q not found; available: %s", name, strings.Join(available, ", "))
+		}
+	}
+	return reqs, nil
 }
 
-func runMockServer() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: nexus mock [port]")
+// parseStages reads duration:target pairs such as "30s:20,1m:20,30s:0".
+func parseStages(s string) ([]load.Stage, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var stages []load.Stage
+	for _, part := range strings.Split(s, ",") {
+		d, target, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("stage %q: want duration:target", part)
+		}
+		dur, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, fmt.Errorf("stage %q: %w", part, err)
+		}
+		n, err := strconv.Atoi(target)
+		if err != nil {
+			return nil, fmt.Errorf("stage %q: %w", part, err)
+		}
+		stages = append(stages, load.Stage{Duration: dur, Target: n})
+	}
+	return stages, nil
+}
+
+// runLoadCompare compares two JSON summaries and fails on regressions.
+func runLoadCompare() {
+	fs := flag.NewFlagSet("load compare", flag.ExitOnError)
+	latency := fs.Float64("tolerance", load.DefaultTolerance.Latency, "allowed latency increase in percent")
+	rps := fs.Float64("rps-tolerance", load.DefaultTolerance.RPS, "allowed RPS decrease in percent")
+	errorRate := fs.Float64("error-tolerance", load.DefaultTolerance.ErrorRate, "allowed error rate increase in percentage points")
+	args := os.Args[3:]
+	var paths []string
+	for len(args) > 0 {
+		fs.Parse(args)
+		if args = fs.Args(); len(args) > 0 {
+			paths = append(paths, args[0])
+			args = args[1:]
+		}
+	}
+	if len(paths) != 2 {
+		fmt.Println("Usage: nexus load compare <base.json> <current.json> [flags]")
+		fs.PrintDefaults()
 		os.Exit(1)
 	}
 
-	port := "9999"
-	if len(os.Args) > 2 {
-		port = os.Args[2]
+	base, err := load.ReadSummary(paths[0])
+	if err != nil {
+		log.Fatal(err)
 	}
+	current, err := load.ReadSummary(paths[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := load.Compare(base, current, load.Tolerance{Latency: *latency, RPS: *rps, ErrorRate: *errorRate})
+	c.Base, c.Current = paths[0], paths[1]
+	fmt.Println(c)
+	if len(c.Regressions()) > 0 {
+		os.Exit(1)
+	}
+}
 
-	fmt.Printf("Starting mock server on port %s...\n", port)
+// runLoadAgent generates load for a coordinator until it says stop.
+func runLoadAgent() {
+	fs := flag.NewFlagSet("load agent", flag.ExitOnError)
+	coordinator := fs.String("coordinator", "", "coordinator URL, e.g. http://host:7070")
+	name := fs.String("name", "", "name shown in the coordinator's summary (default: assigned)")
+	envName := fs.String("env", getEnv(), "environment to use")
+	fs.Parse(os.Args[3:])
+	if *coordinator == "" {
+		fmt.Println("Usage: nexus load agent --coordinator <url> [--name name]")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+	if !strings.Contains(*coordinator, "://") {
+		*coordinator = "http://" + *coordinator
+	}
 
-	server := mock.NewServer()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	server.AddEndpoint(&mock.Endpoint{
-		Path:   "/health",
-		Method: "GET",
-		Response: mock.Response{
-			StatusCode: 200,
-			Body:       "OK",
-		},
-	})
+	fmt.Printf("Joining coordinator %s\n", *coordinator)
+	result, err := load.NewAgent(*coordinator, *name, collection.NewRunner(*envName)).Run(ctx)
+	if result == nil {
+		log.Fatal(err)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	fmt.Printf("✅ Agent finished: %d requests, %d failed\n", result.TotalRequests, result.FailedRequests)
+}
+
+type progressReporter interface {
+	Progress() load.Progress
+}
+
+func printLoadProgress(engine progressReporter, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			p := engine.Progress()
+			fmt.Printf("\r  %6s  vus %-4d requests %-8d failed %-6d rps %.1f   ",
+				p.Elapsed.Truncate(time.Second), p.ActiveVUs, p.Requests, p.Failed, p.RPS)
+			if p.Dropped > 0 {
+				fmt.Printf("dropped %d   ", p.Dropped)
+			}
+		}
+	}
+}
+
+func runMockServer() {
+	fs := flag.NewFlagSet("mock", flag.ExitOnError)
+	configPath := fs.String("config", "", "YAML file or directory describing mock endpoints and resources")
+	openapiPath := fs.String("openapi", "", "OpenAPI 3 spec (YAML or JSON) to generate endpoints from")
+	record := fs.Bool("record", false, "proxy requests to --upstream and record them")
+	replay := fs.Bool("replay", false, "serve recorded requests from --dir")
+	upstream := fs.String("upstream", "", "upstream base URL for --record")
+	dir := fs.String("dir", "recordings", "directory for recordings")
+	redactHeaders := fs.String("redact-headers", "Authorization,Cookie,Set-Cookie", "comma-separated headers to redact in recordings")
+	redactFields := fs.String("redact-fields", "", "comma-separated JSON fields to redact in recorded bodies")
+	matchKeys := fs.String("match-keys", strings.Join(mock.DefaultMatchKeys, ","), "request parts used for replay matching (method,path,query,body,header:<name>)")
+	lenient := fs.Bool("lenient", false, "replay the closest recording for method and path instead of requiring all match keys")
+	admin := fs.Bool("admin", false, "serve the admin API under "+mock.AdminPrefix+" on the mock port")
+	adminAddr := fs.String("admin-addr", "", "also serve the admin API on this address, e.g. :9998")
+	instances := fs.String("instances", "", "YAML file describing several named mock instances")
+	useTLS := fs.Bool("tls", false, "serve HTTPS and HTTP/2 with a self-signed certificate unless --cert/--key are given")
+	certFile := fs.String("cert", "", "TLS certificate file")
+	keyFile := fs.String("key", "", "TLS key file")
+
+	port, args := "9999", os.Args[2:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		port, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+	addr := ":" + port
+
+	if *record {
+		recorder, err := mock.NewRecorder(mock.RecordConfig{
+			Upstream:      *upstream,
+			Dir:           *dir,
+			RedactHeaders: splitList(*redactHeaders),
+			RedactFields:  splitList(*redactFields),
+			MatchKeys:     splitList(*matchKeys),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("Recording %s into %s via http://localhost%s\n", *upstream, *dir, addr)
+		serveUntilSignal(addr, recorder)
+		return
+	}
+
+	if *replay {
+		recs, err := mock.LoadRecordings(*dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		replayer := mock.NewReplayer(recs, mock.ReplayConfig{Lenient: *lenient})
+
+		fmt.Printf("Replaying %d recordings from %s at http://localhost%s\n", len(recs), *dir, addr)
+		fmt.Printf("Unmatched requests: GET %s/unmatched\n", mock.AdminPrefix)
+		serveUntilSignal(addr, replayer)
+
+		unmatched := replayer.Unmatched()
+		fmt.Printf("\n%d unmatched requests\n", len(unmatched)+replayer.Dropped())
+		if n := replayer.Dropped(); n > 0 {
+			fmt.Printf("  (%d oldest not shown)\n", n)
+		}
+		for _, um := range unmatched {
+			fmt.Printf("  %s %s", um.Method, um.Path)
+			if um.Query != "" {
+				fmt.Printf("?%s", um.Query)
+			}
+			fmt.Printf(" - %s", um.Reason)
+			if um.Closest != "" {
+				fmt.Printf(" (closest: %s)", um.Closest)
+			}
+			fmt.Println()
+		}
+		if len(unmatched) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *instances != "" {
+		cfg, err := mock.LoadManagerConfig(*instances)
+		if err != nil {
+			log.Fatal(err)
+		}
+		manager, err := cfg.Build(addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := manager.Listen(); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Mock instances:")
+		for _, inst := range manager.Instances() {
+			fmt.Printf("  %-12s %s (%d endpoints)\n", inst.Name, inst.URL(), len(inst.Server.Endpoints()))
+		}
+		if err := manager.Serve(ctx); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	server.AddEndpoint(&mock.Endpoint{
-		Path:   "/api/users",
-		Method: "GET",
-		Response: mock.Response{
-			StatusCode: 200,
-			Headers: map[string]string{
-				"Content-Type": "application/json",
+	fmt.Printf("Starting mock server on port %s...\n", port)
+
+	server, err := mock.LoadServer(*configPath, *openapiPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.SetAdmin(*admin)
+
+	if *configPath == "" && *openapiPath == "" {
+		server.AddEndpoint(&mock.Endpoint{
+			Path:   "/health",
+			Method: "GET",
+			Response: mock.Response{
+				StatusCode: 200,
+				Body:       "OK",
 			},
-			Body: map[string]interface{}{
-				"users": []map[string]interface{}{
-					{"id": 1, "name": "Alice", "email": "alice@example.com"},
-					{"id": 2, "name": "Bob", "email": "bob@example.com"},
+		})
+
+		server.AddEndpoint(&mock.Endpoint{
+			Path:   "/api/users",
+			Method: "GET",
+			Response: mock.Response{
+				StatusCode: 200,
+				Headers: map[string]string{
+					"Content-Type": "application/json",
+				},
+				Body: map[string]interface{}{
+					"users": []map[string]interface{}{
+						{"id": 1, "name": "Alice", "email": "alice@example.com"},
+						{"id": 2, "name": "Bob", "email": "bob@example.com"},
+					},
 				},
 			},
-		},
-	})
+		})
+	}
 
-	addr := ":" + port
-	fmt.Printf("Mock server running at http://localhost%s\n", addr)
+	manager := mock.NewManager(addr)
+	if err := manager.Add(&mock.Instance{
+		Name:     "default",
+		Server:   server,
+		TLS:      *useTLS || *certFile != "",
+		CertFile: *certFile,
+		KeyFile:  *keyFile,
+	}); err != nil {
+		log.Fatal(err)
+	}
+	if err := manager.Listen(); err != nil {
+		log.Fatal(err)
+	}
+	url := manager.Get("default").URL()
+
+	fmt.Printf("Mock server running at %s\n", url)
 	fmt.Println("Endpoints:")
-	fmt.Println("  GET  /health")
-	fmt.Println("  GET  /api/users")
+	for _, ep := range server.Endpoints() {
+		if ep.Resource != nil {
+			fmt.Printf("  *    %s (resource)\n", ep.Path)
+			continue
+		}
+		if ep.GraphQL != nil {
+			fmt.Printf("  %-4s %s (graphql)\n", ep.Method, ep.Path)
+			continue
+		}
+		if ep.Scenario != "" {
+			fmt.Printf("  %-4s %s [%s: %s]\n", ep.Method, ep.Path, ep.Scenario, ep.State)
+			continue
+		}
+		fmt.Printf("  %-4s %s\n", ep.Method, ep.Path)
+	}
+	if *openapiPath != "" {
+		fmt.Println("  (send \"Prefer: code=<status>\" or \"Prefer: example=<name>\" to pick alternate responses)")
+	}
 
-	if err := server.Start(addr); err != nil {
+	if *admin {
+		fmt.Printf("Admin API: %s%s/endpoints\n", url, mock.AdminPrefix)
+	}
+	if *adminAddr != "" {
+		fmt.Printf("Admin API: http://localhost%s%s/endpoints\n", *adminAddr, mock.AdminPrefix)
+		go func() {
+			if err := http.ListenAndServe(*adminAddr, server.AdminHandler()); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	if err := manager.Serve(ctx); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// serveUntilSignal serves h on addr until SIGINT or SIGTERM.
+func serveUntilSignal(addr string, h http.Handler) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: h}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
 func runCollabServer() {
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: nexus collab [port]")
@@ -3,8 +3,10 @@ package api
 import (
     "context"
     "encoding/json"
+    "errors"
     "fmt"
     "net/http"
+    "strconv"
     "time"
 
     "github.com/nexusapi/nexus/pkg/ai"
@@ -49,8 +51,13 @@ func (s *APIServer) RegisterRoutes(mux *http.ServeMux) {
     mux.HandleFunc("/api/collections", s.corsWrap(s.handleCollections))
     mux.HandleFunc("/api/collections/get", s.corsWrap(s.handleGetCollection))
     mux.HandleFunc("/api/collections/save", s.corsWrap(s.handleSaveCollection))
+    mux.HandleFunc("/api/collections/history", s.corsWrap(s.handleCollectionHistory))
+    mux.HandleFunc("/api/collections/diff", s.corsWrap(s.handleCollectionDiff))
     mux.HandleFunc("/api/run", s.corsWrap(s.handleRun))
     mux.HandleFunc("/api/mock/add", s.corsWrap(s.handleMockAdd))
+    // endpoints added above are served under /mock/, with the mock admin
+    // API at /mock/__nexus/
+    mux.Handle("/mock/", http.StripPrefix("/mock", s.corsWrap(s.mockServer.ServeHTTP)))
     mux.HandleFunc("/api/ai/generate-body", s.corsWrap(s.handleAIGenerateBody))
     mux.Handle("/metrics", metrics.Handler())
 }
@@ -59,7 +66,7 @@ func (s *APIServer) RegisterRoutes(mux *http.ServeMux) {
 func (s *APIServer) corsWrap(h http.HandlerFunc) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         w.Header().Set("Access-Control-Allow-Origin", "*")
-        w.Header().Set("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
+        w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
         w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
         if r.Method == http.MethodOptions {
             w.WriteHeader(http.StatusOK)
@@ -102,6 +109,65 @@ func (s *APIServer) handleGetCollection(w http.ResponseWriter, r *http.Request)
     writeJSON(w, coll)
 }
 
+// handleCollectionHistory lists the commits that changed a collection:
+// GET ?name=<collection>&n=<count, default 20>.
+func (s *APIServer) handleCollectionHistory(w http.ResponseWriter, r *http.Request) {
+    name := r.URL.Query().Get("name")
+    if name == "" {
+        http.Error(w, "missing name", http.StatusBadRequest)
+        return
+    }
+    n := 20
+    if v := r.URL.Query().Get("n"); v != "" {
+        var err error
+        if n, err = strconv.Atoi(v); err != nil || n <= 0 {
+            http.Error(w, "n must be a positive number", http.StatusBadRequest)
+            return
+        }
+    }
+
+    commits, err := s.repo.CollectionHistory(name, n)
+    if err != nil {
+        http.Error(w, err.Error(), storageStatus(err))
+        return
+    }
+    writeJSON(w, map[string]interface{}{"commits": commits})
+}
+
+// handleCollectionDiff compares a collection at two revisions:
+// GET ?name=<collection>&from=<rev, default HEAD>&to=<rev, default the
+// working copy>.
+func (s *APIServer) handleCollectionDiff(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query()
+    name := q.Get("name")
+    if name == "" {
+        http.Error(w, "missing name", http.StatusBadRequest)
+        return
+    }
+    from := q.Get("from")
+    if from == "" {
+        from = "HEAD"
+    }
+
+    diff, err := s.repo.DiffCollection(name, from, q.Get("to"))
+    if err != nil {
+        http.Error(w, err.Error(), storageStatus(err))
+        return
+    }
+    writeJSON(w, diff)
+}
+
+// storageStatus maps repository errors to HTTP statuses.
+func storageStatus(err error) int {
+    switch {
+    case errors.Is(err, storage.ErrCollectionNotFound), errors.Is(err, storage.ErrUnknownRevision):
+        return http.StatusNotFound
+    case errors.Is(err, storage.ErrNotVersioned):
+        return http.StatusConflict
+    }
+    return http.StatusInternalServerError
+}
+
 type saveReq struct {
     Name    string `json:"name"`
     Content string `json:"content"`
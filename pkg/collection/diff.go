@@ -0,0 +1,289 @@
+package collection
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diff is what changed between two versions of a collection, by request
+// rather than by line.
+type Diff struct {
+	// Collection holds changes to the collection's own settings.
+	Collection []Change      `json:"collection,omitempty"`
+	Added      []string      `json:"added,omitempty"`
+	Removed    []string      `json:"removed,omitempty"`
+	Renamed    []Rename      `json:"renamed,omitempty"`
+	Changed    []RequestDiff `json:"changed,omitempty"`
+}
+
+// Rename is a request whose name changed while its method and URL did not.
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RequestDiff lists the changes to one request, under its new name.
+type RequestDiff struct {
+	Name    string   `json:"name"`
+	Changes []Change `json:"changes"`
+}
+
+// Change is one changed field, such as "url", "header Accept",
+// "body.user.name" or "assertion". Old is empty for additions and New for
+// removals; values are shown as JSON where they are not strings.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("+ %s: %s", c.Field, c.New)
+	case c.New == "":
+		return fmt.Sprintf("- %s: %s", c.Field, c.Old)
+	}
+	return fmt.Sprintf("~ %s: %s → %s", c.Field, c.Old, c.New)
+}
+
+func (d *Diff) Empty() bool {
+	return len(d.Collection) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Renamed) == 0 && len(d.Changed) == 0
+}
+
+func (d *Diff) String() string {
+	if d.Empty() {
+		return "No changes"
+	}
+	var b strings.Builder
+	for _, c := range d.Collection {
+		fmt.Fprintf(&b, "%s\n", c)
+	}
+	fmt.Fprintf(&b, "Requests: %d added, %d removed, %d renamed, %d changed\n",
+		len(d.Added), len(d.Removed), len(d.Renamed), len(d.Changed))
+	for _, name := range d.Added {
+		fmt.Fprintf(&b, "  + %s\n", name)
+	}
+	for _, name := range d.Removed {
+		fmt.Fprintf(&b, "  - %s\n", name)
+	}
+	for _, r := range d.Renamed {
+		fmt.Fprintf(&b, "  → %s renamed to %s\n", r.From, r.To)
+	}
+	for _, r := range d.Changed {
+		fmt.Fprintf(&b, "  ~ %s\n", r.Name)
+		for _, c := range r.Changes {
+			fmt.Fprintf(&b, "      %s\n", c)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// DiffCollections compares two versions of a collection; either may be
+// nil for one that does not exist. Requests are matched by name, and an
+// unmatched pair with the same method and URL counts as a rename.
+func DiffCollections(from, to *Collection) *Diff {
+	if from == nil {
+		from = &Collection{}
+	}
+	if to == nil {
+		to = &Collection{}
+	}
+	d := &Diff{Collection: diffSettings(from, to)}
+
+	// Requests sharing a name are matched in order.
+	byName := map[string][]int{}
+	for i, r := range from.Requests {
+		byName[r.Name] = append(byName[r.Name], i)
+	}
+	matched := make([]bool, len(from.Requests))
+	var added []Request
+	for _, r := range to.Requests {
+		if queue := byName[r.Name]; len(queue) > 0 {
+			byName[r.Name] = queue[1:]
+			matched[queue[0]] = true
+			d.addChanges(r.Name, diffRequests(from.Requests[queue[0]], r))
+			continue
+		}
+		added = append(added, r)
+	}
+	var removed []Request
+	for i, r := range from.Requests {
+		if !matched[i] {
+			removed = append(removed, r)
+		}
+	}
+
+	for _, r := range added {
+		i := -1
+		for j, o := range removed {
+			if o.Method == r.Method && o.URL == r.URL {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			d.Added = append(d.Added, r.Name)
+			continue
+		}
+		d.Renamed = append(d.Renamed, Rename{From: removed[i].Name, To: r.Name})
+		d.addChanges(r.Name, diffRequests(removed[i], r))
+		removed = append(removed[:i], removed[i+1:]...)
+	}
+	for _, r := range removed {
+		d.Removed = append(d.Removed, r.Name)
+	}
+	return d
+}
+
+func (d *Diff) addChanges(name string, changes []Change) {
+	if len(changes) > 0 {
+		d.Changed = append(d.Changed, RequestDiff{Name: name, Changes: changes})
+	}
+}
+
+func diffSettings(from, to *Collection) []Change {
+	var c changes
+	c.value("name", from.Name, to.Name)
+	c.value("baseUrl", from.BaseURL, to.BaseURL)
+	c.value("preRequest", from.PreRequest, to.PreRequest)
+	c.list("test", from.Tests, to.Tests)
+	c.list("threshold", from.Thresholds, to.Thresholds)
+	for _, name := range keys(from.Environment, to.Environment) {
+		o, inOld := from.Environment[name]
+		n, inNew := to.Environment[name]
+		field := "environment " + name
+		switch {
+		case !inOld:
+			c.add(field, "", "added")
+		case !inNew:
+			c.add(field, "removed", "")
+		default:
+			c.value(field+" baseUrl", o.BaseURL, n.BaseURL)
+			c.dict(field+" variable", o.Variables, n.Variables)
+		}
+	}
+	return c
+}
+
+func diffRequests(from, to Request) []Change {
+	var c changes
+	c.value("method", from.Method, to.Method)
+	c.value("url", from.URL, to.URL)
+	c.value("folder", from.Folder, to.Folder)
+	c.dict("header", from.Headers, to.Headers)
+	c.dict("query", from.QueryParams, to.QueryParams)
+	c.body("body", normalize(from.Body), normalize(to.Body))
+	c.body("auth", normalize(from.Auth), normalize(to.Auth))
+	c.value("preRequest", from.PreRequest, to.PreRequest)
+	c.list("test", from.Tests, to.Tests)
+	c.list("assertion", from.Assertions, to.Assertions)
+	c.dict("extract", from.Extract, to.Extract)
+	return c
+}
+
+type changes []Change
+
+func (c *changes) add(field, from, to string) {
+	*c = append(*c, Change{Field: field, Old: from, New: to})
+}
+
+func (c *changes) value(field, from, to string) {
+	if from != to {
+		c.add(field, from, to)
+	}
+}
+
+func (c *changes) dict(field string, from, to map[string]string) {
+	for _, k := range keys(from, to) {
+		c.value(field+" "+k, from[k], to[k])
+	}
+}
+
+// list reports items removed from and added to a list, ignoring order.
+func (c *changes) list(field string, from, to []string) {
+	count := map[string]int{}
+	for _, s := range from {
+		count[s]++
+	}
+	var added []string
+	for _, s := range to {
+		if count[s] > 0 {
+			count[s]--
+			continue
+		}
+		added = append(added, s)
+	}
+	for _, s := range from {
+		if count[s] > 0 {
+			count[s]--
+			c.add(field, s, "")
+		}
+	}
+	for _, s := range added {
+		c.add(field, "", s)
+	}
+}
+
+// body reports the differences between two decoded JSON values by path,
+// e.g. body.user.name or body.items[2].
+func (c *changes) body(field string, from, to interface{}) {
+	om, oldMap := from.(map[string]interface{})
+	nm, newMap := to.(map[string]interface{})
+	if oldMap && newMap {
+		for _, k := range keys(om, nm) {
+			c.body(field+"."+k, om[k], nm[k])
+		}
+		return
+	}
+	ol, oldList := from.([]interface{})
+	nl, newList := to.([]interface{})
+	if oldList && newList && len(ol) == len(nl) {
+		for i := range ol {
+			c.body(fmt.Sprintf("%s[%d]", field, i), ol[i], nl[i])
+		}
+		return
+	}
+	c.value(field, render(from), render(to))
+}
+
+// normalize turns v into plain JSON values, so that YAML and JSON
+// versions of the same body compare equal.
+func normalize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return string(data)
+	}
+	return out
+}
+
+func render(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func keys[V any](a, b map[string]V) []string {
+	var out []string
+	for k := range a {
+		out = append(out, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -22,7 +22,12 @@ func (p *Parser) ParseFile(path string) (*Collection, error) {
 		return nil, fmt.Errorf("read file: %w", err)
 	}
 
-	ext := strings.ToLower(filepath.Ext(path))
+	return p.Parse(path, data)
+}
+
+// Parse parses data in the format given by name's extension.
+func (p *Parser) Parse(name string, data []byte) (*Collection, error) {
+	ext := strings.ToLower(filepath.Ext(name))
 	switch ext {
 	case ".yaml", ".yml":
 		return p.ParseYAML(data)
@@ -50,7 +55,21 @@ func (p *Parser) ParseJSON(data []byte) (*Collection, error) {
 }
 
 func (p *Parser) SaveFile(coll *Collection, path string) error {
-	ext := strings.ToLower(filepath.Ext(path))
+	data, err := p.Marshal(coll, path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
+
+// Marshal encodes coll in the format given by name's extension.
+func (p *Parser) Marshal(coll *Collection, name string) ([]byte, error) {
+	ext := strings.ToLower(filepath.Ext(name))
 	var data []byte
 	var err error
 
@@ -60,16 +79,11 @@ func (p *Parser) SaveFile(coll *Collection, path string) error {
 	case ".json":
 		data, err = json.MarshalIndent(coll, "", "  ")
 	default:
-		return fmt.Errorf("unsupported file format: %s", ext)
+		return nil, fmt.Errorf("unsupported file format: %s", ext)
 	}
 
 	if err != nil {
-		return fmt.Errorf("marshal: %w", err)
+		return nil, fmt.Errorf("marshal: %w", err)
 	}
-
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("write file: %w", err)
-	}
-
-	return nil
+	return data, nil
 }
@@ -2,7 +2,9 @@ package collection
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
@@ -35,7 +37,7 @@ func (r *Runner) Run(coll *Collection) ([]ExecutionResult, error) {
 		results = append(results, result)
 
 		if result.Error == nil && result.Response.StatusCode < 400 {
-			r.extractVariables(result.Response)
+			r.ExtractVariables(req, result.Response)
 		}
 	}
 
@@ -43,6 +45,21 @@ func (r *Runner) Run(coll *Collection) ([]ExecutionResult, error) {
 }
 
 func (r *Runner) ExecuteRequest(req Request) ExecutionResult {
+	result := r.SendRequest(req)
+	if result.Error == nil {
+		result.Passed, result.Failures = r.RunAssertions(req, result.Response)
+	}
+	return result
+}
+
+// SendRequest executes req without checking its tests and assertions.
+func (r *Runner) SendRequest(req Request) ExecutionResult {
+	return r.SendRequestContext(context.Background(), req)
+}
+
+// SendRequestContext is SendRequest with a context that can cancel the
+// request in flight.
+func (r *Runner) SendRequestContext(ctx context.Context, req Request) ExecutionResult {
 	startTime := time.Now()
 
 	url := r.Resolver.Resolve(req.URL)
@@ -71,7 +88,7 @@ func (r *Runner) ExecuteRequest(req Request) ExecutionResult {
 		headers["Content-Type"] = "application/json"
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	resp, err := r.client.Do(ctx, &nexushttp.RequestOptions{
@@ -100,21 +117,21 @@ func (r *Runner) ExecuteRequest(req Request) ExecutionResult {
 		Body:       resp.Body,
 		Time:       resp.Time,
 		Size:       resp.Size,
+		Reused:     resp.Reused,
 	}
 
-	passed, failures := r.runAssertions(req, response)
-
 	return ExecutionResult{
 		Request:   req,
 		Response:  response,
 		StartTime: startTime,
 		EndTime:   endTime,
-		Passed:    passed,
-		Failures:  failures,
+		Passed:    true,
 	}
 }
 
-func (r *Runner) runAssertions(req Request, resp Response) (bool, []string) {
+// RunAssertions checks resp against req's tests and assertions and returns
+// the ones that failed.
+func (r *Runner) RunAssertions(req Request, resp Response) (bool, []string) {
 	assertions := append(req.Tests, req.Assertions...)
 	if len(assertions) == 0 {
 		return true, nil
@@ -131,6 +148,13 @@ func (r *Runner) runAssertions(req Request, resp Response) (bool, []string) {
 	return len(failures) == 0, failures
 }
 
+var (
+	statusAssertionRe = regexp.MustCompile(`status\s*(==|!=|>|<|>=|<=)\s*(\d+)`)
+	bodyContainsRe    = regexp.MustCompile(`body\.contains\("([^"]+)"\)`)
+	bodyLengthRe      = regexp.MustCompile(`body\.length\s*(>|<|>=|<=|==)\s*(\d+)`)
+	timeAssertionRe   = regexp.MustCompile(`(?:response\.)?time\s*<\s*(\d+)`)
+)
+
 func (r *Runner) evaluateAssertion(assertion string, resp Response) bool {
 	assertion = strings.TrimSpace(assertion)
 
@@ -150,8 +174,7 @@ func (r *Runner) evaluateAssertion(assertion string, resp Response) bool {
 }
 
 func (r *Runner) evalStatusAssertion(assertion string, status int) bool {
-	re := regexp.MustCompile(`status\s*(==|!=|>|<|>=|<=)\s*(\d+)`)
-	matches := re.FindStringSubmatch(assertion)
+	matches := statusAssertionRe.FindStringSubmatch(assertion)
 	if len(matches) < 3 {
 		return true
 	}
@@ -181,16 +204,14 @@ func (r *Runner) evalBodyAssertion(assertion string, body []byte) bool {
 	bodyStr := string(body)
 
 	if strings.Contains(assertion, "contains") {
-		re := regexp.MustCompile(`body\.contains\("([^"]+)"\)`)
-		matches := re.FindStringSubmatch(assertion)
+		matches := bodyContainsRe.FindStringSubmatch(assertion)
 		if len(matches) > 1 {
 			return strings.Contains(bodyStr, matches[1])
 		}
 	}
 
 	if strings.Contains(assertion, "length") {
-		re := regexp.MustCompile(`body\.length\s*(>|<|>=|<=|==)\s*(\d+)`)
-		matches := re.FindStringSubmatch(assertion)
+		matches := bodyLengthRe.FindStringSubmatch(assertion)
 		if len(matches) > 2 {
 			op := matches[1]
 			expected, _ := strconv.Atoi(matches[2])
@@ -215,8 +236,7 @@ func (r *Runner) evalBodyAssertion(assertion string, body []byte) bool {
 }
 
 func (r *Runner) evalTimeAssertion(assertion string, duration time.Duration) bool {
-	re := regexp.MustCompile(`(?:response\.)?time\s*<\s*(\d+)`)
-	matches := re.FindStringSubmatch(assertion)
+	matches := timeAssertionRe.FindStringSubmatch(assertion)
 	if len(matches) > 1 {
 		maxMs, _ := strconv.Atoi(matches[1])
 		return duration < time.Duration(maxMs)*time.Millisecond
@@ -224,5 +244,64 @@ func (r *Runner) evalTimeAssertion(assertion string, duration time.Duration) boo
 	return true
 }
 
-func (r *Runner) extractVariables(resp Response) {
+// Clone returns a runner sharing r's HTTP client with its own copy of the
+// variables, so concurrent users can chain values without interfering.
+func (r *Runner) Clone() *Runner {
+	return &Runner{client: r.client, Resolver: r.Resolver.Clone(), env: r.env}
+}
+
+// WithClient is a clone of r that sends its requests with client.
+func (r *Runner) WithClient(client *nexushttp.Client) *Runner {
+	c := r.Clone()
+	c.client = client
+	return c
+}
+
+// ExtractVariables applies req.Extract to resp.
+func (r *Runner) ExtractVariables(req Request, resp Response) {
+	for name, source := range req.Extract {
+		if value, ok := extractValue(source, resp); ok {
+			r.Resolver.SetVariable(name, value)
+		}
+	}
+}
+
+func extractValue(source string, resp Response) (string, bool) {
+	switch {
+	case source == "status":
+		return strconv.Itoa(resp.StatusCode), true
+	case source == "body":
+		return string(resp.Body), true
+	case strings.HasPrefix(source, "header."):
+		value := http.Header(resp.Headers).Get(strings.TrimPrefix(source, "header."))
+		return value, value != ""
+	case strings.HasPrefix(source, "body."):
+		var v interface{}
+		if err := json.Unmarshal(resp.Body, &v); err != nil {
+			return "", false
+		}
+		for _, part := range strings.Split(strings.TrimPrefix(source, "body."), ".") {
+			switch node := v.(type) {
+			case map[string]interface{}:
+				var ok bool
+				if v, ok = node[part]; !ok {
+					return "", false
+				}
+			case []interface{}:
+				i, err := strconv.Atoi(part)
+				if err != nil || i < 0 || i >= len(node) {
+					return "", false
+				}
+				v = node[i]
+			default:
+				return "", false
+			}
+		}
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+		out, _ := json.Marshal(v)
+		return string(out), true
+	}
+	return "", false
 }
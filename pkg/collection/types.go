@@ -11,6 +11,8 @@ type Collection struct {
 	Requests    []Request              `json:"requests" yaml:"requests"`
 	PreRequest  string                 `json:"preRequest,omitempty" yaml:"preRequest,omitempty"`
 	Tests       []string               `json:"tests,omitempty" yaml:"tests,omitempty"`
+	// Thresholds are the pass/fail checks for `nexus load`, e.g. "p95 < 300ms".
+	Thresholds  []string               `json:"thresholds,omitempty" yaml:"thresholds,omitempty"`
 }
 
 type Environment struct {
@@ -29,6 +31,11 @@ type Request struct {
 	PreRequest string            `json:"preRequest,omitempty" yaml:"preRequest,omitempty"`
 	Tests      []string          `json:"tests,omitempty" yaml:"tests,omitempty"`
 	Assertions []string          `json:"assertions,omitempty" yaml:"assertions,omitempty"`
+	// Folder groups requests, e.g. into user journeys for load tests.
+	Folder     string            `json:"folder,omitempty" yaml:"folder,omitempty"`
+	// Extract sets variables from a successful response for later requests:
+	// "status", "body", "body.<field.path>" or "header.<Name>".
+	Extract    map[string]string `json:"extract,omitempty" yaml:"extract,omitempty"`
 }
 
 type Auth struct {
@@ -43,6 +50,8 @@ type Response struct {
 	Body       []byte
 	Time       time.Duration
 	Size       int64
+	// Reused is set when the request went over an existing connection.
+	Reused     bool
 }
 
 type ExecutionResult struct {
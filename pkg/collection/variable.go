@@ -35,6 +35,26 @@ func (vr *VariableResolver) LoadEnvironment(coll *Collection, envName string) {
 	}
 }
 
+func (vr *VariableResolver) Clone() *VariableResolver {
+	clone := NewVariableResolver(vr.env)
+	for k, v := range vr.variables {
+		clone.variables[k] = v
+	}
+	for k, v := range vr.globals {
+		clone.globals[k] = v
+	}
+	return clone
+}
+
+// Variables returns a copy of the resolved environment and set variables.
+func (vr *VariableResolver) Variables() map[string]string {
+	vars := make(map[string]string, len(vr.variables))
+	for k, v := range vr.variables {
+		vars[k] = v
+	}
+	return vars
+}
+
 func (vr *VariableResolver) SetGlobal(key, value string) {
 	vr.globals[key] = value
 }
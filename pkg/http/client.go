@@ -6,23 +6,41 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"time"
 
 	"golang.org/x/net/http2"
 )
 
 type Client struct {
-	client  *http.Client
-	timeout time.Duration
+	client    *http.Client
+	transport *http.Transport
+	timeout   time.Duration
 }
 
 type Config struct {
 	Timeout            time.Duration
 	InsecureSkipVerify bool
 	MaxIdleConns       int
-	MaxConnsPerHost    int
-	EnableHTTP2        bool
+	// MaxConnsPerHost is the number of idle connections kept per host.
+	MaxConnsPerHost int
+	EnableHTTP2     bool
+
+	// MaxActiveConnsPerHost caps all connections to a host; requests
+	// beyond it wait. 0 means no limit.
+	MaxActiveConnsPerHost int
+	// DisableKeepAlives opens a connection for every request.
+	DisableKeepAlives bool
+	// Protocol forces "http1" or "http2" (HTTP/2 also over plain TCP);
+	// empty negotiates per EnableHTTP2.
+	Protocol string
+	// TLSSessionResumption caches TLS sessions so that new connections can
+	// resume them instead of doing a full handshake.
+	TLSSessionResumption bool
+	// LocalAddr is the source IP address to connect from.
+	LocalAddr string
 }
 
 func NewClient(cfg *Config) *Client {
@@ -35,9 +53,16 @@ func NewClient(cfg *Config) *Client {
 		}
 	}
 
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if cfg.LocalAddr != "" {
+		dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(cfg.LocalAddr)}
+	}
 	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
 		MaxIdleConns:        cfg.MaxIdleConns,
 		MaxIdleConnsPerHost: cfg.MaxConnsPerHost,
+		MaxConnsPerHost:     cfg.MaxActiveConnsPerHost,
+		DisableKeepAlives:   cfg.DisableKeepAlives,
 		IdleConnTimeout:     90 * time.Second,
 		TLSHandshakeTimeout: 10 * time.Second,
 		TLSClientConfig: &tls.Config{
@@ -45,12 +70,26 @@ func NewClient(cfg *Config) *Client {
 			MinVersion:         tls.VersionTLS12,
 		},
 	}
+	if cfg.TLSSessionResumption {
+		transport.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
 
-	if cfg.EnableHTTP2 {
-		http2.ConfigureTransport(transport)
+	switch cfg.Protocol {
+	case "http1":
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP1(true)
+	case "http2":
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	default:
+		if cfg.EnableHTTP2 {
+			http2.ConfigureTransport(transport)
+		}
 	}
 
 	return &Client{
+		transport: transport,
 		client: &http.Client{
 			Transport: transport,
 			Timeout:   cfg.Timeout,
@@ -81,6 +120,13 @@ func (c *Client) Do(ctx context.Context, opts *RequestOptions) (*Response, error
 		bodyReader = bytes.NewReader(opts.Body)
 	}
 
+	var reused bool
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			reused = info.Reused
+		},
+	})
+
 	req, err := http.NewRequestWithContext(ctx, opts.Method, opts.URL, bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
@@ -119,9 +165,15 @@ func (c *Client) Do(ctx context.Context, opts *RequestOptions) (*Response, error
 		Time:       duration,
 		Size:       int64(len(body)),
 		Proto:      resp.Proto,
+		Reused:     reused,
 	}, nil
 }
 
+// CloseIdleConnections closes connections that are not in use.
+func (c *Client) CloseIdleConnections() {
+	c.transport.CloseIdleConnections()
+}
+
 type Response struct {
 	StatusCode int
 	Status     string
@@ -130,4 +182,6 @@ type Response struct {
 	Time       time.Duration
 	Size       int64
 	Proto      string
+	// Reused is set when the request went over an existing connection.
+	Reused bool
 }
@@ -0,0 +1,104 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	nexushttp "github.com/nexusapi/nexus/pkg/http"
+)
+
+func get(t *testing.T, c *nexushttp.Client, url string) *nexushttp.Response {
+	t.Helper()
+	resp, err := c.Do(context.Background(), &nexushttp.RequestOptions{Method: "GET", URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestClientKeepAlive(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := nexushttp.NewClient(&nexushttp.Config{MaxIdleConns: 10, MaxConnsPerHost: 10})
+	if get(t, c, ts.URL).Reused || !get(t, c, ts.URL).Reused {
+		t.Error("second request should reuse the first connection")
+	}
+
+	c = nexushttp.NewClient(&nexushttp.Config{DisableKeepAlives: true})
+	for i := 0; i < 3; i++ {
+		if get(t, c, ts.URL).Reused {
+			t.Errorf("request %d reused a connection without keep-alive", i+1)
+		}
+	}
+}
+
+func TestClientProtocol(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	for protocol, want := range map[string]string{"": "HTTP/2.0", "http1": "HTTP/1.1", "http2": "HTTP/2.0"} {
+		c := nexushttp.NewClient(&nexushttp.Config{InsecureSkipVerify: true, EnableHTTP2: true, Protocol: protocol})
+		if got := string(get(t, c, ts.URL).Body); got != want {
+			t.Errorf("protocol %q: server saw %s, want %s", protocol, got, want)
+		}
+	}
+
+	// HTTP/2 without TLS.
+	h2c := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	h2c.Config.Protocols = new(http.Protocols)
+	h2c.Config.Protocols.SetHTTP1(true)
+	h2c.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2c.Start()
+	defer h2c.Close()
+	if got := string(get(t, nexushttp.NewClient(&nexushttp.Config{Protocol: "http2"}), h2c.URL).Body); got != "HTTP/2.0" {
+		t.Errorf("h2c: server saw %s", got)
+	}
+}
+
+func TestClientTLSSessionResumption(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS.DidResume {
+			io.WriteString(w, "resumed")
+		}
+	}))
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	for _, resume := range []bool{false, true} {
+		c := nexushttp.NewClient(&nexushttp.Config{InsecureSkipVerify: true, DisableKeepAlives: true, TLSSessionResumption: resume})
+		get(t, c, ts.URL)
+		if got := string(get(t, c, ts.URL).Body) == "resumed"; got != resume {
+			t.Errorf("resumption %v: second connection resumed = %v", resume, got)
+		}
+	}
+}
+
+func TestClientLocalAddr(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		io.WriteString(w, host)
+	}))
+	defer ts.Close()
+
+	c := nexushttp.NewClient(&nexushttp.Config{LocalAddr: "127.0.0.2"})
+	resp, err := c.Do(context.Background(), &nexushttp.RequestOptions{Method: "GET", URL: ts.URL})
+	if err != nil {
+		t.Skipf("cannot bind 127.0.0.2: %v", err)
+	}
+	if string(resp.Body) != "127.0.0.2" {
+		t.Errorf("server saw %s", resp.Body)
+	}
+}
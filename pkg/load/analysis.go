@@ -0,0 +1,339 @@
+package load
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Drift is the trend of one metric over the steady phase of a soak test,
+// from a least-squares fit of its per-second values.
+type Drift struct {
+	// Metric is p95, rps, error_rate or memory.
+	Metric string `json:"metric"`
+	// Start and End are the fitted values at either end of the steady
+	// phase: milliseconds, requests per second, percent or bytes.
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// Change is the move from Start to End in percent, or in percentage
+	// points for error_rate; PerHour is the same per hour.
+	Change  float64 `json:"change"`
+	PerHour float64 `json:"per_hour"`
+	// Drifting is set when the metric got worse by more than the limit.
+	Drifting bool `json:"drifting"`
+}
+
+func (d Drift) String() string {
+	mark := "✅"
+	if d.Drifting {
+		mark = "❌"
+	}
+	unit := "%"
+	if d.Metric == "error_rate" {
+		unit = "pp"
+	}
+	return fmt.Sprintf("%s %s %s → %s (%+.1f%s, %+.1f%s/h)",
+		mark, d.Metric, d.format(d.Start), d.format(d.End), d.Change, unit, d.PerHour, unit)
+}
+
+func (d Drift) format(v float64) string {
+	switch d.Metric {
+	case "p95":
+		return time.Duration(v * float64(time.Millisecond)).Round(time.Microsecond).String()
+	case "rps":
+		return fmt.Sprintf("%.1f/s", v)
+	case "error_rate":
+		return fmt.Sprintf("%.2f%%", v)
+	default:
+		return fmt.Sprintf("%.1fMB", v/(1<<20))
+	}
+}
+
+// SpikeReport compares a spike test's peak with its baseline.
+type SpikeReport struct {
+	BaselineP95       time.Duration
+	PeakP95           time.Duration
+	BaselineErrorRate float64
+	PeakErrorRate     float64
+	// Recovery is how long after the load dropped back the p95 and error
+	// rate returned to within 20% (or 5ms) and one percentage point of the
+	// baseline for three seconds running.
+	Recovered bool
+	Recovery  time.Duration
+}
+
+func (s *SpikeReport) String() string {
+	recovery := "❌ did not recover"
+	if s.Recovered {
+		recovery = fmt.Sprintf("✅ recovered in %v", s.Recovery.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("  p95: %v baseline, %v at peak\n  Error rate: %.2f%% baseline, %.2f%% at peak\n  %s",
+		s.BaselineP95.Round(time.Microsecond), s.PeakP95.Round(time.Microsecond),
+		s.BaselineErrorRate, s.PeakErrorRate, recovery)
+}
+
+// BreakpointReport is where a breakpoint test broke.
+type BreakpointReport struct {
+	Broken bool
+	// At is when the first of the broken seconds began, Rate the arrival
+	// rate per second scheduled then, and Reason what broke.
+	At     time.Duration
+	Rate   float64
+	Reason string
+	// MaxRPS is the highest rate of successful requests, averaged over
+	// five seconds, before the system broke.
+	MaxRPS float64
+}
+
+func (b *BreakpointReport) String() string {
+	if !b.Broken {
+		return fmt.Sprintf("  ✅ did not break; max sustainable RPS at least %.1f", b.MaxRPS)
+	}
+	return fmt.Sprintf("  ❌ broke after %v at %.1f iterations/s: %s\n  Max sustainable RPS: %.1f",
+		b.At.Round(time.Second), b.Rate, b.Reason, b.MaxRPS)
+}
+
+const (
+	// breakSeconds broken seconds in a row end a breakpoint test.
+	breakSeconds = 3
+	// rpsWindow is how many seconds MaxRPS is averaged over.
+	rpsWindow = 5
+)
+
+// findBreak returns the index of the first of breakSeconds consecutive
+// broken samples and why they broke, or -1.
+func findBreak(series []Sample, limits PresetLimits) (int, string) {
+	run := 0
+	for i, s := range series {
+		reason := brokenSample(s, limits)
+		if reason == "" {
+			run = 0
+			continue
+		}
+		if run++; run == breakSeconds {
+			first := i - breakSeconds + 1
+			return first, brokenSample(series[first], limits)
+		}
+	}
+	return -1, ""
+}
+
+func brokenSample(s Sample, limits PresetLimits) string {
+	switch {
+	case s.Dropped > 0:
+		return fmt.Sprintf("%d iterations found no free VU", s.Dropped)
+	case s.Requests == 0:
+		return ""
+	case s.P95 > limits.P95:
+		return fmt.Sprintf("p95 %v > %v", s.P95.Round(time.Microsecond), limits.P95)
+	}
+	if rate := percent(s.Failed, s.Requests); rate > limits.ErrorRate {
+		return fmt.Sprintf("error rate %.2f%% > %.2f%%", rate, limits.ErrorRate)
+	}
+	return ""
+}
+
+// checkBreak ends a breakpoint test once it has broken. Iterations in
+// flight still get their grace period.
+func (e *Engine) checkBreak() {
+	if e.config.Preset != PresetBreakpoint || e.broken {
+		return
+	}
+	e.mu.Lock()
+	i, _ := findBreak(e.series, e.config.Limits)
+	e.mu.Unlock()
+	if i >= 0 {
+		e.broken = true
+		e.cancel()
+	}
+}
+
+// analyze adds the analysis of cfg's preset, if any, to r. start is when
+// the run began; memory holds the target's memory readings of a soak test.
+func (r *LoadTestResult) analyze(cfg Config, start time.Time, memory []MemoryReading) {
+	switch cfg.Preset {
+	case PresetSoak:
+		r.Drift = soakDrift(r.TimeSeries, memory, cfg.Limits)
+	case PresetSpike:
+		r.Spike = spikeReport(r.TimeSeries, cfg, start)
+	case PresetBreakpoint:
+		r.Breakpoint = breakpointReport(r.TimeSeries, cfg, start)
+	}
+}
+
+// soakDrift fits each metric over the steady phase, taken to be the run
+// less its first and last tenth.
+func soakDrift(series []Sample, memory []MemoryReading, limits PresetLimits) []Drift {
+	if len(series) == 0 {
+		return nil
+	}
+	first, last := series[0].Time, series[len(series)-1].Time
+	from, to := first.Add(last.Sub(first)/10), last.Add(-last.Sub(first)/10)
+	steady := func(t time.Time) bool { return !t.Before(from) && !t.After(to) }
+
+	var p95, rps, errs, mem []point
+	for _, s := range series {
+		if !steady(s.Time) {
+			continue
+		}
+		x := s.Time.Sub(from).Seconds()
+		rps = append(rps, point{x, s.RPS})
+		if s.Requests > 0 {
+			p95 = append(p95, point{x, float64(s.P95) / float64(time.Millisecond)})
+			errs = append(errs, point{x, percent(s.Failed, s.Requests)})
+		}
+	}
+	for _, m := range memory {
+		if steady(m.Time) {
+			mem = append(mem, point{m.Time.Sub(from).Seconds(), m.Bytes})
+		}
+	}
+
+	hours := to.Sub(from).Hours()
+	var drifts []Drift
+	add := func(metric string, points []point, worse float64) {
+		if len(points) < 3 || hours <= 0 {
+			return
+		}
+		slope, intercept := fit(points)
+		d := Drift{Metric: metric, Start: intercept, End: intercept + slope*to.Sub(from).Seconds()}
+		limit := limits.Drift
+		if metric == "error_rate" {
+			d.Change = d.End - d.Start
+			limit /= 20
+		} else if d.Start != 0 {
+			d.Change = (d.End - d.Start) / math.Abs(d.Start) * 100
+		}
+		d.PerHour = d.Change / hours
+		d.Drifting = d.Change*worse > limit
+		drifts = append(drifts, d)
+	}
+	add("p95", p95, 1)
+	add("rps", rps, -1)
+	add("error_rate", errs, 1)
+	add("memory", mem, 1)
+	return drifts
+}
+
+type point struct{ x, y float64 }
+
+// fit returns the least-squares line through points.
+func fit(points []point) (slope, intercept float64) {
+	var sx, sy, sxx, sxy float64
+	for _, p := range points {
+		sx += p.x
+		sy += p.y
+		sxx += p.x * p.x
+		sxy += p.x * p.y
+	}
+	n := float64(len(points))
+	if d := n*sxx - sx*sx; d != 0 {
+		slope = (n*sxy - sx*sy) / d
+	}
+	return slope, (sy - slope*sx) / n
+}
+
+// spikeReport finds the spike in cfg's stages: it starts with the first
+// stage that climbs above the starting VUs and ends with the first one
+// after it that comes down.
+func spikeReport(series []Sample, cfg Config, start time.Time) *SpikeReport {
+	var rise, peakFrom, fall, settled time.Duration
+	var elapsed time.Duration
+	level, phase := cfg.VirtualUsers, 0
+	for _, st := range cfg.Stages {
+		switch {
+		case phase == 0 && st.Target > level:
+			rise, peakFrom, phase = elapsed, elapsed+st.Duration, 1
+		case phase == 1 && st.Target < level:
+			fall, settled, phase = elapsed, elapsed+st.Duration, 2
+		}
+		elapsed += st.Duration
+		level = st.Target
+	}
+	if phase != 2 {
+		return nil
+	}
+
+	// A sample covers the second before its time.
+	began := func(s Sample) time.Duration { return s.Time.Sub(start) - time.Second }
+	var base, peak []Sample
+	for _, s := range series {
+		switch at := began(s); {
+		case at >= 0 && at+time.Second <= rise:
+			base = append(base, s)
+		case at >= peakFrom && at+time.Second <= fall:
+			peak = append(peak, s)
+		}
+	}
+	r := &SpikeReport{}
+	r.BaselineP95, r.BaselineErrorRate = phaseStats(base)
+	r.PeakP95, r.PeakErrorRate = phaseStats(peak)
+
+	tolerance := max(r.BaselineP95/5, 5*time.Millisecond)
+	run := 0
+	for _, s := range series {
+		at := began(s)
+		if at < settled {
+			continue
+		}
+		healthy := s.Requests > 0 && s.P95 <= r.BaselineP95+tolerance &&
+			percent(s.Failed, s.Requests) <= r.BaselineErrorRate+1
+		if !healthy {
+			run = 0
+			continue
+		}
+		if run++; run == breakSeconds {
+			r.Recovered = true
+			r.Recovery = max(at-time.Duration(breakSeconds-1)*time.Second-settled, 0)
+			break
+		}
+	}
+	return r
+}
+
+// phaseStats averages the p95 of samples, weighted by requests, and
+// returns their error rate.
+func phaseStats(samples []Sample) (time.Duration, float64) {
+	var requests, failed int64
+	var p95 float64
+	for _, s := range samples {
+		requests += s.Requests
+		failed += s.Failed
+		p95 += float64(s.P95) * float64(s.Requests)
+	}
+	if requests == 0 {
+		return 0, 0
+	}
+	return time.Duration(p95 / float64(requests)), percent(failed, requests)
+}
+
+func breakpointReport(series []Sample, cfg Config, start time.Time) *BreakpointReport {
+	r := &BreakpointReport{}
+	sustained := series
+	if i, reason := findBreak(series, cfg.Limits); i >= 0 {
+		r.Broken, r.Reason = true, reason
+		r.At = max(series[i].Time.Sub(start)-time.Second, 0)
+		r.Rate = stageValue(cfg.Rate, cfg.Stages, r.At) / cfg.TimeUnit.Seconds()
+		sustained = series[:i]
+	}
+	// Samples are timed rather than counted as a second each, since the
+	// last one of a run is shorter.
+	for i := range sustained {
+		from := max(i-rpsWindow+1, 0)
+		if i-from+1 < rpsWindow && len(sustained) >= rpsWindow {
+			continue
+		}
+		began := start
+		if from > 0 {
+			began = sustained[from-1].Time
+		}
+		var ok int64
+		for _, s := range sustained[from : i+1] {
+			ok += s.Requests - s.Failed
+		}
+		if d := sustained[i].Time.Sub(began); d > 0 {
+			r.MaxRPS = max(r.MaxRPS, float64(ok)/d.Seconds())
+		}
+	}
+	return r
+}
@@ -0,0 +1,146 @@
+package load
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tolerance is how much worse a run may be than its baseline before
+// Compare calls it a regression.
+type Tolerance struct {
+	// Latency and RPS are relative changes in percent.
+	Latency float64
+	RPS     float64
+	// ErrorRate is a change in percentage points.
+	ErrorRate float64
+}
+
+var DefaultTolerance = Tolerance{Latency: 10, RPS: 10, ErrorRate: 1}
+
+// Delta compares one metric between two runs. Change is in percent, or in
+// percentage points for error_rate.
+type Delta struct {
+	Name       string
+	Metric     string
+	Base       float64
+	Current    float64
+	Change     float64
+	Regression bool
+}
+
+type Comparison struct {
+	Base, Current string
+	Deltas        []Delta
+	// Missing lists requests of the baseline that the current run lacks;
+	// each counts as a regression.
+	Missing []string
+}
+
+// Compare checks current against base, overall and for each request the
+// two runs have in common.
+func Compare(base, current *Summary, tol Tolerance) *Comparison {
+	c := &Comparison{Base: base.Title, Current: current.Title}
+	c.compareStats("total", base.Latency, current.Latency, tol)
+	c.add("total", "rps", base.RPS, current.RPS, tol.RPS, false)
+
+	endpoints := make(map[string]StatsSummary, len(current.Endpoints))
+	for _, e := range current.Endpoints {
+		endpoints[e.Scenario+"\x00"+e.Name] = e
+	}
+	for _, b := range base.Endpoints {
+		name := b.Name
+		if len(base.Scenarios) > 1 {
+			name = b.Scenario + " › " + b.Name
+		}
+		cur, ok := endpoints[b.Scenario+"\x00"+b.Name]
+		if !ok {
+			c.Missing = append(c.Missing, name)
+			continue
+		}
+		c.compareStats(name, b, cur, tol)
+	}
+	return c
+}
+
+func (c *Comparison) compareStats(name string, base, cur StatsSummary, tol Tolerance) {
+	c.add(name, "avg", base.Avg, cur.Avg, tol.Latency, true)
+	c.add(name, "p50", base.P50, cur.P50, tol.Latency, true)
+	c.add(name, "p95", base.P95, cur.P95, tol.Latency, true)
+	c.add(name, "p99", base.P99, cur.P99, tol.Latency, true)
+
+	d := Delta{Name: name, Metric: "error_rate", Base: base.ErrorRate(), Current: cur.ErrorRate()}
+	d.Change = d.Current - d.Base
+	d.Regression = d.Change > tol.ErrorRate
+	c.Deltas = append(c.Deltas, d)
+}
+
+// add records a relative change; higherIsWorse says which way is a
+// regression.
+func (c *Comparison) add(name, metric string, base, cur, tol float64, higherIsWorse bool) {
+	d := Delta{Name: name, Metric: metric, Base: base, Current: cur}
+	if base != 0 {
+		d.Change = (cur - base) / base * 100
+	}
+	if higherIsWorse {
+		d.Regression = d.Change > tol
+	} else {
+		d.Regression = -d.Change > tol
+	}
+	c.Deltas = append(c.Deltas, d)
+}
+
+// Regressions are the deltas outside the tolerance, followed by one
+// "missing" delta for each request the current run lacks.
+func (c *Comparison) Regressions() []Delta {
+	var out []Delta
+	for _, d := range c.Deltas {
+		if d.Regression {
+			out = append(out, d)
+		}
+	}
+	for _, name := range c.Missing {
+		out = append(out, Delta{Name: name, Metric: "missing", Regression: true})
+	}
+	return out
+}
+
+func (c *Comparison) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Comparing %s (base) with %s:\n", c.Base, c.Current)
+	fmt.Fprintf(&b, "  %-32s %-10s %12s %12s %10s\n", "NAME", "METRIC", "BASE", "CURRENT", "CHANGE")
+	for _, d := range c.Deltas {
+		mark := ""
+		if d.Regression {
+			mark = "  ❌"
+		}
+		fmt.Fprintf(&b, "  %-32s %-10s %12s %12s %10s%s\n",
+			d.Name, d.Metric, d.format(d.Base), d.format(d.Current), d.formatChange(), mark)
+	}
+	for _, name := range c.Missing {
+		fmt.Fprintf(&b, "  %-32s missing from the current run  ❌\n", name)
+	}
+	if n := len(c.Regressions()); n > 0 {
+		fmt.Fprintf(&b, "\n❌ %d regressions", n)
+	} else {
+		b.WriteString("\n✅ No regressions")
+	}
+	return b.String()
+}
+
+func (d Delta) format(v float64) string {
+	switch d.Metric {
+	case "rps":
+		return fmt.Sprintf("%.1f", v)
+	case "error_rate":
+		return fmt.Sprintf("%.2f%%", v)
+	default:
+		return fmt.Sprintf("%.2fms", v)
+	}
+}
+
+func (d Delta) formatChange() string {
+	if d.Metric == "error_rate" {
+		return fmt.Sprintf("%+.2fpp", d.Change)
+	}
+	return fmt.Sprintf("%+.1f%%", d.Change)
+}
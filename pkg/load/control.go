@@ -0,0 +1,78 @@
+package load
+
+import "context"
+
+// Pause stops VUs from starting new iterations until Resume. Iterations in
+// progress finish, and the test's clock keeps running.
+func (e *Engine) Pause() {
+	ch := make(chan struct{})
+	e.paused.CompareAndSwap(nil, &ch)
+}
+
+// Resume continues a paused test.
+func (e *Engine) Resume() {
+	if ch := e.paused.Swap(nil); ch != nil {
+		close(*ch)
+	}
+}
+
+func (e *Engine) Paused() bool {
+	return e.paused.Load() != nil
+}
+
+// waitResumed blocks while the test is paused and reports whether ctx is
+// still live.
+func (e *Engine) waitResumed(ctx context.Context) bool {
+	if ch := e.paused.Load(); ch != nil {
+		select {
+		case <-*ch:
+		case <-ctx.Done():
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// AddVUs changes the number of VUs of a running test by n and returns the
+// total added so far. Only added VUs can be taken away again. Arrival-rate
+// executors raise their MaxVUs instead.
+func (e *Engine) AddVUs(n int) int {
+	for {
+		cur := e.extraVUs.Load()
+		next := max(cur+int64(n), 0)
+		if e.extraVUs.CompareAndSwap(cur, next) {
+			return int(next)
+		}
+	}
+}
+
+// Why a run ended early; see LoadTestResult.StopReason.
+const (
+	StopInterrupted = "interrupted"
+	StopMaxDuration = "max duration"
+	StopRequested   = "stopped"
+)
+
+// Stop ends a running test early; Run returns the results so far. No new
+// iterations start, and those in flight get GracefulStop to finish. To cut
+// them short at once, use Abort.
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	cancel := e.cancel
+	e.stopped = cancel != nil
+	e.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Abort ends a running test at once: like Stop, but iterations in flight
+// are cut short and counted as interrupted.
+func (e *Engine) Abort() {
+	e.mu.Lock()
+	abort := e.abort
+	e.stopped = abort != nil
+	e.mu.Unlock()
+	if abort != nil {
+		abort(errAbortRequested)
+	}
+}
@@ -0,0 +1,136 @@
+package load_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEnginePauseResumeStop(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	engine := load.NewEngine(&load.Config{VirtualUsers: 2, Duration: time.Minute}, collection.NewRunner("dev"))
+	done := make(chan *load.LoadTestResult, 1)
+	go func() {
+		result, err := engine.RunScenarios(context.Background(), &load.Scenario{
+			Name: "ping", Requests: []collection.Request{req}, ThinkTime: 5 * time.Millisecond,
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- result
+	}()
+
+	waitFor(t, "requests", func() bool { return hits.Load() > 5 })
+	engine.Pause()
+	if !engine.Progress().Paused {
+		t.Error("Progress does not report the pause")
+	}
+	time.Sleep(50 * time.Millisecond)
+	paused := hits.Load()
+	time.Sleep(150 * time.Millisecond)
+	if n := hits.Load(); n != paused {
+		t.Errorf("%d requests while paused", n-paused)
+	}
+
+	engine.Resume()
+	waitFor(t, "requests after resume", func() bool { return hits.Load() > paused })
+
+	engine.Stop()
+	select {
+	case result := <-done:
+		if result.TotalRequests != hits.Load() || result.Statuses[0].Name != "200" {
+			t.Errorf("result = %+v", result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not end the run")
+	}
+}
+
+func TestEngineAddVUs(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	engine := load.NewEngine(&load.Config{VirtualUsers: 1, Duration: time.Minute}, collection.NewRunner("dev"))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		engine.RunScenarios(context.Background(), &load.Scenario{
+			Name: "ping", Requests: []collection.Request{req}, ThinkTime: 5 * time.Millisecond,
+		})
+	}()
+	defer func() {
+		engine.Stop()
+		<-done
+	}()
+
+	waitFor(t, "the first VU", func() bool { return engine.Progress().ActiveVUs == 1 })
+	if n := engine.AddVUs(3); n != 3 {
+		t.Errorf("AddVUs = %d, want 3", n)
+	}
+	waitFor(t, "4 VUs", func() bool { return engine.Progress().ActiveVUs == 4 })
+
+	// Only added VUs can be taken away.
+	if n := engine.AddVUs(-10); n != 0 {
+		t.Errorf("AddVUs = %d, want 0", n)
+	}
+	waitFor(t, "1 VU", func() bool { return engine.Progress().ActiveVUs == 1 })
+	if s := engine.Progress().Statuses; s["200"] == 0 {
+		t.Errorf("statuses = %v", s)
+	}
+}
+
+func TestEngineAbort(t *testing.T) {
+	var started atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	engine := load.NewEngine(&load.Config{VirtualUsers: 2, Duration: time.Minute, GracefulStop: 30 * time.Second}, collection.NewRunner("dev"))
+	done := make(chan *load.LoadTestResult, 1)
+	go func() {
+		result, err := engine.RunScenarios(context.Background(), &load.Scenario{
+			Name: "slow", Requests: []collection.Request{{Name: "slow", Method: "GET", URL: srv.URL}},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- result
+	}()
+
+	// Unlike Stop, Abort does not wait out GracefulStop for the
+	// iterations in flight.
+	waitFor(t, "requests in flight", func() bool { return started.Load() == 2 })
+	engine.Abort()
+	select {
+	case result := <-done:
+		if result.StopReason != load.StopRequested || result.TotalRequests != 0 {
+			t.Errorf("stop reason = %q, requests = %d", result.StopReason, result.TotalRequests)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Abort did not end the run")
+	}
+}
@@ -0,0 +1,549 @@
+package load
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+)
+
+// Coordinator splits a load test between agents and merges what they
+// report. It is an http.Handler; serve it and call Run:
+//
+//	POST /register         {"name": ...} -> {"id": ...}
+//	GET  /plan?agent=<id>  the agent's share, once every agent has joined
+//	POST /report?agent=<id> a metrics snapshot -> {"stop": bool}
+//
+// Agents report every second. One that stays silent for AgentTimeout is
+// marked lost; its last report still counts towards the result.
+type Coordinator struct {
+	// StartDelay gives agents time to fetch their plans so that they all
+	// start together. Defaults to 2s.
+	StartDelay   time.Duration
+	AgentTimeout time.Duration
+	// Variables are set on every agent's runner, e.g. the collection's
+	// environment.
+	Variables map[string]string
+
+	config    Config
+	agents    int
+	scenarios []*Scenario
+	mux       *http.ServeMux
+
+	mu      sync.Mutex
+	remotes []*remoteAgent
+	joined  chan struct{}
+	planned chan struct{}
+	started time.Time
+	stop    bool
+	agg     *Engine
+}
+
+type remoteAgent struct {
+	id, name string
+	plan     agentPlan
+	last     engineSnapshot
+	seen     time.Time
+	done     bool
+	lost     bool
+	err      string
+}
+
+type agentPlan struct {
+	Config    Config            `json:"config"`
+	Scenarios []*Scenario       `json:"scenarios"`
+	Variables map[string]string `json:"variables,omitempty"`
+	VUOffset  int               `json:"vuOffset"`
+	// StartIn is how long after receiving the plan the agent starts. It is
+	// relative so that clock skew between machines does not matter.
+	StartIn time.Duration `json:"startIn"`
+}
+
+type agentReport struct {
+	Snapshot engineSnapshot `json:"snapshot"`
+	Done     bool           `json:"done"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// AgentStatus describes one agent of a distributed run.
+type AgentStatus struct {
+	Name  string
+	VUs   int
+	Lost  bool
+	Error string
+}
+
+// NewCoordinator runs cfg split evenly over the given number of agents.
+// Thresholds and outputs are handled by the coordinator on the merged
+// metrics.
+func NewCoordinator(cfg *Config, agents int, scenarios ...*Scenario) *Coordinator {
+	c := &Coordinator{
+		StartDelay:   2 * time.Second,
+		AgentTimeout: 5 * time.Second,
+		config:       *cfg,
+		agents:       agents,
+		scenarios:    scenarios,
+		mux:          http.NewServeMux(),
+		joined:       make(chan struct{}, 1),
+		planned:      make(chan struct{}),
+	}
+	c.mux.HandleFunc("POST /register", c.handleRegister)
+	c.mux.HandleFunc("GET /plan", c.handlePlan)
+	c.mux.HandleFunc("POST /report", c.handleReport)
+	return c
+}
+
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mux.ServeHTTP(w, r)
+}
+
+func (c *Coordinator) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.remotes) >= c.agents {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "the test already has all its agents"})
+		return
+	}
+	id := strconv.Itoa(len(c.remotes) + 1)
+	if req.Name == "" {
+		req.Name = "agent-" + id
+	}
+	c.remotes = append(c.remotes, &remoteAgent{id: id, name: req.Name})
+	select {
+	case c.joined <- struct{}{}:
+	default:
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": id})
+}
+
+// handlePlan holds the request until plans are out, answering 204 after
+// 30s so that the agent asks again.
+func (c *Coordinator) handlePlan(w http.ResponseWriter, r *http.Request) {
+	ra := c.remote(r)
+	if ra == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown agent"})
+		return
+	}
+	select {
+	case <-c.planned:
+		c.mu.Lock()
+		plan := ra.plan
+		plan.StartIn = max(0, time.Until(c.started))
+		c.mu.Unlock()
+		writeJSON(w, http.StatusOK, plan)
+	case <-time.After(30 * time.Second):
+		w.WriteHeader(http.StatusNoContent)
+	case <-r.Context().Done():
+	}
+}
+
+func (c *Coordinator) handleReport(w http.ResponseWriter, r *http.Request) {
+	ra := c.remote(r)
+	if ra == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown agent"})
+		return
+	}
+	var report agentReport
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !ra.lost {
+		ra.last, ra.seen = report.Snapshot, time.Now()
+		ra.done, ra.err = report.Done, report.Error
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"stop": c.stop || ra.lost})
+}
+
+func (c *Coordinator) remote(r *http.Request) *remoteAgent {
+	id := r.URL.Query().Get("agent")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ra := range c.remotes {
+		if ra.id == id {
+			return ra
+		}
+	}
+	return nil
+}
+
+// Run waits for every agent to register, hands out the plans and follows
+// the test until each agent has finished or been lost. Cancelling ctx
+// asks the agents to stop early.
+func (c *Coordinator) Run(ctx context.Context) (*LoadTestResult, error) {
+	cfg, err := c.config.resolve()
+	if err != nil {
+		return nil, err
+	}
+	thresholds, err := parseThresholds(cfg.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTargets(thresholds, c.scenarios); err != nil {
+		return nil, err
+	}
+	if c.agents <= 0 {
+		return nil, fmt.Errorf("at least one agent is required")
+	}
+	shares := make([]Config, c.agents)
+	for i := range shares {
+		shares[i] = c.config.share(i, c.agents)
+		shares[i].Executor = cfg.Executor
+		if _, err := shares[i].resolve(); err != nil {
+			return nil, fmt.Errorf("cannot split the test over %d agents: %w", c.agents, err)
+		}
+	}
+
+	for {
+		c.mu.Lock()
+		n := len(c.remotes)
+		c.mu.Unlock()
+		if n >= c.agents {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for agents: %w", ctx.Err())
+		case <-c.joined:
+		}
+	}
+
+	c.mu.Lock()
+	startAt := time.Now().Add(c.StartDelay)
+	offset := 0
+	for i, ra := range c.remotes {
+		ra.plan = agentPlan{
+			Config:    shares[i],
+			Scenarios: c.scenarios,
+			Variables: c.Variables,
+			VUOffset:  offset,
+		}
+		ra.seen = startAt
+		offset += max(shares[i].VirtualUsers, shares[i].MaxVUs)
+	}
+	c.started = startAt
+	c.mu.Unlock()
+	close(c.planned)
+
+	var (
+		series      []Sample
+		prev        histSnapshot
+		prevFailed  int64
+		prevDropped int64
+		prevTime    = startAt
+		abortedBy   string
+		broken      bool
+	)
+	outputs := startOutputs(cfg.Outputs)
+	cancelled := ctx.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for finished := false; !finished; {
+		select {
+		case <-cancelled:
+			c.setStop()
+			cancelled = nil
+			continue
+		case <-ticker.C:
+		}
+		now := time.Now()
+		if now.Before(startAt) {
+			continue
+		}
+
+		// Each sample is the difference between two merged snapshots.
+		agg, active := c.aggregate(cfg, thresholds)
+		finished = active == 0
+		total, failed, dropped := agg.metrics.latency.snapshot(), agg.metrics.failed.Load(), agg.dropped.Load()
+		window := newHistogram()
+		window.merge(total.since(prev))
+		s := Sample{
+			Time:     now,
+			VUs:      int(agg.activeVUs.Load()),
+			Requests: window.count.Load(),
+			Failed:   failed - prevFailed,
+			Dropped:  dropped - prevDropped,
+			RPS:      float64(window.count.Load()) / now.Sub(prevTime).Seconds(),
+			Avg:      window.mean(),
+			P50:      window.percentile(0.50),
+			P95:      window.percentile(0.95),
+			P99:      window.percentile(0.99),
+			Max:      time.Duration(window.max.Load()),
+		}
+		prev, prevFailed, prevDropped, prevTime = total, failed, dropped, now
+		series = append(series, s)
+
+		elapsed := now.Sub(startAt)
+
+		for _, t := range thresholds {
+			if abortedBy == "" && t.Abort && elapsed >= t.AbortDelay && !agg.evaluate(t, elapsed).Passed {
+				abortedBy = t.Source
+				c.setStop()
+			}
+		}
+		if cfg.Preset == PresetBreakpoint && !broken {
+			if i, _ := findBreak(series, cfg.Limits); i >= 0 {
+				broken = true
+				c.setStop()
+			}
+		}
+		outputs.write(s)
+	}
+	outputErr := outputs.close()
+
+	agg, _ := c.aggregate(cfg, thresholds)
+	result := agg.result(time.Since(startAt), agg.runs)
+	result.TimeSeries, result.AbortedBy = series, abortedBy
+	result.analyze(cfg, startAt, cfg.Memory.Readings())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lost := 0
+	for _, ra := range c.remotes {
+		result.Agents = append(result.Agents, AgentStatus{
+			Name:  ra.name,
+			VUs:   max(ra.plan.Config.VirtualUsers, ra.plan.Config.MaxVUs),
+			Lost:  ra.lost,
+			Error: ra.err,
+		})
+		if ra.lost {
+			lost++
+		}
+	}
+	if lost == len(c.remotes) {
+		return result, fmt.Errorf("all %d agents were lost", lost)
+	}
+	return result, outputErr
+}
+
+// aggregate marks silent agents lost and merges every agent's latest
+// report. It returns the number of agents still running.
+func (c *Coordinator) aggregate(cfg Config, thresholds []Threshold) (*Engine, int) {
+	agg := NewEngine(&cfg, nil)
+	agg.thresholds = thresholds
+	for _, sc := range c.scenarios {
+		agg.runs = append(agg.runs, &scenarioRun{Scenario: sc, metrics: newMetrics()})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	active := 0
+	for _, ra := range c.remotes {
+		if !ra.done && !ra.lost && time.Since(ra.seen) > c.AgentTimeout {
+			ra.lost = true
+		}
+		if !ra.done && !ra.lost {
+			active++
+		}
+		agg.merge(ra.last)
+	}
+	agg.started = c.started
+	c.agg = agg
+	return agg, active
+}
+
+func (c *Coordinator) setStop() {
+	c.mu.Lock()
+	c.stop = true
+	c.mu.Unlock()
+}
+
+// Progress reports the merged state of the run as of the last report.
+func (c *Coordinator) Progress() Progress {
+	c.mu.Lock()
+	agg := c.agg
+	c.mu.Unlock()
+	if agg == nil {
+		return Progress{}
+	}
+	return agg.Progress()
+}
+
+// share is agent i's part of c when split n ways; counts that do not
+// divide evenly go to the first agents.
+func (c Config) share(i, n int) Config {
+	part := func(v int) int {
+		p := v / n
+		if i < v%n {
+			p++
+		}
+		return p
+	}
+	s := c
+	s.VirtualUsers = part(c.VirtualUsers)
+	s.Iterations = part(c.Iterations)
+	s.Rate = part(c.Rate)
+	s.PreAllocatedVUs = part(c.PreAllocatedVUs)
+	s.MaxVUs = part(c.MaxVUs)
+	s.Stages = make([]Stage, len(c.Stages))
+	for j, st := range c.Stages {
+		s.Stages[j] = Stage{Duration: st.Duration, Target: part(st.Target)}
+	}
+	if len(c.Stages) == 0 {
+		s.Stages = nil
+	}
+	// The coordinator collects, checks and analyses the merged results.
+	s.Outputs, s.Thresholds = nil, nil
+	s.Preset, s.Memory = "", nil
+	return s
+}
+
+// Agent runs its share of a distributed test for a coordinator.
+type Agent struct {
+	// Coordinator is the coordinator's base URL, e.g. http://host:7070.
+	Coordinator string
+	Name        string
+	Runner      *collection.Runner
+
+	client *http.Client
+	id     string
+}
+
+func NewAgent(coordinator, name string, runner *collection.Runner) *Agent {
+	return &Agent{
+		Coordinator: strings.TrimRight(coordinator, "/"),
+		Name:        name,
+		Runner:      runner,
+		client:      &http.Client{Timeout: 40 * time.Second},
+	}
+}
+
+// Run registers with the coordinator, waiting for it to come up, then
+// runs the plan it is given and reports every second until done. It
+// returns the agent's own part of the results.
+func (a *Agent) Run(ctx context.Context) (*LoadTestResult, error) {
+	for {
+		var resp struct {
+			ID string `json:"id"`
+		}
+		err := a.call(ctx, http.MethodPost, "/register", map[string]string{"name": a.Name}, &resp)
+		if err == nil {
+			a.id = resp.ID
+			break
+		}
+		// Keep trying while the coordinator is not up yet.
+		var urlErr *url.Error
+		if !errors.As(err, &urlErr) || !sleepContext(ctx, time.Second) {
+			return nil, fmt.Errorf("register: %w", err)
+		}
+	}
+
+	var plan *agentPlan
+	for plan == nil {
+		if err := a.call(ctx, http.MethodGet, "/plan?agent="+a.id, nil, &plan); err != nil {
+			return nil, fmt.Errorf("get plan: %w", err)
+		}
+	}
+	startAt := time.Now().Add(plan.StartIn)
+
+	runner := a.Runner.Clone()
+	for k, v := range plan.Variables {
+		runner.Resolver.SetVariable(k, v)
+	}
+	engine := NewEngine(&plan.Config, runner)
+	engine.vuOffset = plan.VUOffset
+	if !sleepContext(ctx, time.Until(startAt)) {
+		return nil, ctx.Err()
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type outcome struct {
+		result *LoadTestResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		r, err := engine.RunScenarios(runCtx, plan.Scenarios...)
+		done <- outcome{r, err}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case o := <-done:
+			report := agentReport{Snapshot: engine.snapshot(), Done: true}
+			if o.err != nil {
+				report.Error = o.err.Error()
+			}
+			// The final report carries the complete numbers, so retry it.
+			for i := 0; i < 3; i++ {
+				if err := a.report(context.Background(), report); err == nil {
+					break
+				}
+				time.Sleep(time.Second)
+			}
+			return o.result, o.err
+		case <-ticker.C:
+			// A stop from the coordinator ends the run gracefully, like
+			// Ctrl-C on a local one; cancel is for ctx going away.
+			if stop, _ := a.reportStop(runCtx, agentReport{Snapshot: engine.snapshot()}); stop {
+				engine.Stop()
+			}
+		}
+	}
+}
+
+func (a *Agent) report(ctx context.Context, report agentReport) error {
+	_, err := a.reportStop(ctx, report)
+	return err
+}
+
+func (a *Agent) reportStop(ctx context.Context, report agentReport) (bool, error) {
+	var resp struct {
+		Stop bool `json:"stop"`
+	}
+	err := a.call(ctx, http.MethodPost, "/report?agent="+a.id, report, &resp)
+	return resp.Stop, err
+}
+
+func (a *Agent) call(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.Coordinator+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return nil
+	case resp.StatusCode/100 != 2:
+		return fmt.Errorf("coordinator returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,201 @@
+package load_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+func TestDistributedRunMergesAgents(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	coord := load.NewCoordinator(&load.Config{
+		VirtualUsers: 6,
+		Iterations:   90,
+		Thresholds:   []string{"ping: requests == 90", "p99 < 10s"},
+	}, 3, &load.Scenario{Name: "ping", Requests: []collection.Request{req}})
+	coord.StartDelay = 200 * time.Millisecond
+	srv := httptest.NewServer(coord)
+	t.Cleanup(srv.Close)
+
+	var wg sync.WaitGroup
+	vus := make([]string, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			agent := load.NewAgent(srv.URL, "", collection.NewRunner("dev"))
+			if _, err := agent.Run(context.Background()); err != nil {
+				t.Errorf("agent %d: %v", i, err)
+			}
+		}(i)
+	}
+
+	result, err := coord.Run(context.Background())
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.TotalRequests != 90 || hits.Load() != 90 {
+		t.Errorf("merged requests = %d, hits = %d, want 90", result.TotalRequests, hits.Load())
+	}
+	if result.Iterations != 90 || len(result.Requests) != 1 || result.Requests[0].Count != 90 {
+		t.Errorf("iterations = %d, requests = %+v", result.Iterations, result.Requests)
+	}
+	if result.P99Latency <= 0 || result.P99Latency < result.P50Latency {
+		t.Errorf("merged p50 = %v, p99 = %v", result.P50Latency, result.P99Latency)
+	}
+	if !result.ThresholdsPassed() {
+		t.Errorf("thresholds: %+v", result.Thresholds)
+	}
+	if len(result.Agents) != 3 {
+		t.Fatalf("agents = %+v", result.Agents)
+	}
+	for i, a := range result.Agents {
+		if a.Lost || a.Error != "" || a.VUs != 2 {
+			t.Errorf("agent %+v", a)
+		}
+		vus[i] = a.Name
+	}
+	if vus[0] != "agent-1" || vus[2] != "agent-3" {
+		t.Errorf("agent names = %v", vus)
+	}
+
+	// The test already has all its agents.
+	late := load.NewAgent(srv.URL, "late", collection.NewRunner("dev"))
+	if _, err := late.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "409") {
+		t.Errorf("late agent err = %v", err)
+	}
+}
+
+func TestDistributedAgentLoss(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	coord := load.NewCoordinator(&load.Config{
+		VirtualUsers: 2,
+		Duration:     1500 * time.Millisecond,
+	}, 2, &load.Scenario{Name: "ping", Requests: []collection.Request{req}})
+	coord.StartDelay = 100 * time.Millisecond
+	coord.AgentTimeout = 1500 * time.Millisecond
+	srv := httptest.NewServer(coord)
+	t.Cleanup(srv.Close)
+
+	// A healthy agent, and one that registers, takes its plan and vanishes.
+	done := make(chan error, 1)
+	go func() {
+		_, err := load.NewAgent(srv.URL, "healthy", collection.NewRunner("dev")).Run(context.Background())
+		done <- err
+	}()
+	go func() {
+		resp, err := http.Post(srv.URL+"/register", "application/json", strings.NewReader(`{"name":"ghost"}`))
+		if err != nil {
+			return
+		}
+		var reg struct{ ID string }
+		json.NewDecoder(resp.Body).Decode(&reg)
+		resp.Body.Close()
+		if resp, err := http.Get(srv.URL + "/plan?agent=" + reg.ID); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	result, err := coord.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	lost := map[string]bool{}
+	for _, a := range result.Agents {
+		lost[a.Name] = a.Lost
+	}
+	if len(lost) != 2 || lost["healthy"] || !lost["ghost"] {
+		t.Errorf("agents = %+v", result.Agents)
+	}
+	if result.TotalRequests == 0 || result.TotalRequests != hits.Load() {
+		t.Errorf("requests = %d, hits = %d", result.TotalRequests, hits.Load())
+	}
+	if !strings.Contains(result.String(), "❌ ghost (1 VUs) lost") {
+		t.Errorf("summary:\n%s", result)
+	}
+}
+
+func TestDistributedStartAndStop(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	coord := load.NewCoordinator(&load.Config{
+		VirtualUsers: 2,
+		Duration:     time.Minute,
+	}, 2, &load.Scenario{Name: "ping", Requests: []collection.Request{req}})
+	coord.StartDelay = 300 * time.Millisecond
+	coord.AgentTimeout = 1500 * time.Millisecond
+	srv := httptest.NewServer(coord)
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type outcome struct {
+		result *load.LoadTestResult
+		err    error
+	}
+	agentDone := make(chan outcome, 1)
+	go func() {
+		r, err := load.NewAgent(srv.URL, "worker", collection.NewRunner("dev")).Run(context.Background())
+		agentDone <- outcome{r, err}
+	}()
+	coordDone := make(chan outcome, 1)
+	go func() {
+		r, err := coord.Run(ctx)
+		coordDone <- outcome{r, err}
+	}()
+
+	// The start time is sent relative to when the plan is served, so an
+	// agent whose clock is off still starts on time.
+	resp, err := http.Post(srv.URL+"/register", "application/json", strings.NewReader(`{"name":"probe"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reg struct{ ID string }
+	json.NewDecoder(resp.Body).Decode(&reg)
+	resp.Body.Close()
+	resp, err = http.Get(srv.URL + "/plan?agent=" + reg.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&plan)
+	resp.Body.Close()
+	startIn, _ := plan["startIn"].(float64)
+	if _, absolute := plan["startAt"]; absolute || startIn <= 0 || time.Duration(startIn) > coord.StartDelay {
+		t.Fatalf("plan start = %v", plan)
+	}
+
+	// Stopping the coordinator stops the agents gracefully.
+	time.Sleep(coord.StartDelay + 1500*time.Millisecond)
+	cancel()
+	a := <-agentDone
+	if a.err != nil {
+		t.Fatal(a.err)
+	}
+	if a.result.StopReason != load.StopRequested {
+		t.Errorf("agent stop reason = %q, want %q", a.result.StopReason, load.StopRequested)
+	}
+	if c := <-coordDone; c.err != nil {
+		t.Fatal(c.err)
+	}
+}
@@ -2,199 +2,435 @@ package load
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/nexusapi/nexus/pkg/collection"
+	nexushttp "github.com/nexusapi/nexus/pkg/http"
 )
 
 type Config struct {
+	// Executor defaults to ramping-vus when Stages are set,
+	// constant-arrival-rate when Rate is, and constant-vus otherwise.
+	Executor     Executor
 	VirtualUsers int
 	Duration     time.Duration
 	RampUp       time.Duration
 	RampDown     time.Duration
-	Iterations   int
+	// Iterations is the total number of scenario iterations across all VUs.
+	Iterations int
+	Stages     []Stage
+
+	// Rate is the iterations started per TimeUnit (default 1s) by the
+	// arrival-rate executors.
+	Rate     int
+	TimeUnit time.Duration
+	// PreAllocatedVUs (default VirtualUsers) are started up front; the pool
+	// grows on demand up to MaxVUs.
+	PreAllocatedVUs int
+	MaxVUs          int
+
+	// Outputs receive a Sample every second while the test runs.
+	Outputs []Output
+	// Thresholds are checked at the end of the run; see Threshold.
+	Thresholds []string
+
+	// CheckRate is the fraction of responses checked against their
+	// request's tests and assertions (default 1); SkipChecks checks none.
+	CheckRate  float64
+	SkipChecks bool
+	// FailureSamples is how many failed requests of each kind are kept
+	// for debugging (default 5; negative keeps none).
+	FailureSamples int
+
+	Transport *Transport
+
+	// GracefulStop is how long iterations in flight when the test ends or
+	// Stop is called may take to finish before they are cut short
+	// (default 30s; negative cuts them short at once).
+	GracefulStop time.Duration
+	// MaxDuration ends the run, in-flight iterations included, if it is
+	// still going after this long. It guards iteration-count runs against
+	// a target that stops answering.
+	MaxDuration time.Duration
+
+	// Preset is the built-in test the config came from, if any; the
+	// result gets its analysis, tuned by Limits. Memory, if set, reads the
+	// target's memory use for a soak test's analysis.
+	Preset Preset
+	Limits PresetLimits
+	Memory *MemoryProbe
 }
 
 type Engine struct {
 	config  *Config
 	runner  *collection.Runner
 	metrics *Metrics
-}
 
-type Metrics struct {
-	totalRequests   atomic.Int64
-	successRequests atomic.Int64
-	failedRequests  atomic.Int64
-	totalLatency    atomic.Int64
-	minLatency      atomic.Int64
-	maxLatency      atomic.Int64
-	mu              sync.RWMutex
-	latencies       []time.Duration
+	started    time.Time
+	activeVUs  atomic.Int64
+	iterations atomic.Int64
+	dropped    atomic.Int64
+	// interrupted counts iterations cut short by the end of the run.
+	interrupted atomic.Int64
+	// newConns and reusedConns count responses by connection.
+	newConns    atomic.Int64
+	reusedConns atomic.Int64
+
+	window         atomic.Pointer[window]
+	flushedDropped int64
+	outputErr      error
+
+	runs       []*scenarioRun
+	thresholds []Threshold
+	// cancel ends the schedule, letting iterations in flight finish;
+	// abort ends inflight, the context of those iterations.
+	cancel    context.CancelFunc
+	abort     context.CancelCauseFunc
+	inflight  context.Context
+	stopped   bool
+	broken    bool
+	abortedBy string
+	// vuOffset numbers an agent's VUs after those of the agents before it.
+	vuOffset int
+	// paused is closed on Resume; nil while running.
+	paused   atomic.Pointer[chan struct{}]
+	extraVUs atomic.Int64
+
+	mu       sync.Mutex
+	requests map[requestKey]*Metrics
+	series   []Sample
+	failures []FailureSample
+	clients  map[int]*nexushttp.Client
 }
 
+type requestKey struct{ scenario, request string }
+
 func NewEngine(cfg *Config, runner *collection.Runner) *Engine {
 	return &Engine{
-		config:  cfg,
-		runner:  runner,
-		metrics: &Metrics{latencies: make([]time.Duration, 0, 1000)},
+		config:   cfg,
+		runner:   runner,
+		metrics:  newMetrics(),
+		requests: make(map[requestKey]*Metrics),
+		clients:  make(map[int]*nexushttp.Client),
 	}
 }
 
+// Run drives a single request with the configured virtual users until
+// Duration has passed or Iterations requests have been made.
 func (e *Engine) Run(ctx context.Context, req collection.Request) (*LoadTestResult, error) {
-	startTime := time.Now()
-
-	workerCount := e.config.VirtualUsers
-	reqChan := make(chan struct{}, workerCount*10)
-
-	var wg sync.WaitGroup
-	for i := 0; i < workerCount; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			e.worker(ctx, req, reqChan)
-		}(i)
-	}
-
-	go e.distributeLoad(ctx, reqChan)
-
-	if e.config.Duration > 0 {
-		timer := time.NewTimer(e.config.Duration)
-		<-timer.C
-		close(reqChan)
-	} else if e.config.Iterations > 0 {
-		for i := 0; i < e.config.Iterations; i++ {
-			select {
-			case <-ctx.Done():
-				close(reqChan)
-				goto wait
-			default:
-				reqChan <- struct{}{}
-			}
+	return e.RunScenarios(ctx, &Scenario{Name: req.Name, Requests: []collection.Request{req}})
+}
+
+// RunScenarios splits the virtual users between scenarios by weight. Each
+// VU runs its scenario with its own copy of the runner's variables, as
+// scheduled by the executor, until Duration has passed or Iterations
+// iterations have run in total. If an output fails, the result is returned
+// along with the first output error.
+func (e *Engine) RunScenarios(ctx context.Context, scenarios ...*Scenario) (*LoadTestResult, error) {
+	cfg, err := e.config.resolve()
+	if err != nil {
+		return nil, err
+	}
+	e.config = &cfg
+	if e.thresholds, err = parseThresholds(cfg.Thresholds); err != nil {
+		return nil, err
+	}
+	if err := checkTargets(e.thresholds, scenarios); err != nil {
+		return nil, err
+	}
+	if cfg.Transport != nil {
+		defer e.closeClients()
+	}
+
+	var runs, slots []*scenarioRun
+	for _, sc := range scenarios {
+		if err := sc.validate(); err != nil {
+			return nil, err
 		}
-		close(reqChan)
-	}
-
-wait:
-	wg.Wait()
-
-	return &LoadTestResult{
-		TotalRequests:   e.metrics.totalRequests.Load(),
-		SuccessRequests: e.metrics.successRequests.Load(),
-		FailedRequests:  e.metrics.failedRequests.Load(),
-		Duration:        time.Since(startTime),
-		RPS:             float64(e.metrics.totalRequests.Load()) / time.Since(startTime).Seconds(),
-		AvgLatency:      e.calculateAvgLatency(),
-		MinLatency:      time.Duration(e.metrics.minLatency.Load()),
-		MaxLatency:      time.Duration(e.metrics.maxLatency.Load()),
-		P50Latency:      e.calculatePercentile(0.50),
-		P95Latency:      e.calculatePercentile(0.95),
-		P99Latency:      e.calculatePercentile(0.99),
-	}, nil
-}
-
-func (e *Engine) distributeLoad(ctx context.Context, reqChan chan struct{}) {
-	if e.config.RampUp > 0 {
-		interval := e.config.RampUp / time.Duration(e.config.VirtualUsers)
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-
-		for i := 0; i < e.config.VirtualUsers; i++ {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-			}
+		run := &scenarioRun{Scenario: sc, metrics: newMetrics()}
+		runs = append(runs, run)
+		for i := 0; i < max(sc.Weight, 1); i++ {
+			slots = append(slots, run)
 		}
 	}
-}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no scenarios to run")
+	}
 
-func (e *Engine) worker(ctx context.Context, req collection.Request, reqChan <-chan struct{}) {
-	for range reqChan {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			result := e.runner.ExecuteRequest(req)
-			e.recordMetrics(result)
-		}
+	// Iterations run under inflight, which ends with ctx, at MaxDuration,
+	// on an abort threshold or Abort, or once the grace period runs out. The
+	// executors schedule them under sched, which also ends after Duration
+	// or on Stop.
+	inflight, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	if cfg.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		inflight, cancel = context.WithTimeoutCause(inflight, cfg.MaxDuration, errMaxDuration)
+		defer cancel()
+	}
+	sched, cancel := context.WithCancel(inflight)
+	defer cancel()
+	e.mu.Lock()
+	e.runs, e.cancel, e.abort, e.inflight = runs, cancel, abort, inflight
+	e.mu.Unlock()
+	if cfg.Duration > 0 {
+		var cancelSched context.CancelFunc
+		sched, cancelSched = context.WithTimeout(sched, cfg.Duration)
+		defer cancelSched()
 	}
-}
 
-func (e *Engine) recordMetrics(result collection.ExecutionResult) {
-	e.metrics.totalRequests.Add(1)
+	e.mu.Lock()
+	e.started = time.Now()
+	e.mu.Unlock()
+	e.window.Store(newWindow(e.started))
+	stop, collected := make(chan struct{}), make(chan struct{})
+	go e.collect(stop, collected)
+	finished, graced := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(graced)
+		e.endGracefully(sched, finished, abort)
+	}()
 
-	if result.Error == nil && result.Response.StatusCode < 400 {
-		e.metrics.successRequests.Add(1)
-	} else {
-		e.metrics.failedRequests.Add(1)
+	switch cfg.Executor {
+	case ExecutorConstantVUs:
+		e.runConstantVUs(sched, slots)
+	case ExecutorRampingVUs:
+		e.runRampingVUs(sched, slots)
+	default:
+		e.runArrivalRate(sched, slots)
 	}
+	duration := time.Since(e.started)
+	close(finished)
+	<-graced
+	close(stop)
+	<-collected
 
-	latency := result.Response.Time
-	e.metrics.totalLatency.Add(int64(latency))
+	result := e.result(duration, runs)
+	result.analyze(cfg, e.started, cfg.Memory.Readings())
+	e.mu.Lock()
+	stopped := e.stopped
+	e.mu.Unlock()
+	switch {
+	case ctx.Err() != nil:
+		result.StopReason = StopInterrupted
+	case context.Cause(inflight) == errMaxDuration:
+		result.StopReason = StopMaxDuration
+	case stopped:
+		result.StopReason = StopRequested
+	}
+	return result, e.outputErr
+}
 
-	for {
-		min := e.metrics.minLatency.Load()
-		if min == 0 || int64(latency) < min {
-			if e.metrics.minLatency.CompareAndSwap(min, int64(latency)) {
-				break
-			}
-		} else {
-			break
-		}
+var (
+	errMaxDuration    = errors.New("max duration reached")
+	errGraceExpired   = errors.New("graceful stop period expired")
+	errAborted        = errors.New("abort threshold failed")
+	errAbortRequested = errors.New("run aborted")
+)
+
+// endGracefully cuts iterations short once GracefulStop has passed since
+// sched ended, unless the executor finishes first.
+func (e *Engine) endGracefully(sched context.Context, finished <-chan struct{}, abort context.CancelCauseFunc) {
+	select {
+	case <-sched.Done():
+	case <-finished:
+		return
 	}
+	grace := time.NewTimer(max(e.config.GracefulStop, 0))
+	defer grace.Stop()
+	select {
+	case <-grace.C:
+		abort(errGraceExpired)
+	case <-finished:
+	}
+}
 
-	for {
-		max := e.metrics.maxLatency.Load()
-		if int64(latency) > max {
-			if e.metrics.maxLatency.CompareAndSwap(max, int64(latency)) {
-				break
-			}
+// iterate runs every request of sc once, passing extracted values along,
+// and reports whether it finished. An iteration cut short by the end of
+// the run is not recorded, nor is a request it cancelled. Responses are
+// checked against their tests and assertions at the configured rate.
+//
+// Arrival-rate executors pass the time the iteration was scheduled for.
+// Any delay before it started is added to the first request's latency so
+// that a slow system cannot hide queueing (coordinated omission).
+func (e *Engine) iterate(ctx context.Context, runner *collection.Runner, sc *scenarioRun, scheduled time.Time) bool {
+	start := scheduled
+	if start.IsZero() {
+		start = time.Now()
+	}
+	lag := time.Since(start)
+	ok := true
+	for i, req := range sc.Requests {
+		if ctx.Err() != nil {
+			return false
+		}
+		result := runner.SendRequestContext(ctx, req)
+		if result.Error != nil && ctx.Err() != nil {
+			return false
+		}
+		if result.Error != nil {
+			result.Response.Time = result.EndTime.Sub(result.StartTime)
+		} else if !e.config.SkipChecks && (e.config.CheckRate >= 1 || rand.Float64() < e.config.CheckRate) {
+			result.Passed, result.Failures = runner.RunAssertions(req, result.Response)
+		}
+		if i == 0 {
+			result.Response.Time += lag
+		}
+		if e.recordMetrics(sc.Name, runner, result) {
+			runner.ExtractVariables(req, result.Response)
 		} else {
-			break
+			ok = false
+		}
+		if !sleepContext(ctx, sc.ThinkTime) {
+			return false
 		}
 	}
+	sc.metrics.record(time.Since(start), ok)
+	return true
+}
 
-	e.metrics.mu.Lock()
-	e.metrics.latencies = append(e.metrics.latencies, latency)
-	e.metrics.mu.Unlock()
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
 }
 
-func (e *Engine) calculateAvgLatency() time.Duration {
-	total := e.metrics.totalRequests.Load()
-	if total == 0 {
-		return 0
+// Progress is a snapshot of a running test.
+type Progress struct {
+	Elapsed   time.Duration
+	ActiveVUs int
+	Requests  int64
+	Failed    int64
+	RPS       float64
+	// Dropped counts arrival-rate iterations that found no free VU.
+	Dropped int64
+	// Statuses counts requests by status code, or "error" for requests
+	// that got no response.
+	Statuses map[string]int64
+	// Errors counts failed requests by kind, e.g. "timeout" or "5xx".
+	Errors map[string]int64
+	Paused bool
+}
+
+// Progress reports the state of the current run. It is safe to call while
+// Run is in progress.
+func (e *Engine) Progress() Progress {
+	p := Progress{
+		ActiveVUs: int(e.activeVUs.Load()),
+		Requests:  e.metrics.latency.count.Load(),
+		Failed:    e.metrics.failed.Load(),
+		Dropped:   e.dropped.Load(),
+		Statuses:  e.metrics.statusCounts(),
+		Errors:    e.metrics.failureCounts(),
+		Paused:    e.Paused(),
 	}
-	return time.Duration(e.metrics.totalLatency.Load() / total)
+	e.mu.Lock()
+	started := e.started
+	e.mu.Unlock()
+	if !started.IsZero() {
+		p.Elapsed = time.Since(started)
+		p.RPS = float64(p.Requests) / p.Elapsed.Seconds()
+	}
+	return p
 }
 
-func (e *Engine) calculatePercentile(p float64) time.Duration {
-	e.metrics.mu.RLock()
-	defer e.metrics.mu.RUnlock()
+// recordMetrics records result globally and under its request name, and
+// reports whether it succeeded. The first failures of each kind are kept.
+func (e *Engine) recordMetrics(scenario string, runner *collection.Runner, result collection.ExecutionResult) bool {
+	kind := classify(result)
+	ok := kind == ""
+	status := result.Response.StatusCode
+	if result.Error != nil {
+		status = 0
+	}
+	e.metrics.recordStatus(result.Response.Time, status, ok)
+	e.metrics.recordFailure(kind)
+	e.window.Load().record(result.Response.Time, ok)
+	if result.Error == nil {
+		e.recordConn(result.Response.Reused)
+	}
 
-	if len(e.metrics.latencies) == 0 {
-		return 0
+	key := requestKey{scenario, result.Request.Name}
+	e.mu.Lock()
+	m, exists := e.requests[key]
+	if !exists {
+		m = newMetrics()
+		e.requests[key] = m
+	}
+	if !ok && e.keepFailure(kind) {
+		url := runner.Resolver.Resolve(result.Request.URL)
+		e.failures = append(e.failures, newFailureSample(scenario, kind, url, result))
 	}
+	e.mu.Unlock()
+	m.recordStatus(result.Response.Time, status, ok)
+	m.recordFailure(kind)
+	return ok
+}
 
-	sorted := make([]time.Duration, len(e.metrics.latencies))
-	copy(sorted, e.metrics.latencies)
+func (e *Engine) result(duration time.Duration, runs []*scenarioRun) *LoadTestResult {
+	total := e.metrics.stats("")
+	r := &LoadTestResult{
+		TotalRequests:   total.Count,
+		SuccessRequests: total.Count - total.Failed,
+		FailedRequests:  total.Failed,
+		Duration:        duration,
+		RPS:             float64(total.Count) / duration.Seconds(),
+		AvgLatency:      total.Avg,
+		MinLatency:      total.Min,
+		MaxLatency:      total.Max,
+		P50Latency:      total.P50,
+		P95Latency:      total.P95,
+		P99Latency:      total.P99,
 
-	for i := 0; i < len(sorted); i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[i] > sorted[j] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
+		DroppedIterations: e.dropped.Load(),
+		Interrupted:       e.interrupted.Load(),
+		NewConnections:    e.newConns.Load(),
+		ReusedConnections: e.reusedConns.Load(),
+		Statuses:          total.Statuses,
+		Errors:            total.Errors,
+		TimeSeries:        append([]Sample(nil), e.series...),
+		AbortedBy:         e.abortedBy,
+	}
+	for _, t := range e.thresholds {
+		r.Thresholds = append(r.Thresholds, e.evaluate(t, duration))
 	}
 
-	idx := int(float64(len(sorted)) * p)
-	if idx >= len(sorted) {
-		idx = len(sorted) - 1
+	for _, run := range runs {
+		stats := run.metrics.stats(run.Name)
+		r.Iterations += stats.Count
+		r.Scenarios = append(r.Scenarios, stats)
 	}
 
-	return sorted[idx]
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r.FailureSamples = append([]FailureSample(nil), e.failures...)
+	for key, m := range e.requests {
+		stats := m.stats(key.request)
+		stats.Scenario = key.scenario
+		r.Requests = append(r.Requests, stats)
+	}
+	sort.Slice(r.Requests, func(i, j int) bool {
+		if r.Requests[i].Scenario != r.Requests[j].Scenario {
+			return r.Requests[i].Scenario < r.Requests[j].Scenario
+		}
+		return r.Requests[i].Name < r.Requests[j].Name
+	})
+	return r
 }
 
 type LoadTestResult struct {
@@ -209,10 +445,70 @@ type LoadTestResult struct {
 	P50Latency      time.Duration
 	P95Latency      time.Duration
 	P99Latency      time.Duration
+
+	// Iterations counts completed scenario iterations.
+	Iterations int64
+	// DroppedIterations were due under an arrival-rate executor but found
+	// the VU pool exhausted.
+	DroppedIterations int64
+	// Thresholds holds the outcome of each configured threshold.
+	Thresholds []ThresholdResult
+	// Interrupted counts iterations cut short by the end of the run.
+	Interrupted int64
+	// AbortedBy is the abort threshold that stopped the run early, if any.
+	AbortedBy string
+	// StopReason says why the run ended early other than by a threshold:
+	// StopInterrupted, StopMaxDuration or StopRequested.
+	StopReason string
+	// Drift, Spike and Breakpoint analyse soak, spike and breakpoint
+	// preset runs.
+	Drift      []Drift
+	Spike      *SpikeReport
+	Breakpoint *BreakpointReport
+	// Agents lists the agents of a distributed run.
+	Agents []AgentStatus
+	// Statuses counts requests by status code.
+	Statuses []Stats
+	// NewConnections and ReusedConnections count responses by whether
+	// they needed a new connection.
+	NewConnections    int64
+	ReusedConnections int64
+	// Errors counts failed requests by kind; see classify.
+	Errors map[string]int64
+	// FailureSamples are the first failed requests of each kind.
+	FailureSamples []FailureSample
+	// TimeSeries has a sample for each second of the run.
+	TimeSeries []Sample
+	// Requests breaks the results down by scenario and request name.
+	Requests []Stats
+	// Scenarios summarises iterations; their latencies are iteration times.
+	Scenarios []Stats
+}
+
+// ThresholdsPassed reports whether every threshold held.
+func (r *LoadTestResult) ThresholdsPassed() bool {
+	for _, t := range r.Thresholds {
+		if !t.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrorRate is the percentage of requests that failed.
+func (r *LoadTestResult) ErrorRate() float64 {
+	return percent(r.FailedRequests, r.TotalRequests)
+}
+
+func percent(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
 }
 
 func (r *LoadTestResult) String() string {
-	return fmt.Sprintf(`Load Test Results:
+	out := fmt.Sprintf(`Load Test Results:
   Total Requests: %d
   Success: %d (%.2f%%)
   Failed: %d (%.2f%%)
@@ -225,8 +521,8 @@ func (r *LoadTestResult) String() string {
   P95 Latency: %v
   P99 Latency: %v`,
 		r.TotalRequests,
-		r.SuccessRequests, float64(r.SuccessRequests)/float64(r.TotalRequests)*100,
-		r.FailedRequests, float64(r.FailedRequests)/float64(r.TotalRequests)*100,
+		r.SuccessRequests, percent(r.SuccessRequests, r.TotalRequests),
+		r.FailedRequests, r.ErrorRate(),
 		r.Duration,
 		r.RPS,
 		r.AvgLatency,
@@ -236,4 +532,107 @@ func (r *LoadTestResult) String() string {
 		r.P95Latency,
 		r.P99Latency,
 	)
+	if len(r.Statuses) > 0 {
+		codes := make([]string, len(r.Statuses))
+		for i, s := range r.Statuses {
+			codes[i] = fmt.Sprintf("%s=%d", s.Name, s.Count)
+		}
+		out += "\n  Status Codes: " + strings.Join(codes, " ")
+	}
+	if len(r.Errors) > 0 {
+		out += "\n  Errors: " + formatCounts(r.Errors)
+	}
+	if r.DroppedIterations > 0 {
+		out += fmt.Sprintf("\n  Dropped Iterations: %d", r.DroppedIterations)
+	}
+	if r.Interrupted > 0 {
+		out += fmt.Sprintf("\n  Interrupted Iterations: %d", r.Interrupted)
+	}
+	if r.NewConnections > 0 {
+		out += fmt.Sprintf("\n  Connections: %d new, %d reused", r.NewConnections, r.ReusedConnections)
+	}
+
+	if r.StopReason != "" {
+		out += "\n  Stopped Early: " + r.StopReason
+	}
+
+	sections := []string{out}
+	if len(r.Requests) > 1 || len(r.Scenarios) > 1 {
+		var b strings.Builder
+		b.WriteString("Scenarios (iterations):\n")
+		writeStatsTable(&b, r.Scenarios, false)
+		b.WriteString("\nRequests:\n")
+		writeStatsTable(&b, r.Requests, len(r.Scenarios) > 1)
+		sections = append(sections, strings.TrimRight(b.String(), "\n"))
+	}
+	if len(r.FailureSamples) > 0 {
+		lines := []string{"Failure Samples:"}
+		for _, f := range r.FailureSamples {
+			lines = append(lines, "  "+f.String())
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	if len(r.Drift) > 0 {
+		lines := []string{"Drift (steady phase):"}
+		for _, d := range r.Drift {
+			lines = append(lines, "  "+d.String())
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	if r.Spike != nil {
+		sections = append(sections, "Spike:\n"+r.Spike.String())
+	}
+	if r.Breakpoint != nil {
+		sections = append(sections, "Breakpoint:\n"+r.Breakpoint.String())
+	}
+	if len(r.Agents) > 0 {
+		lines := []string{"Agents:"}
+		for _, a := range r.Agents {
+			switch {
+			case a.Lost:
+				lines = append(lines, fmt.Sprintf("  ❌ %s (%d VUs) lost", a.Name, a.VUs))
+			case a.Error != "":
+				lines = append(lines, fmt.Sprintf("  ❌ %s (%d VUs): %s", a.Name, a.VUs, a.Error))
+			default:
+				lines = append(lines, fmt.Sprintf("  ✅ %s (%d VUs)", a.Name, a.VUs))
+			}
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	if len(r.Thresholds) > 0 {
+		lines := []string{"Thresholds:"}
+		for _, t := range r.Thresholds {
+			lines = append(lines, "  "+t.String())
+		}
+		if r.AbortedBy != "" {
+			lines = append(lines, fmt.Sprintf("Aborted early: threshold %q failed", r.AbortedBy))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// formatCounts lists counts as name=n, sorted by name.
+func formatCounts(counts map[string]int64) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%s=%d", name, counts[name])
+	}
+	return strings.Join(names, " ")
+}
+
+func writeStatsTable(b *strings.Builder, rows []Stats, withScenario bool) {
+	fmt.Fprintf(b, "  %-32s %8s %8s %12s %12s %12s\n", "NAME", "COUNT", "FAILED", "AVG", "P95", "MAX")
+	for _, s := range rows {
+		name := s.Name
+		if withScenario {
+			name = s.Scenario + " › " + s.Name
+		}
+		fmt.Fprintf(b, "  %-32s %8d %8d %12v %12v %12v\n",
+			name, s.Count, s.Failed, s.Avg.Round(time.Microsecond), s.P95.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	}
 }
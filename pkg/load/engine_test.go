@@ -0,0 +1,98 @@
+package load_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+func newTarget(t *testing.T, hits *atomic.Int64) collection.Request {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return collection.Request{Name: "ping", Method: "GET", URL: srv.URL}
+}
+
+func TestEngineIterations(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	engine := load.NewEngine(&load.Config{VirtualUsers: 4, Iterations: 25}, collection.NewRunner("dev"))
+	result, err := engine.Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalRequests != 25 || hits.Load() != 25 {
+		t.Errorf("requests = %d, hits = %d, want 25", result.TotalRequests, hits.Load())
+	}
+	if result.FailedRequests != 0 || result.ErrorRate() != 0 {
+		t.Errorf("failed = %d", result.FailedRequests)
+	}
+}
+
+func TestEngineDuration(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+	req.URL += "?fail=1"
+
+	engine := load.NewEngine(&load.Config{
+		VirtualUsers: 3,
+		Duration:     300 * time.Millisecond,
+		RampUp:       60 * time.Millisecond,
+		RampDown:     60 * time.Millisecond,
+	}, collection.NewRunner("dev"))
+
+	var peak atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				if p := engine.Progress(); int64(p.ActiveVUs) > peak.Load() {
+					peak.Store(int64(p.ActiveVUs))
+				}
+			}
+		}
+	}()
+	result, err := engine.Run(context.Background(), req)
+	close(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.TotalRequests == 0 {
+		t.Fatal("duration run sent no requests")
+	}
+	if result.Duration < 300*time.Millisecond || result.Duration > 2*time.Second {
+		t.Errorf("duration = %v", result.Duration)
+	}
+	if result.ErrorRate() != 100 {
+		t.Errorf("error rate = %.1f, want 100", result.ErrorRate())
+	}
+	if peak.Load() != 3 {
+		t.Errorf("peak active VUs = %d, want 3", peak.Load())
+	}
+	if p := engine.Progress(); p.ActiveVUs != 0 {
+		t.Errorf("%d VUs still active after Run", p.ActiveVUs)
+	}
+}
+
+func TestEngineRequiresLimit(t *testing.T) {
+	engine := load.NewEngine(&load.Config{VirtualUsers: 1}, collection.NewRunner("dev"))
+	if _, err := engine.Run(context.Background(), collection.Request{}); err == nil {
+		t.Error("expected an error without duration or iterations")
+	}
+}
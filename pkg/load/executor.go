@@ -0,0 +1,413 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+)
+
+// Executor is the load model a test runs with.
+type Executor string
+
+const (
+	// ExecutorConstantVUs keeps VirtualUsers VUs looping (closed model).
+	ExecutorConstantVUs Executor = "constant-vus"
+	// ExecutorRampingVUs moves the VU count through Stages, starting from
+	// VirtualUsers.
+	ExecutorRampingVUs Executor = "ramping-vus"
+	// ExecutorConstantArrivalRate starts Rate iterations per TimeUnit no
+	// matter how long they take (open model).
+	ExecutorConstantArrivalRate Executor = "constant-arrival-rate"
+	// ExecutorRampingArrivalRate moves the arrival rate through Stages,
+	// starting from Rate.
+	ExecutorRampingArrivalRate Executor = "ramping-arrival-rate"
+)
+
+// Stage ramps linearly to Target over Duration. Target is a VU count for
+// ramping-vus and iterations per TimeUnit for ramping-arrival-rate.
+type Stage struct {
+	Duration time.Duration `yaml:"duration"`
+	Target   int           `yaml:"target"`
+}
+
+const (
+	// rampInterval is how often VU executors adjust the number of VUs.
+	rampInterval = 10 * time.Millisecond
+	// defaultGracefulStop is how long iterations in flight at the end of a
+	// test get to finish.
+	defaultGracefulStop = 30 * time.Second
+)
+
+// resolve fills in defaults and checks the options make sense for the
+// executor.
+func (c Config) resolve() (Config, error) {
+	if c.Executor == "" {
+		switch {
+		case len(c.Stages) > 0:
+			c.Executor = ExecutorRampingVUs
+		case c.Rate > 0:
+			c.Executor = ExecutorConstantArrivalRate
+		default:
+			c.Executor = ExecutorConstantVUs
+		}
+	}
+	for _, st := range c.Stages {
+		if st.Duration < 0 || st.Target < 0 {
+			return c, fmt.Errorf("stage %v:%d must not be negative", st.Duration, st.Target)
+		}
+	}
+
+	switch c.Executor {
+	case ExecutorConstantVUs:
+		if c.VirtualUsers <= 0 {
+			return c, fmt.Errorf("virtual users must be positive")
+		}
+		if len(c.Stages) > 0 {
+			return c, fmt.Errorf("%s does not use stages", c.Executor)
+		}
+	case ExecutorRampingVUs, ExecutorRampingArrivalRate:
+		if len(c.Stages) == 0 {
+			return c, fmt.Errorf("%s needs at least one stage", c.Executor)
+		}
+		if c.Duration > 0 || c.RampUp > 0 || c.RampDown > 0 {
+			return c, fmt.Errorf("%s takes its duration and ramps from the stages", c.Executor)
+		}
+		c.Duration = stagesDuration(c.Stages)
+	case ExecutorConstantArrivalRate:
+		if c.Rate <= 0 {
+			return c, fmt.Errorf("%s needs a positive rate", c.Executor)
+		}
+		if len(c.Stages) > 0 {
+			return c, fmt.Errorf("%s does not use stages", c.Executor)
+		}
+	default:
+		return c, fmt.Errorf("unknown executor %s", c.Executor)
+	}
+	if c.Duration <= 0 && c.Iterations <= 0 {
+		return c, fmt.Errorf("either a duration or an iteration count is required")
+	}
+
+	if c.Executor == ExecutorConstantArrivalRate || c.Executor == ExecutorRampingArrivalRate {
+		if c.RampUp > 0 || c.RampDown > 0 {
+			return c, fmt.Errorf("%s does not ramp VUs; use %s", c.Executor, ExecutorRampingArrivalRate)
+		}
+		if c.TimeUnit <= 0 {
+			c.TimeUnit = time.Second
+		}
+		if c.PreAllocatedVUs <= 0 {
+			c.PreAllocatedVUs = max(c.VirtualUsers, 1)
+		}
+		c.MaxVUs = max(c.MaxVUs, c.PreAllocatedVUs)
+	}
+
+	if c.CheckRate < 0 || c.CheckRate > 1 {
+		return c, fmt.Errorf("check rate %v must be between 0 and 1", c.CheckRate)
+	}
+	if c.CheckRate == 0 {
+		c.CheckRate = 1
+	}
+	if c.FailureSamples == 0 {
+		c.FailureSamples = defaultFailureSamples
+	}
+	c.Limits = c.Limits.resolve()
+	if c.Memory != nil {
+		c.Outputs = append(slices.Clip(c.Outputs), c.Memory)
+	}
+	if c.GracefulStop == 0 {
+		c.GracefulStop = defaultGracefulStop
+	}
+	if c.MaxDuration < 0 {
+		return c, fmt.Errorf("max duration must not be negative")
+	}
+	if c.Transport != nil {
+		if err := c.Transport.validate(); err != nil {
+			return c, fmt.Errorf("transport: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func stagesDuration(stages []Stage) time.Duration {
+	var total time.Duration
+	for _, st := range stages {
+		total += st.Duration
+	}
+	return total
+}
+
+// stageValue interpolates the stage curve, starting at start, at elapsed.
+// Past the last stage it holds the final target.
+func stageValue(start int, stages []Stage, elapsed time.Duration) float64 {
+	from := float64(start)
+	for _, st := range stages {
+		if elapsed < st.Duration {
+			return from + (float64(st.Target)-from)*float64(elapsed)/float64(st.Duration)
+		}
+		elapsed -= st.Duration
+		from = float64(st.Target)
+	}
+	return from
+}
+
+// stageArea integrates the stage curve from 0 to elapsed, in target-seconds.
+func stageArea(start int, stages []Stage, elapsed time.Duration) float64 {
+	from, area := float64(start), 0.0
+	for _, st := range stages {
+		d := min(elapsed, st.Duration)
+		if d > 0 {
+			to := from + (float64(st.Target)-from)*float64(d)/float64(st.Duration)
+			area += (from + to) / 2 * d.Seconds()
+		}
+		if elapsed -= d; elapsed <= 0 {
+			return area
+		}
+		from = float64(st.Target)
+	}
+	return area + from*elapsed.Seconds()
+}
+
+type virtualUser struct {
+	id     int
+	runner *collection.Runner
+	sc     *scenarioRun
+	iter   int
+}
+
+func (e *Engine) newVU(id int, slots []*scenarioRun) *virtualUser {
+	id += e.vuOffset
+	runner := e.runner.Clone()
+	if e.config.Transport != nil {
+		runner = e.runner.WithClient(e.client(id))
+	}
+	runner.Resolver.SetVariable("__VU", strconv.Itoa(id+1))
+	return &virtualUser{id: id, runner: runner, sc: slots[id%len(slots)]}
+}
+
+// iteration runs the VU's scenario once under the run's in-flight context;
+// see iterate for scheduled.
+func (e *Engine) iteration(vu *virtualUser, scheduled time.Time) {
+	vu.runner.Resolver.SetVariable("__ITER", strconv.Itoa(vu.iter))
+	for k, v := range vu.sc.row(vu.id) {
+		vu.runner.Resolver.SetVariable(k, v)
+	}
+	vu.iter++
+	if !e.iterate(e.inflight, vu.runner, vu.sc, scheduled) {
+		e.interrupted.Add(1)
+	}
+}
+
+// loop repeats iterations until ctx ends, the iteration budget is spent or
+// running reports false. A VU asked to stop finishes its current iteration;
+// while the test is paused it waits before starting the next.
+func (e *Engine) loop(ctx context.Context, vu *virtualUser, running func() bool) {
+	e.activeVUs.Add(1)
+	defer e.activeVUs.Add(-1)
+	for e.waitResumed(ctx) && running() && e.claimIteration() {
+		e.iteration(vu, time.Time{})
+	}
+}
+
+func (e *Engine) claimIteration() bool {
+	return e.config.Iterations <= 0 || e.iterations.Add(1) <= int64(e.config.Iterations)
+}
+
+func (e *Engine) iterationsSpent() bool {
+	return e.config.Iterations > 0 && e.iterations.Load() >= int64(e.config.Iterations)
+}
+
+// runConstantVUs starts VUs spread evenly over RampUp and, in duration runs,
+// stops them spread evenly over the final RampDown. VUs added with AddVUs
+// run until the end.
+func (e *Engine) runConstantVUs(ctx context.Context, slots []*scenarioRun) {
+	vus := time.Duration(e.config.VirtualUsers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i := 0; i < e.config.VirtualUsers; i++ {
+		var stopAt time.Time
+		if e.config.Duration > 0 && e.config.RampDown > 0 {
+			remaining := e.config.RampDown * (vus - time.Duration(i)) / vus
+			stopAt = e.started.Add(e.config.Duration - e.config.RampDown + remaining)
+		}
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if !sleepContext(ctx, e.config.RampUp*time.Duration(id)/vus) {
+				return
+			}
+			e.loop(ctx, e.newVU(id, slots), func() bool {
+				return stopAt.IsZero() || time.Now().Before(stopAt)
+			})
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	extra := &vuSet{engine: e, slots: slots, first: e.config.VirtualUsers}
+	defer extra.stop()
+	e.follow(ctx, done, func() int { return int(e.extraVUs.Load()) }, extra)
+}
+
+// runRampingVUs starts and stops VUs to follow the stages, plus any added
+// with AddVUs.
+func (e *Engine) runRampingVUs(ctx context.Context, slots []*scenarioRun) {
+	vus := &vuSet{engine: e, slots: slots}
+	defer vus.stop()
+	e.follow(ctx, nil, func() int {
+		return int(math.Round(stageValue(e.config.VirtualUsers, e.config.Stages, time.Since(e.started)))) + int(e.extraVUs.Load())
+	}, vus)
+}
+
+// follow scales vus to target until ctx ends, done is closed or the
+// iteration budget is spent.
+func (e *Engine) follow(ctx context.Context, done <-chan struct{}, target func() int, vus *vuSet) {
+	ticker := time.NewTicker(rampInterval)
+	defer ticker.Stop()
+	for !e.iterationsSpent() {
+		vus.scale(ctx, target())
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// vuSet is a group of looping VUs whose size can change. The most recently
+// started VUs are the first to stop.
+type vuSet struct {
+	engine  *Engine
+	slots   []*scenarioRun
+	first   int
+	wg      sync.WaitGroup
+	running []*atomic.Bool
+}
+
+func (s *vuSet) scale(ctx context.Context, target int) {
+	for len(s.running) < target {
+		r := new(atomic.Bool)
+		r.Store(true)
+		vu := s.engine.newVU(s.first+len(s.running), s.slots)
+		s.running = append(s.running, r)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.engine.loop(ctx, vu, r.Load)
+		}()
+	}
+	for len(s.running) > max(target, 0) {
+		s.running[len(s.running)-1].Store(false)
+		s.running = s.running[:len(s.running)-1]
+	}
+}
+
+func (s *vuSet) stop() {
+	for _, r := range s.running {
+		r.Store(false)
+	}
+	s.running = nil
+	s.wg.Wait()
+}
+
+// runArrivalRate starts iterations as they come due, however long earlier
+// ones take. Each takes an idle VU from the pool, which grows up to MaxVUs
+// (plus any added with AddVUs); an iteration that finds no VU is dropped.
+// Iterations that come due while the test is paused are skipped.
+func (e *Engine) runArrivalRate(ctx context.Context, slots []*scenarioRun) {
+	var (
+		mu   sync.Mutex
+		idle []*virtualUser
+		pool int
+	)
+	for pool < e.config.PreAllocatedVUs {
+		idle = append(idle, e.newVU(pool, slots))
+		pool++
+	}
+	e.activeVUs.Add(int64(pool))
+	defer func() { e.activeVUs.Add(-int64(pool)) }()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for n := int64(0); ; n++ {
+		due, ok := e.dueAt(n)
+		if !ok {
+			<-ctx.Done()
+			return
+		}
+		scheduled := e.started.Add(due)
+		if !sleepContext(ctx, time.Until(scheduled)) {
+			return
+		}
+		if e.Paused() {
+			continue
+		}
+		if !e.claimIteration() {
+			return
+		}
+
+		var vu *virtualUser
+		mu.Lock()
+		if len(idle) > 0 {
+			vu, idle = idle[len(idle)-1], idle[:len(idle)-1]
+		}
+		mu.Unlock()
+		if vu == nil {
+			if pool >= e.config.MaxVUs+int(e.extraVUs.Load()) {
+				e.dropped.Add(1)
+				continue
+			}
+			vu = e.newVU(pool, slots)
+			pool++
+			e.activeVUs.Add(1)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.iteration(vu, scheduled)
+			mu.Lock()
+			idle = append(idle, vu)
+			mu.Unlock()
+		}()
+	}
+}
+
+// arrivals is how many iterations should have started by elapsed.
+func (e *Engine) arrivals(elapsed time.Duration) float64 {
+	perSecond := 1 / e.config.TimeUnit.Seconds()
+	if e.config.Executor == ExecutorRampingArrivalRate {
+		return stageArea(e.config.Rate, e.config.Stages, elapsed) * perSecond
+	}
+	return float64(e.config.Rate) * elapsed.Seconds() * perSecond
+}
+
+// dueAt is when iteration n (from 0) should start, or false if the stages
+// end first.
+func (e *Engine) dueAt(n int64) (time.Duration, bool) {
+	if e.config.Executor != ExecutorRampingArrivalRate {
+		return time.Duration(float64(n) * float64(e.config.TimeUnit) / float64(e.config.Rate)), true
+	}
+	lo, hi := time.Duration(0), e.config.Duration
+	if e.arrivals(hi) < float64(n) {
+		return 0, false
+	}
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if e.arrivals(mid) < float64(n) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, true
+}
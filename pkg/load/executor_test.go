@@ -0,0 +1,136 @@
+package load_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+// peakVUs samples the engine's active VUs until the returned stop is called.
+func peakVUs(engine *load.Engine) (stop func() int) {
+	var peak atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+				if p := engine.Progress(); int64(p.ActiveVUs) > peak.Load() {
+					peak.Store(int64(p.ActiveVUs))
+				}
+			}
+		}
+	}()
+	return func() int {
+		close(done)
+		return int(peak.Load())
+	}
+}
+
+func TestRampingVUsFollowsStages(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	engine := load.NewEngine(&load.Config{
+		Stages: []load.Stage{
+			{Duration: 100 * time.Millisecond, Target: 4},
+			{Duration: 100 * time.Millisecond, Target: 4},
+			{Duration: 100 * time.Millisecond, Target: 0},
+		},
+	}, collection.NewRunner("dev"))
+	stop := peakVUs(engine)
+	result, err := engine.Run(context.Background(), req)
+	peak := stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if peak != 4 {
+		t.Errorf("peak active VUs = %d, want 4", peak)
+	}
+	if result.TotalRequests == 0 {
+		t.Error("staged run sent no requests")
+	}
+	if result.Duration < 300*time.Millisecond || result.Duration > 2*time.Second {
+		t.Errorf("duration = %v", result.Duration)
+	}
+	if p := engine.Progress(); p.ActiveVUs != 0 {
+		t.Errorf("%d VUs still active after Run", p.ActiveVUs)
+	}
+}
+
+func TestConstantArrivalRate(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	engine := load.NewEngine(&load.Config{
+		Rate:            100,
+		Duration:        500 * time.Millisecond,
+		PreAllocatedVUs: 2,
+		MaxVUs:          20,
+	}, collection.NewRunner("dev"))
+	result, err := engine.Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 100/s for half a second, whatever the VUs manage in between.
+	if result.Iterations < 40 || result.Iterations > 51 {
+		t.Errorf("iterations = %d, want about 50", result.Iterations)
+	}
+	if result.DroppedIterations != 0 {
+		t.Errorf("dropped = %d", result.DroppedIterations)
+	}
+}
+
+func TestArrivalRateDropsWhenPoolExhausted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+	}))
+	t.Cleanup(srv.Close)
+
+	engine := load.NewEngine(&load.Config{
+		Executor:        load.ExecutorRampingArrivalRate,
+		Rate:            50,
+		Stages:          []load.Stage{{Duration: 400 * time.Millisecond, Target: 100}},
+		PreAllocatedVUs: 1,
+		MaxVUs:          3,
+	}, collection.NewRunner("dev"))
+	stop := peakVUs(engine)
+	result, err := engine.Run(context.Background(), collection.Request{Name: "slow", Method: "GET", URL: srv.URL})
+	peak := stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if peak != 3 {
+		t.Errorf("peak VUs = %d, want the pool to grow to 3", peak)
+	}
+	if result.DroppedIterations == 0 {
+		t.Error("expected dropped iterations with a saturated pool")
+	}
+	if result.TotalRequests > 9 {
+		t.Errorf("requests = %d, more than 3 VUs can make", result.TotalRequests)
+	}
+}
+
+func TestExecutorOptionsValidated(t *testing.T) {
+	for name, cfg := range map[string]load.Config{
+		"stages with duration":   {Stages: []load.Stage{{Duration: time.Second, Target: 1}}, Duration: time.Second},
+		"arrival without rate":   {Executor: load.ExecutorConstantArrivalRate, Duration: time.Second},
+		"ramping without stages": {Executor: load.ExecutorRampingArrivalRate, Rate: 1},
+		"unknown executor":       {Executor: "per-vu-iterations", VirtualUsers: 1, Iterations: 1},
+	} {
+		engine := load.NewEngine(&cfg, collection.NewRunner("dev"))
+		if _, err := engine.Run(context.Background(), collection.Request{Name: "x", URL: "http://localhost"}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
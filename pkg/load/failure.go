@@ -0,0 +1,157 @@
+package load
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+)
+
+// Kinds of failed request.
+const (
+	FailTimeout     = "timeout"
+	FailRefused     = "connection_refused"
+	FailReset       = "connection_reset"
+	FailTLS         = "tls"
+	FailDNS         = "dns"
+	FailAssertion   = "assertion"
+	FailClientError = "4xx"
+	FailServerError = "5xx"
+	FailOther       = "other"
+)
+
+// classify names the kind of failure of result, or returns "" if it
+// succeeded. A response that broke its assertions is an assertion failure
+// even if its status was also an error.
+func classify(result collection.ExecutionResult) string {
+	if err := result.Error; err != nil {
+		return classifyError(err)
+	}
+	switch status := result.Response.StatusCode; {
+	case len(result.Failures) > 0:
+		return FailAssertion
+	case status >= 500:
+		return FailServerError
+	case status >= 400:
+		return FailClientError
+	}
+	return ""
+}
+
+func classifyError(err error) string {
+	var (
+		netErr  net.Error
+		dnsErr  *net.DNSError
+		certErr *tls.CertificateVerificationError
+		alert   tls.AlertError
+		record  tls.RecordHeaderError
+		unknown x509.UnknownAuthorityError
+		host    x509.HostnameError
+		invalid x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return FailDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return FailTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return FailReset
+	case errors.As(err, &certErr), errors.As(err, &alert), errors.As(err, &record),
+		errors.As(err, &unknown), errors.As(err, &host), errors.As(err, &invalid),
+		strings.Contains(err.Error(), "tls: "):
+		return FailTLS
+	}
+	return FailOther
+}
+
+// FailureSample is one failed request kept for debugging.
+type FailureSample struct {
+	Time     time.Time
+	Kind     string
+	Scenario string
+	Request  string
+	Method   string
+	URL      string
+	Status   int
+	Latency  time.Duration
+	Error    string
+	// Failures are the assertions the response broke.
+	Failures []string
+	// Body is the start of the response body.
+	Body string
+}
+
+func (f FailureSample) String() string {
+	out := fmt.Sprintf("[%s] %s: %s %s", f.Kind, f.Request, f.Method, f.URL)
+	if f.Status != 0 {
+		out += fmt.Sprintf(" → %d", f.Status)
+	}
+	out += fmt.Sprintf(" in %v", f.Latency.Round(time.Microsecond))
+	switch {
+	case f.Error != "":
+		out += ": " + f.Error
+	case len(f.Failures) > 0:
+		out += ": failed " + strings.Join(f.Failures, ", ")
+	case f.Body != "":
+		out += ": " + truncate(strings.Join(strings.Fields(f.Body), " "), 100)
+	}
+	return out
+}
+
+const (
+	// defaultFailureSamples is how many failures of each kind are kept.
+	defaultFailureSamples = 5
+	maxSampleBody         = 1024
+)
+
+func newFailureSample(scenario, kind, url string, result collection.ExecutionResult) FailureSample {
+	s := FailureSample{
+		Time:     result.EndTime,
+		Kind:     kind,
+		Scenario: scenario,
+		Request:  result.Request.Name,
+		Method:   result.Request.Method,
+		URL:      url,
+		Status:   result.Response.StatusCode,
+		Latency:  result.Response.Time,
+		Failures: result.Failures,
+		Body:     truncate(string(result.Response.Body), maxSampleBody),
+	}
+	if result.Error != nil {
+		s.Error = result.Error.Error()
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
+
+// keepFailure reports whether another failure of kind fits under the
+// cap. The caller holds e.mu.
+func (e *Engine) keepFailure(kind string) bool {
+	n := 0
+	for _, f := range e.failures {
+		if f.Kind == kind {
+			n++
+		}
+	}
+	return n < e.config.FailureSamples
+}
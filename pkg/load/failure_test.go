@@ -0,0 +1,123 @@
+package load_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+func runRequest(t *testing.T, cfg load.Config, req collection.Request) *load.LoadTestResult {
+	t.Helper()
+	if cfg.VirtualUsers == 0 {
+		cfg.VirtualUsers = 1
+	}
+	result, err := load.NewEngine(&cfg, collection.NewRunner("dev")).Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestFailureClassification(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/broken":
+			http.Error(w, `{"error":"database down"}`, http.StatusServiceUnavailable)
+		case "/reset":
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	tlsSrv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	tlsSrv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsSrv.StartTLS()
+	t.Cleanup(tlsSrv.Close)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name string
+		req  collection.Request
+		kind string
+	}{
+		{"4xx", collection.Request{URL: srv.URL + "/missing"}, load.FailClientError},
+		{"5xx", collection.Request{URL: srv.URL + "/broken"}, load.FailServerError},
+		{"assertion", collection.Request{URL: srv.URL + "/ok", Assertions: []string{"status == 201"}}, load.FailAssertion},
+		{"reset", collection.Request{URL: srv.URL + "/reset"}, load.FailReset},
+		{"refused", collection.Request{URL: closed.URL}, load.FailRefused},
+		{"tls", collection.Request{URL: tlsSrv.URL}, load.FailTLS},
+	}
+	for _, tt := range tests {
+		tt.req.Name, tt.req.Method = tt.name, "GET"
+		result := runRequest(t, load.Config{Iterations: 3}, tt.req)
+		if result.FailedRequests != 3 || result.Errors[tt.kind] != 3 || len(result.Errors) != 1 {
+			t.Errorf("%s: failed = %d, errors = %v, want 3 %s", tt.name, result.FailedRequests, result.Errors, tt.kind)
+		}
+		if len(result.Requests) != 1 || result.Requests[0].Errors[tt.kind] != 3 {
+			t.Errorf("%s: request stats = %+v", tt.name, result.Requests)
+		}
+	}
+}
+
+func TestFailureSamples(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"database down"}`, http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	req := collection.Request{Name: "Orders", Method: "POST", URL: "{{base}}/orders"}
+	cfg := load.Config{VirtualUsers: 2, Iterations: 10, FailureSamples: 2}
+	runner := collection.NewRunner("dev")
+	runner.Resolver.SetVariable("base", srv.URL)
+	result, err := load.NewEngine(&cfg, runner).Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.FailureSamples) != 2 {
+		t.Fatalf("samples = %+v", result.FailureSamples)
+	}
+	f := result.FailureSamples[0]
+	if f.Kind != load.FailServerError || f.Status != 503 || f.URL != srv.URL+"/orders" || !strings.Contains(f.Body, "database down") {
+		t.Errorf("sample = %+v", f)
+	}
+	if out := result.String(); !strings.Contains(out, "Errors: 5xx=10") || !strings.Contains(out, "[5xx] Orders: POST "+srv.URL+"/orders → 503") {
+		t.Errorf("summary:\n%s", out)
+	}
+
+	cfg = load.Config{VirtualUsers: 2, Iterations: 10, FailureSamples: -1}
+	if result := runRequest(t, cfg, collection.Request{Name: "x", Method: "GET", URL: srv.URL}); len(result.FailureSamples) != 0 {
+		t.Errorf("kept %d samples", len(result.FailureSamples))
+	}
+}
+
+func TestCheckSampling(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+	req.Assertions = []string{"status == 201"}
+
+	if r := runRequest(t, load.Config{Iterations: 200, SkipChecks: true}, req); r.FailedRequests != 0 {
+		t.Errorf("skipped checks: %d failed", r.FailedRequests)
+	}
+	r := runRequest(t, load.Config{Iterations: 200, CheckRate: 0.5}, req)
+	if r.FailedRequests < 50 || r.FailedRequests > 150 || r.Errors[load.FailAssertion] != r.FailedRequests {
+		t.Errorf("half checked: %d failed, errors = %v", r.FailedRequests, r.Errors)
+	}
+	if r := runRequest(t, load.Config{Iterations: 20}, req); r.FailedRequests != 20 {
+		t.Errorf("all checked: %d failed", r.FailedRequests)
+	}
+	if _, err := load.NewEngine(&load.Config{VirtualUsers: 1, Iterations: 1, CheckRate: 2}, collection.NewRunner("dev")).Run(context.Background(), req); err == nil {
+		t.Error("expected an error for a check rate above 1")
+	}
+}
@@ -0,0 +1,112 @@
+package load
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// histogram is a lock-free HDR-style latency histogram. Values below
+// 2*histSub nanoseconds are exact; above that each power of two is split
+// into histSub buckets, keeping every value within 1% of the truth.
+type histogram struct {
+	counts [histBuckets]atomic.Int64
+	count  atomic.Int64
+	sum    atomic.Int64
+	// min starts at math.MaxInt64 so that a recorded 0 still lowers it;
+	// read it through lowest.
+	min atomic.Int64
+	max atomic.Int64
+}
+
+const (
+	histSubBits = 7
+	histSub     = 1 << histSubBits
+	// histMaxBits caps recorded values at about 4.9 hours.
+	histMaxBits = 44
+	histBuckets = (histMaxBits-histSubBits-1)*histSub + 2*histSub
+)
+
+func newHistogram() *histogram {
+	h := &histogram{}
+	h.min.Store(math.MaxInt64)
+	return h
+}
+
+func histIndex(v int64) int {
+	if v < 2*histSub {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - histSubBits - 1
+	return shift*histSub + int(v>>shift)
+}
+
+// histValue is the midpoint of bucket i.
+func histValue(i int) int64 {
+	if i < 2*histSub {
+		return int64(i)
+	}
+	shift := i/histSub - 1
+	lo := int64(i-shift*histSub) << shift
+	return lo + (int64(1)<<shift)/2
+}
+
+func (h *histogram) record(d time.Duration) {
+	v := max(int64(d), 0)
+	v = min(v, int64(1)<<histMaxBits-1)
+	// Bounds first, so that a histogram with a count has them.
+	h.bound(v, v)
+	h.counts[histIndex(v)].Add(1)
+	h.sum.Add(v)
+	h.count.Add(1)
+}
+
+func (h *histogram) bound(lo, hi int64) {
+	for {
+		cur := h.min.Load()
+		if lo >= cur || h.min.CompareAndSwap(cur, lo) {
+			break
+		}
+	}
+	for {
+		cur := h.max.Load()
+		if hi <= cur || h.max.CompareAndSwap(cur, hi) {
+			break
+		}
+	}
+}
+
+// lowest is the smallest value recorded, or 0 if there is none.
+func (h *histogram) lowest() int64 {
+	if h.count.Load() == 0 {
+		return 0
+	}
+	return h.min.Load()
+}
+
+func (h *histogram) mean() time.Duration {
+	n := h.count.Load()
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(h.sum.Load() / n)
+}
+
+// percentile returns the value at or below which a fraction p of the
+// samples fall.
+func (h *histogram) percentile(p float64) time.Duration {
+	n := h.count.Load()
+	if n == 0 {
+		return 0
+	}
+	rank := max(int64(math.Ceil(p*float64(n))), 1)
+	var seen int64
+	for i := range h.counts {
+		if seen += h.counts[i].Load(); seen >= rank {
+			v := min(max(histValue(i), h.lowest()), h.max.Load())
+			return time.Duration(v)
+		}
+	}
+	return time.Duration(h.max.Load())
+}
@@ -0,0 +1,198 @@
+package load_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+// newSlowTarget answers /slow after delay, or when the client gives up,
+// and everything else at once.
+func newSlowTarget(t *testing.T, delay time.Duration, hits *atomic.Int64) (fast, slow collection.Request) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/slow" {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return collection.Request{Name: "fast", Method: "GET", URL: srv.URL + "/fast"},
+		collection.Request{Name: "slow", Method: "GET", URL: srv.URL + "/slow"}
+}
+
+func TestGracefulStopFinishesIterations(t *testing.T) {
+	var hits atomic.Int64
+	_, slow := newSlowTarget(t, 300*time.Millisecond, &hits)
+
+	start := time.Now()
+	result := runRequest(t, load.Config{VirtualUsers: 3, Duration: 100 * time.Millisecond}, slow)
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("returned after %v, before iterations in flight finished", elapsed)
+	}
+	if result.TotalRequests != 3 || result.Iterations != 3 || result.FailedRequests != 0 || result.Interrupted != 0 {
+		t.Errorf("requests = %d, iterations = %d, failed = %d, interrupted = %d",
+			result.TotalRequests, result.Iterations, result.FailedRequests, result.Interrupted)
+	}
+	if result.StopReason != "" {
+		t.Errorf("stop reason = %q for a run that ended on time", result.StopReason)
+	}
+}
+
+func TestGracefulStopExpires(t *testing.T) {
+	var hits atomic.Int64
+	_, slow := newSlowTarget(t, 10*time.Second, &hits)
+
+	start := time.Now()
+	result := runRequest(t, load.Config{VirtualUsers: 3, Duration: 100 * time.Millisecond, GracefulStop: 100 * time.Millisecond}, slow)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("returned after %v", elapsed)
+	}
+	// Cancelled requests are not failures of the target.
+	if result.TotalRequests != 0 || result.FailedRequests != 0 || result.Interrupted != 3 {
+		t.Errorf("requests = %d, failed = %d, interrupted = %d", result.TotalRequests, result.FailedRequests, result.Interrupted)
+	}
+}
+
+func TestCancelAbortsWithPartialResult(t *testing.T) {
+	var hits atomic.Int64
+	fast, slow := newSlowTarget(t, 10*time.Second, &hits)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	engine := load.NewEngine(&load.Config{VirtualUsers: 2, Duration: time.Minute}, collection.NewRunner("dev"))
+	done := make(chan *load.LoadTestResult, 1)
+	go func() {
+		result, err := engine.RunScenarios(ctx, &load.Scenario{Name: "s", Requests: []collection.Request{fast, slow}})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- result
+	}()
+	// Each VU has sent its fast request and is waiting on the slow one.
+	waitFor(t, "requests", func() bool { return hits.Load() == 4 })
+	cancel()
+
+	select {
+	case result := <-done:
+		if result.StopReason != load.StopInterrupted {
+			t.Errorf("stop reason = %q", result.StopReason)
+		}
+		if result.TotalRequests != 2 || result.FailedRequests != 0 || result.Interrupted != 2 {
+			t.Errorf("requests = %d, failed = %d, interrupted = %d", result.TotalRequests, result.FailedRequests, result.Interrupted)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("run did not end when its context was cancelled")
+	}
+}
+
+func TestMaxDuration(t *testing.T) {
+	var hits atomic.Int64
+	_, slow := newSlowTarget(t, 10*time.Second, &hits)
+
+	start := time.Now()
+	result := runRequest(t, load.Config{VirtualUsers: 1, Iterations: 10, MaxDuration: 200 * time.Millisecond}, slow)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("returned after %v", elapsed)
+	}
+	if result.StopReason != load.StopMaxDuration || result.Interrupted != 1 {
+		t.Errorf("stop reason = %q, interrupted = %d", result.StopReason, result.Interrupted)
+	}
+	if _, err := load.NewEngine(&load.Config{VirtualUsers: 1, Iterations: 1, MaxDuration: -time.Second}, collection.NewRunner("dev")).Run(context.Background(), slow); err == nil {
+		t.Error("negative max duration accepted")
+	}
+}
+
+// closedOutput panics if it is written to once the test has closed it.
+type closedOutput struct{ samples chan load.Sample }
+
+func (o closedOutput) Write(s load.Sample) error {
+	select {
+	case o.samples <- s:
+	default:
+	}
+	return nil
+}
+
+func (o closedOutput) Close() error { return nil }
+
+func TestRunEndsCleanly(t *testing.T) {
+	var hits atomic.Int64
+	fast, _ := newSlowTarget(t, 0, &hits)
+	scenario := &load.Scenario{Name: "s", Requests: []collection.Request{fast}, ThinkTime: 2 * time.Millisecond}
+	baseline := runtime.NumGoroutine()
+
+	configs := map[string]load.Config{
+		"constant-vus":  {VirtualUsers: 4},
+		"ramping-vus":   {Stages: []load.Stage{{Duration: 100 * time.Millisecond, Target: 4}, {Duration: 2 * time.Second, Target: 4}}},
+		"arrival-rate":  {Rate: 200, MaxVUs: 8},
+		"ramping-rate":  {Executor: load.ExecutorRampingArrivalRate, Rate: 50, Stages: []load.Stage{{Duration: 2 * time.Second, Target: 200}}},
+		"iterations":    {VirtualUsers: 4, Iterations: 50},
+		"no-keep-alive": {VirtualUsers: 4, Transport: &load.Transport{DisableKeepAlives: true}},
+	}
+	endings := map[string]func(*load.Engine, context.CancelFunc){
+		"duration": func(*load.Engine, context.CancelFunc) {},
+		"stop":     func(e *load.Engine, _ context.CancelFunc) { e.Stop() },
+		"cancel":   func(_ *load.Engine, cancel context.CancelFunc) { cancel() },
+		"paused": func(e *load.Engine, _ context.CancelFunc) {
+			e.Pause()
+			e.AddVUs(2)
+			e.Stop()
+		},
+	}
+	for name, cfg := range configs {
+		for ending, end := range endings {
+			t.Run(name+"/"+ending, func(t *testing.T) {
+				if cfg.Iterations == 0 && len(cfg.Stages) == 0 {
+					cfg.Duration = 2 * time.Second
+				}
+				if ending == "duration" && len(cfg.Stages) == 0 && cfg.Iterations == 0 {
+					cfg.Duration = 150 * time.Millisecond
+				}
+				// The engine closes the idle connections of its own clients.
+				if cfg.Transport == nil {
+					cfg.Transport = &load.Transport{}
+				}
+				out := closedOutput{make(chan load.Sample, 16)}
+				cfg.Outputs = []load.Output{out}
+				engine := load.NewEngine(&cfg, collection.NewRunner("dev"))
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					if _, err := engine.RunScenarios(ctx, scenario); err != nil {
+						t.Error(err)
+					}
+				}()
+				if ending != "duration" {
+					waitFor(t, "requests", func() bool { return engine.Progress().Requests > 0 })
+				}
+				end(engine, cancel)
+				select {
+				case <-done:
+				case <-time.After(3 * time.Second):
+					t.Fatal("run did not end")
+				}
+				// A write from a collector that outlived the run would panic.
+				close(out.samples)
+				time.Sleep(20 * time.Millisecond)
+			})
+		}
+	}
+
+	waitFor(t, "goroutines to exit", func() bool {
+		runtime.GC()
+		return runtime.NumGoroutine() <= baseline
+	})
+}
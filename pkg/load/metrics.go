@@ -0,0 +1,150 @@
+package load
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Metrics struct {
+	failed  atomic.Int64
+	latency *histogram
+
+	mu       sync.RWMutex
+	statuses map[int]*histogram
+	kinds    map[string]*atomic.Int64
+}
+
+// Stats summarises the samples of one request, scenario or the whole run.
+type Stats struct {
+	Name string
+	// Scenario is set on per-request stats.
+	Scenario string
+	Count    int64
+	Failed   int64
+	Avg      time.Duration
+	Min      time.Duration
+	Max      time.Duration
+	P50      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+	// Statuses breaks request stats down by status code, named "error"
+	// for requests that got no response.
+	Statuses []Stats
+	// Errors counts failures by kind.
+	Errors map[string]int64
+}
+
+func newMetrics() *Metrics {
+	return &Metrics{latency: newHistogram(), statuses: make(map[int]*histogram), kinds: make(map[string]*atomic.Int64)}
+}
+
+func (m *Metrics) record(latency time.Duration, ok bool) {
+	m.latency.record(latency)
+	if !ok {
+		m.failed.Add(1)
+	}
+}
+
+// recordStatus also files latency under status; 0 means no response.
+func (m *Metrics) recordStatus(latency time.Duration, status int, ok bool) {
+	m.record(latency, ok)
+
+	m.mu.RLock()
+	h, exists := m.statuses[status]
+	m.mu.RUnlock()
+	if !exists {
+		m.mu.Lock()
+		if h, exists = m.statuses[status]; !exists {
+			h = newHistogram()
+			m.statuses[status] = h
+		}
+		m.mu.Unlock()
+	}
+	h.record(latency)
+}
+
+// recordFailure counts a failure of kind; "" is a success.
+func (m *Metrics) recordFailure(kind string) {
+	if kind != "" {
+		m.failureCount(kind).Add(1)
+	}
+}
+
+func (m *Metrics) failureCount(kind string) *atomic.Int64 {
+	m.mu.RLock()
+	n, exists := m.kinds[kind]
+	m.mu.RUnlock()
+	if !exists {
+		m.mu.Lock()
+		if n, exists = m.kinds[kind]; !exists {
+			n = new(atomic.Int64)
+			m.kinds[kind] = n
+		}
+		m.mu.Unlock()
+	}
+	return n
+}
+
+func (m *Metrics) failureCounts() map[string]int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.kinds) == 0 {
+		return nil
+	}
+	counts := make(map[string]int64, len(m.kinds))
+	for kind, n := range m.kinds {
+		counts[kind] = n.Load()
+	}
+	return counts
+}
+
+func (m *Metrics) stats(name string) Stats {
+	s := histStats(name, m.latency)
+	s.Failed = m.failed.Load()
+	s.Errors = m.failureCounts()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	codes := make([]int, 0, len(m.statuses))
+	for code := range m.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		s.Statuses = append(s.Statuses, histStats(statusName(code), m.statuses[code]))
+	}
+	return s
+}
+
+func (m *Metrics) statusCounts() map[string]int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := make(map[string]int64, len(m.statuses))
+	for code, h := range m.statuses {
+		counts[statusName(code)] = h.count.Load()
+	}
+	return counts
+}
+
+func statusName(code int) string {
+	if code == 0 {
+		return "error"
+	}
+	return strconv.Itoa(code)
+}
+
+func histStats(name string, h *histogram) Stats {
+	return Stats{
+		Name:  name,
+		Count: h.count.Load(),
+		Avg:   h.mean(),
+		Min:   time.Duration(h.lowest()),
+		Max:   time.Duration(h.max.Load()),
+		P50:   h.percentile(0.50),
+		P95:   h.percentile(0.95),
+		P99:   h.percentile(0.99),
+	}
+}
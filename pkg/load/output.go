@@ -0,0 +1,134 @@
+package load
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenOutput opens an output from a kind=target spec: json=<file> writes
+// JSON Lines, csv=<file> writes CSV and prometheus=<url> sends each sample
+// to a Prometheus remote-write receiver.
+func OpenOutput(spec string) (Output, error) {
+	kind, target, ok := strings.Cut(spec, "=")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("output %q: want kind=target", spec)
+	}
+	switch kind {
+	case "json", "csv":
+		f, err := os.Create(target)
+		if err != nil {
+			return nil, fmt.Errorf("create output: %w", err)
+		}
+		if kind == "csv" {
+			return NewCSVOutput(f), nil
+		}
+		return NewJSONOutput(f), nil
+	case "prometheus":
+		return NewRemoteWriteOutput(target), nil
+	default:
+		return nil, fmt.Errorf("unknown output %s; use json, csv or prometheus", kind)
+	}
+}
+
+type jsonSample struct {
+	Time     time.Time `json:"time"`
+	VUs      int       `json:"vus"`
+	Requests int64     `json:"requests"`
+	Failed   int64     `json:"failed"`
+	Dropped  int64     `json:"dropped"`
+	NewConns int64     `json:"new_conns"`
+	RPS      float64   `json:"rps"`
+	Avg      float64   `json:"avg_ms"`
+	P50      float64   `json:"p50_ms"`
+	P95      float64   `json:"p95_ms"`
+	P99      float64   `json:"p99_ms"`
+	Max      float64   `json:"max_ms"`
+}
+
+type jsonOutput struct {
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+// NewJSONOutput writes one JSON object per sample, with latencies in
+// milliseconds. Closing the output closes w.
+func NewJSONOutput(w io.WriteCloser) Output {
+	return &jsonOutput{w: w, enc: json.NewEncoder(w)}
+}
+
+func (o *jsonOutput) Write(s Sample) error {
+	return o.enc.Encode(newJSONSample(s))
+}
+
+func newJSONSample(s Sample) jsonSample {
+	return jsonSample{
+		Time:     s.Time.UTC(),
+		VUs:      s.VUs,
+		Requests: s.Requests,
+		Failed:   s.Failed,
+		Dropped:  s.Dropped,
+		NewConns: s.NewConns,
+		RPS:      s.RPS,
+		Avg:      millis(s.Avg),
+		P50:      millis(s.P50),
+		P95:      millis(s.P95),
+		P99:      millis(s.P99),
+		Max:      millis(s.Max),
+	}
+}
+
+func (o *jsonOutput) Close() error {
+	return o.w.Close()
+}
+
+var csvHeader = []string{"time", "vus", "requests", "failed", "dropped", "rps", "avg_ms", "p50_ms", "p95_ms", "p99_ms", "max_ms", "new_conns"}
+
+type csvOutput struct {
+	w      io.WriteCloser
+	csv    *csv.Writer
+	header bool
+}
+
+// NewCSVOutput writes a header row and then a row per sample. Closing the
+// output closes w.
+func NewCSVOutput(w io.WriteCloser) Output {
+	return &csvOutput{w: w, csv: csv.NewWriter(w)}
+}
+
+func (o *csvOutput) Write(s Sample) error {
+	if !o.header {
+		o.csv.Write(csvHeader)
+		o.header = true
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	o.csv.Write([]string{
+		s.Time.UTC().Format(time.RFC3339),
+		strconv.Itoa(s.VUs),
+		strconv.FormatInt(s.Requests, 10),
+		strconv.FormatInt(s.Failed, 10),
+		strconv.FormatInt(s.Dropped, 10),
+		f(s.RPS),
+		f(millis(s.Avg)),
+		f(millis(s.P50)),
+		f(millis(s.P95)),
+		f(millis(s.P99)),
+		f(millis(s.Max)),
+		strconv.FormatInt(s.NewConns, 10),
+	})
+	o.csv.Flush()
+	return o.csv.Error()
+}
+
+func (o *csvOutput) Close() error {
+	return o.w.Close()
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
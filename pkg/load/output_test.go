@@ -0,0 +1,159 @@
+package load_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+type buffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *buffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestTimeSeriesOutputs(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	var jsonBuf, csvBuf buffer
+	jsonOut, csvOut := load.NewJSONOutput(&jsonBuf), load.NewCSVOutput(&csvBuf)
+	engine := load.NewEngine(&load.Config{
+		Rate:     50,
+		Duration: 1500 * time.Millisecond,
+		Outputs:  []load.Output{jsonOut, csvOut},
+	}, collection.NewRunner("dev"))
+	result, err := engine.Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonOut.Close()
+	csvOut.Close()
+
+	if len(result.TimeSeries) != 2 {
+		t.Fatalf("samples = %d, want a full and a partial second", len(result.TimeSeries))
+	}
+	var total int64
+	for _, s := range result.TimeSeries {
+		total += s.Requests
+	}
+	if total != result.TotalRequests {
+		t.Errorf("samples add up to %d requests, want %d", total, result.TotalRequests)
+	}
+	if first := result.TimeSeries[0]; first.RPS < 40 || first.RPS > 60 || first.P95 <= 0 {
+		t.Errorf("first sample = %+v", first)
+	}
+
+	lines := strings.Split(strings.TrimSpace(jsonBuf.String()), "\n")
+	if len(lines) != 2 || !jsonBuf.closed {
+		t.Fatalf("json lines = %d, closed = %v", len(lines), jsonBuf.closed)
+	}
+	var sample map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &sample); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"time", "vus", "requests", "rps", "p95_ms"} {
+		if _, ok := sample[key]; !ok {
+			t.Errorf("json sample has no %s: %s", key, lines[0])
+		}
+	}
+
+	rows, err := csv.NewReader(&csvBuf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "time" || rows[0][6] != "avg_ms" {
+		t.Errorf("csv rows = %v", rows)
+	}
+}
+
+func TestStatusCodeBreakdown(t *testing.T) {
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1)%4 == 0 {
+			time.Sleep(20 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	engine := load.NewEngine(&load.Config{VirtualUsers: 2, Iterations: 40}, collection.NewRunner("dev"))
+	result, err := engine.Run(context.Background(), collection.Request{Name: "flaky", Method: "GET", URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Statuses) != 2 || result.Statuses[0].Name != "200" || result.Statuses[1].Name != "503" {
+		t.Fatalf("statuses = %+v", result.Statuses)
+	}
+	ok, unavailable := result.Statuses[0], result.Statuses[1]
+	if ok.Count != 30 || unavailable.Count != 10 {
+		t.Errorf("200 = %d, 503 = %d", ok.Count, unavailable.Count)
+	}
+	if unavailable.P50 < 20*time.Millisecond || ok.P50 >= 20*time.Millisecond {
+		t.Errorf("p50: 200 = %v, 503 = %v", ok.P50, unavailable.P50)
+	}
+	if result.P99Latency < 20*time.Millisecond || result.P50Latency >= 20*time.Millisecond {
+		t.Errorf("p50 = %v, p99 = %v", result.P50Latency, result.P99Latency)
+	}
+	if !strings.Contains(result.String(), "Status Codes: 200=30 503=10") {
+		t.Errorf("summary has no status codes:\n%s", result)
+	}
+}
+
+func TestRemoteWriteOutput(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			http.Error(w, "bad headers", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.Close)
+
+	out, err := load.OpenOutput("prometheus=" + receiver.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Write(load.Sample{Time: time.Now(), VUs: 3, Requests: 10, P95: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 1 {
+		t.Fatalf("receiver got %d writes", len(bodies))
+	}
+	for _, name := range []string{"nexus_load_requests_total", "nexus_load_vus", "quantile", "0.95"} {
+		if !bytes.Contains(bodies[0], []byte(name)) {
+			t.Errorf("write request has no %s", name)
+		}
+	}
+
+	receiver.Close()
+	if err := out.Write(load.Sample{Time: time.Now()}); err == nil {
+		t.Error("expected an error once the receiver is gone")
+	}
+}
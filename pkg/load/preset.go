@@ -0,0 +1,136 @@
+package load
+
+import (
+	"fmt"
+	"time"
+)
+
+// Preset is a built-in test shape whose results get an analysis of their
+// own; see LoadTestResult.Drift, Spike and Breakpoint.
+type Preset string
+
+const (
+	// PresetSoak holds steady load for a long time and looks for latency,
+	// throughput, error and memory drift.
+	PresetSoak Preset = "soak"
+	// PresetSpike jumps from a baseline to a peak and back, and measures
+	// how the system coped and how long it took to recover.
+	PresetSpike Preset = "spike"
+	// PresetBreakpoint ramps the arrival rate until the system breaks
+	// and reports the most it sustained.
+	PresetBreakpoint Preset = "breakpoint"
+)
+
+// PresetOptions size a preset. Zero values take the preset's defaults.
+type PresetOptions struct {
+	// VUs is the steady load of a soak test (default 10), the baseline of
+	// a spike test (default 5) and the VU pool limit of a breakpoint test
+	// (default 1000).
+	VUs int
+	// Peak is the VU count at the top of a spike (default 10 × VUs).
+	Peak int
+	// Rate is the arrival rate per second a breakpoint test ramps to
+	// (default 1000).
+	Rate int
+	// Duration is the length of the whole test: 1h for soak, 5m for spike
+	// and 10m for breakpoint by default.
+	Duration time.Duration
+}
+
+// PresetLimits tune the analysis of a preset run. Zero values take the
+// defaults.
+type PresetLimits struct {
+	// P95 and ErrorRate (percent) are where a breakpoint test counts a
+	// second as broken (default 1s and 5%). Three broken seconds in a row,
+	// or iterations dropped for want of a VU, end the test.
+	P95       time.Duration `yaml:"p95"`
+	ErrorRate float64       `yaml:"errorRate"`
+	// Drift is how far, in percent, a soak test's latency, throughput or
+	// memory may move over the steady phase (default 20). The error rate
+	// may rise by a twentieth of that in percentage points.
+	Drift float64 `yaml:"drift"`
+}
+
+func (l PresetLimits) resolve() PresetLimits {
+	if l.P95 <= 0 {
+		l.P95 = time.Second
+	}
+	if l.ErrorRate <= 0 {
+		l.ErrorRate = 5
+	}
+	if l.Drift <= 0 {
+		l.Drift = 20
+	}
+	return l
+}
+
+// ParsePreset checks name is a known preset.
+func ParsePreset(name string) (Preset, error) {
+	switch p := Preset(name); p {
+	case PresetSoak, PresetSpike, PresetBreakpoint:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown preset %q; use soak, spike or breakpoint", name)
+}
+
+// Profile returns the load profile of the preset:
+//
+//   - soak ramps to VUs over the first 5% of Duration, holds, and ramps
+//     down over the last 5%;
+//   - spike holds VUs for 30% of Duration, jumps to Peak, holds it for
+//     20%, drops back and holds VUs for the rest to watch recovery;
+//   - breakpoint ramps the arrival rate from 1% of Rate to Rate over
+//     Duration, growing the VU pool up to VUs.
+func (p Preset) Profile(opts PresetOptions) (*Profile, error) {
+	if opts.VUs < 0 || opts.Peak < 0 || opts.Rate < 0 || opts.Duration < 0 {
+		return nil, fmt.Errorf("%s preset: options must not be negative", p)
+	}
+	profile := &Profile{Preset: p}
+	switch p {
+	case PresetSoak:
+		vus, d := withDefault(opts.VUs, 10), withDefault(opts.Duration, time.Hour)
+		ramp := d / 20
+		profile.Executor = ExecutorRampingVUs
+		profile.Stages = []Stage{{ramp, vus}, {d - 2*ramp, vus}, {ramp, 0}}
+
+	case PresetSpike:
+		base, d := withDefault(opts.VUs, 5), withDefault(opts.Duration, 5*time.Minute)
+		peak := withDefault(opts.Peak, 10*base)
+		if peak <= base {
+			return nil, fmt.Errorf("spike preset: peak %d must be above the baseline of %d VUs", peak, base)
+		}
+		jump := min(d/50, 5*time.Second)
+		profile.Executor = ExecutorRampingVUs
+		profile.VUs = base
+		profile.Stages = []Stage{
+			{d * 3 / 10, base},
+			{jump, peak},
+			{d / 5, peak},
+			{jump, base},
+			{d/2 - 2*jump, base},
+		}
+
+	case PresetBreakpoint:
+		rate, d := withDefault(opts.Rate, 1000), withDefault(opts.Duration, 10*time.Minute)
+		maxVUs := withDefault(opts.VUs, 1000)
+		profile.Executor = ExecutorRampingArrivalRate
+		profile.Rate = max(rate/100, 1)
+		profile.TimeUnit = time.Second
+		profile.Stages = []Stage{{d, rate}}
+		profile.PreAllocatedVUs = min(maxVUs, 50)
+		profile.MaxVUs = maxVUs
+		// Once it has broken there is nothing left to learn.
+		profile.GracefulStop = 5 * time.Second
+
+	default:
+		return nil, fmt.Errorf("unknown preset %q; use soak, spike or breakpoint", p)
+	}
+	return profile, nil
+}
+
+func withDefault[T int | time.Duration](v, def T) T {
+	if v == 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,176 @@
+package load_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+func TestPresetProfiles(t *testing.T) {
+	soak, err := load.PresetSoak.Profile(load.PresetOptions{VUs: 50, Duration: 4 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []load.Stage{{Duration: 12 * time.Minute, Target: 50}, {Duration: 216 * time.Minute, Target: 50}, {Duration: 12 * time.Minute, Target: 0}}
+	if soak.Executor != load.ExecutorRampingVUs || fmt.Sprint(soak.Stages) != fmt.Sprint(want) || soak.Preset != load.PresetSoak {
+		t.Errorf("soak = %+v", soak)
+	}
+
+	spike, err := load.PresetSpike.Profile(load.PresetOptions{VUs: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := spike.Config()
+	if cfg.VirtualUsers != 5 || len(cfg.Stages) != 5 || cfg.Stages[1].Target != 50 || cfg.Stages[3].Target != 5 {
+		t.Errorf("spike = %+v", cfg)
+	}
+	var total time.Duration
+	for _, st := range cfg.Stages {
+		total += st.Duration
+	}
+	if total != 5*time.Minute {
+		t.Errorf("spike lasts %v", total)
+	}
+
+	bp, err := load.PresetBreakpoint.Profile(load.PresetOptions{Rate: 500, Duration: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bp.Executor != load.ExecutorRampingArrivalRate || bp.Rate != 5 || bp.Stages[0].Target != 500 || bp.MaxVUs != 1000 {
+		t.Errorf("breakpoint = %+v", bp)
+	}
+
+	if _, err := load.PresetSpike.Profile(load.PresetOptions{VUs: 10, Peak: 10}); err == nil {
+		t.Error("spike with peak at the baseline accepted")
+	}
+	if _, err := load.ParsePreset("stress"); err == nil {
+		t.Error("unknown preset accepted")
+	}
+}
+
+func runPreset(t *testing.T, preset load.Preset, opts load.PresetOptions, cfg func(*load.Config), req collection.Request) *load.LoadTestResult {
+	t.Helper()
+	profile, err := preset.Profile(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := profile.Config()
+	if cfg != nil {
+		cfg(&c)
+	}
+	result, err := load.NewEngine(&c, collection.NewRunner("dev")).Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestBreakpointStopsWhenBroken(t *testing.T) {
+	t.Parallel()
+	// The target serves 30 requests a second and refuses the rest.
+	var mu sync.Mutex
+	var second int64
+	var served int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if now := time.Now().Unix(); now != second {
+			second, served = now, 0
+		}
+		if served++; served > 30 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	result := runPreset(t, load.PresetBreakpoint, load.PresetOptions{Rate: 200, Duration: 10 * time.Second}, nil,
+		collection.Request{Name: "ping", Method: "GET", URL: srv.URL})
+	b := result.Breakpoint
+	if b == nil || !b.Broken || !strings.HasPrefix(b.Reason, "error rate") {
+		t.Fatalf("breakpoint = %+v", b)
+	}
+	if b.MaxRPS < 10 || b.MaxRPS > 40 || b.Rate < 20 {
+		t.Errorf("max RPS = %.1f, broke at %.1f/s", b.MaxRPS, b.Rate)
+	}
+	if result.Duration > 7*time.Second {
+		t.Errorf("ran for %v after breaking", result.Duration)
+	}
+	if !strings.Contains(result.String(), "Max sustainable RPS") {
+		t.Errorf("summary:\n%s", result)
+	}
+}
+
+func TestSpikeRecovery(t *testing.T) {
+	t.Parallel()
+	// Latency grows with the requests in flight.
+	var inflight atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		time.Sleep(time.Duration(n) * 2 * time.Millisecond)
+	}))
+	t.Cleanup(srv.Close)
+
+	result := runPreset(t, load.PresetSpike, load.PresetOptions{VUs: 1, Peak: 20, Duration: 10 * time.Second}, nil,
+		collection.Request{Name: "ping", Method: "GET", URL: srv.URL})
+	s := result.Spike
+	if s == nil {
+		t.Fatal("no spike report")
+	}
+	if s.PeakP95 < 5*s.BaselineP95 {
+		t.Errorf("baseline p95 = %v, peak p95 = %v", s.BaselineP95, s.PeakP95)
+	}
+	if !s.Recovered || s.Recovery > 2*time.Second {
+		t.Errorf("recovered = %v in %v", s.Recovered, s.Recovery)
+	}
+}
+
+func TestSoakDrift(t *testing.T) {
+	t.Parallel()
+	// Latency and memory grow as the test goes on.
+	start := time.Now()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		elapsed := time.Since(start)
+		if r.URL.Path == "/metrics" {
+			fmt.Fprintf(w, "# TYPE process_resident_memory_bytes gauge\nprocess_resident_memory_bytes %d\n", 100<<20+int64(elapsed/time.Millisecond)<<14)
+			return
+		}
+		time.Sleep(elapsed / 200)
+	}))
+	t.Cleanup(srv.Close)
+
+	memory := load.NewMemoryProbe(srv.URL+"/metrics", "process_resident_memory_bytes")
+	memory.Interval = time.Second
+	result := runPreset(t, load.PresetSoak, load.PresetOptions{VUs: 2, Duration: 6 * time.Second}, func(c *load.Config) {
+		c.Memory = memory
+	}, collection.Request{Name: "ping", Method: "GET", URL: srv.URL})
+	if err := memory.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	drift := map[string]load.Drift{}
+	for _, d := range result.Drift {
+		drift[d.Metric] = d
+	}
+	if d := drift["p95"]; !d.Drifting || d.End <= d.Start {
+		t.Errorf("p95 drift = %+v", d)
+	}
+	if d := drift["memory"]; !d.Drifting || d.Change < 20 {
+		t.Errorf("memory drift = %+v", d)
+	}
+	if d := drift["error_rate"]; d.Drifting {
+		t.Errorf("error rate drift = %+v", d)
+	}
+	if !strings.Contains(result.String(), "❌ memory") {
+		t.Errorf("summary:\n%s", result)
+	}
+}
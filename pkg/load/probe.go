@@ -0,0 +1,119 @@
+package load
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryReading is the target's memory use at a point in a run.
+type MemoryReading struct {
+	Time  time.Time
+	Bytes float64
+}
+
+// MemoryProbe reads a gauge, such as process_resident_memory_bytes, from
+// the target's Prometheus metrics endpoint while a soak test runs, so the
+// analysis can look for memory drift. Set it as Config.Memory.
+type MemoryProbe struct {
+	URL    string
+	Metric string
+	// Interval is how often it reads (default 10s), rounded to whole
+	// samples.
+	Interval time.Duration
+
+	client   *http.Client
+	samples  int
+	mu       sync.Mutex
+	readings []MemoryReading
+	err      error
+}
+
+func NewMemoryProbe(url, metric string) *MemoryProbe {
+	return &MemoryProbe{URL: url, Metric: metric, client: &http.Client{Timeout: 2 * time.Second}}
+}
+
+// Write takes a reading if one is due. A failed reading is remembered
+// rather than failing the test; see Err.
+func (p *MemoryProbe) Write(s Sample) error {
+	every := max(int(withDefault(p.Interval, 10*time.Second)/time.Second), 1)
+	if p.samples++; (p.samples-1)%every != 0 {
+		return nil
+	}
+	bytes, err := p.read()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.err = err
+		return nil
+	}
+	p.readings = append(p.readings, MemoryReading{s.Time, bytes})
+	return nil
+}
+
+func (p *MemoryProbe) Close() error {
+	return nil
+}
+
+// Readings returns the readings taken so far; a nil probe has none.
+func (p *MemoryProbe) Readings() []MemoryReading {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]MemoryReading(nil), p.readings...)
+}
+
+// Err is the last failed reading's error, if any.
+func (p *MemoryProbe) Err() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// read fetches the metric from a Prometheus text exposition, taking the
+// first series with that name.
+func (p *MemoryProbe) read() (float64, error) {
+	resp, err := p.client.Get(p.URL)
+	if err != nil {
+		return 0, fmt.Errorf("read memory: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("read memory: %s", resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, p.Metric) {
+			continue
+		}
+		rest := line[len(p.Metric):]
+		if strings.HasPrefix(rest, "{") {
+			_, rest, _ = strings.Cut(rest, "}")
+		} else if !strings.HasPrefix(rest, " ") {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return 0, fmt.Errorf("read memory: %s: %w", p.Metric, err)
+		}
+		return v, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("read memory: %w", err)
+	}
+	return 0, fmt.Errorf("read memory: no %s in %s", p.Metric, p.URL)
+}
@@ -0,0 +1,94 @@
+package load
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is a load test plan kept in a YAML file, so that a CI job can
+// run `nexus load api.yaml --profile smoke.yaml`:
+//
+//	executor: ramping-vus
+//	stages:
+//	  - {duration: 30s, target: 20}
+//	  - {duration: 1m, target: 20}
+//	thinkTime: 500ms
+//	thresholds:
+//	  - p95 < 300ms
+//	  - error_rate < 1% abort
+type Profile struct {
+	Executor        Executor      `yaml:"executor"`
+	VUs             int           `yaml:"vus"`
+	Duration        time.Duration `yaml:"duration"`
+	Iterations      int           `yaml:"iterations"`
+	RampUp          time.Duration `yaml:"rampUp"`
+	RampDown        time.Duration `yaml:"rampDown"`
+	Stages          []Stage       `yaml:"stages"`
+	Rate            int           `yaml:"rate"`
+	TimeUnit        time.Duration `yaml:"timeUnit"`
+	PreAllocatedVUs int           `yaml:"preAllocatedVUs"`
+	MaxVUs          int           `yaml:"maxVUs"`
+	ThinkTime       time.Duration `yaml:"thinkTime"`
+	Thresholds      []string      `yaml:"thresholds"`
+	CheckRate       float64       `yaml:"checkRate"`
+	FailureSamples  int           `yaml:"failureSamples"`
+	Transport       *Transport    `yaml:"transport"`
+	GracefulStop    time.Duration `yaml:"gracefulStop"`
+	MaxDuration     time.Duration `yaml:"maxDuration"`
+	// Preset asks for the analysis of a built-in test; see Preset.Profile.
+	Preset Preset       `yaml:"preset"`
+	Limits PresetLimits `yaml:"limits"`
+}
+
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read profile: %w", err)
+	}
+	var p Profile
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse profile: %w", err)
+	}
+	if _, err := parseThresholds(p.Thresholds); err != nil {
+		return nil, err
+	}
+	if p.Preset != "" {
+		if _, err := ParsePreset(string(p.Preset)); err != nil {
+			return nil, err
+		}
+	}
+	if p.Transport != nil {
+		if err := p.Transport.validate(); err != nil {
+			return nil, fmt.Errorf("transport: %w", err)
+		}
+	}
+	return &p, nil
+}
+
+// Config returns the engine settings of the profile.
+func (p *Profile) Config() Config {
+	return Config{
+		Executor:        p.Executor,
+		VirtualUsers:    p.VUs,
+		Duration:        p.Duration,
+		Iterations:      p.Iterations,
+		RampUp:          p.RampUp,
+		RampDown:        p.RampDown,
+		Stages:          p.Stages,
+		Rate:            p.Rate,
+		TimeUnit:        p.TimeUnit,
+		PreAllocatedVUs: p.PreAllocatedVUs,
+		MaxVUs:          p.MaxVUs,
+		Thresholds:      p.Thresholds,
+		CheckRate:       p.CheckRate,
+		FailureSamples:  p.FailureSamples,
+		Transport:       p.Transport,
+		GracefulStop:    p.GracefulStop,
+		MaxDuration:     p.MaxDuration,
+		Preset:          p.Preset,
+		Limits:          p.Limits,
+	}
+}
@@ -0,0 +1,125 @@
+package load
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+type remoteWriteOutput struct {
+	url    string
+	client *http.Client
+
+	requests, failed, dropped, newConns int64
+}
+
+// NewRemoteWriteOutput pushes each sample to a Prometheus remote-write
+// endpoint such as http://localhost:9090/api/v1/write. Counts are sent as
+// running totals, so rate() works on them.
+func NewRemoteWriteOutput(url string) Output {
+	return &remoteWriteOutput{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+type promSeries struct {
+	name   string
+	labels [][2]string
+	value  float64
+}
+
+func (o *remoteWriteOutput) Write(s Sample) error {
+	o.requests += s.Requests
+	o.failed += s.Failed
+	o.dropped += s.Dropped
+	o.newConns += s.NewConns
+
+	series := []promSeries{
+		{name: "nexus_load_requests_total", value: float64(o.requests)},
+		{name: "nexus_load_failed_requests_total", value: float64(o.failed)},
+		{name: "nexus_load_dropped_iterations_total", value: float64(o.dropped)},
+		{name: "nexus_load_new_connections_total", value: float64(o.newConns)},
+		{name: "nexus_load_vus", value: float64(s.VUs)},
+		{name: "nexus_load_rps", value: s.RPS},
+		{name: "nexus_load_latency_avg_seconds", value: s.Avg.Seconds()},
+		{name: "nexus_load_latency_max_seconds", value: s.Max.Seconds()},
+		{name: "nexus_load_latency_seconds", labels: [][2]string{{"quantile", "0.5"}}, value: s.P50.Seconds()},
+		{name: "nexus_load_latency_seconds", labels: [][2]string{{"quantile", "0.95"}}, value: s.P95.Seconds()},
+		{name: "nexus_load_latency_seconds", labels: [][2]string{{"quantile", "0.99"}}, value: s.P99.Seconds()},
+	}
+	body := snappyBlock(writeRequest(series, s.Time.UnixMilli()))
+
+	req, err := http.NewRequest(http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("remote write: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote write: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("remote write: %s", resp.Status)
+	}
+	return nil
+}
+
+func (o *remoteWriteOutput) Close() error {
+	return nil
+}
+
+// writeRequest encodes a prometheus.WriteRequest protobuf by hand:
+// WriteRequest{timeseries=1}, TimeSeries{labels=1, samples=2},
+// Label{name=1, value=2}, Sample{value=1 double, timestamp=2 int64}.
+func writeRequest(series []promSeries, timestamp int64) []byte {
+	var out []byte
+	for _, s := range series {
+		var ts []byte
+		ts = pbBytes(ts, 1, pbLabel("__name__", s.name))
+		for _, l := range s.labels {
+			ts = pbBytes(ts, 1, pbLabel(l[0], l[1]))
+		}
+		sample := binary.AppendUvarint(nil, 1<<3|1)
+		sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(s.value))
+		sample = binary.AppendUvarint(sample, 2<<3|0)
+		sample = binary.AppendUvarint(sample, uint64(timestamp))
+		ts = pbBytes(ts, 2, sample)
+		out = pbBytes(out, 1, ts)
+	}
+	return out
+}
+
+func pbLabel(name, value string) []byte {
+	return pbBytes(pbBytes(nil, 1, []byte(name)), 2, []byte(value))
+}
+
+func pbBytes(b []byte, field int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// snappyBlock frames src as a snappy block made only of literals. It does
+// not compress, but every snappy decoder accepts it and it needs no
+// dependency; the payloads are a few hundred bytes.
+func snappyBlock(src []byte) []byte {
+	dst := binary.AppendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), 1<<16)
+		switch {
+		case n <= 60:
+			dst = append(dst, byte(n-1)<<2)
+		case n <= 1<<8:
+			dst = append(dst, 60<<2, byte(n-1))
+		default:
+			dst = append(dst, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
@@ -0,0 +1,409 @@
+package load
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Summary is the machine-readable form of a LoadTestResult, written by
+// WriteReport and read back by ReadSummary. Latencies are in milliseconds.
+type Summary struct {
+	Title      string             `json:"title"`
+	Duration   float64            `json:"duration_ms"`
+	Requests   int64              `json:"requests"`
+	Failed     int64              `json:"failed"`
+	ErrorRate  float64            `json:"error_rate"`
+	RPS        float64            `json:"rps"`
+	Iterations int64              `json:"iterations"`
+	Dropped    int64              `json:"dropped_iterations"`
+	NewConns   int64              `json:"new_connections"`
+	Reused     int64              `json:"reused_connections"`
+	Unfinished int64              `json:"interrupted_iterations,omitempty"`
+	Latency    StatsSummary       `json:"latency"`
+	Statuses   map[string]int64   `json:"statuses,omitempty"`
+	Errors     map[string]int64   `json:"errors,omitempty"`
+	Thresholds []ThresholdSummary `json:"thresholds,omitempty"`
+	AbortedBy  string             `json:"aborted_by,omitempty"`
+	StoppedBy  string             `json:"stop_reason,omitempty"`
+	Scenarios  []StatsSummary     `json:"scenarios,omitempty"`
+	Endpoints  []StatsSummary     `json:"endpoints,omitempty"`
+	TimeSeries []jsonSample       `json:"time_series,omitempty"`
+	Failures   []FailureSummary   `json:"failure_samples,omitempty"`
+	Analysis   *AnalysisSummary   `json:"analysis,omitempty"`
+}
+
+type StatsSummary struct {
+	Name     string           `json:"name,omitempty"`
+	Scenario string           `json:"scenario,omitempty"`
+	Count    int64            `json:"count"`
+	Failed   int64            `json:"failed"`
+	Avg      float64          `json:"avg_ms"`
+	Min      float64          `json:"min_ms"`
+	Max      float64          `json:"max_ms"`
+	P50      float64          `json:"p50_ms"`
+	P95      float64          `json:"p95_ms"`
+	P99      float64          `json:"p99_ms"`
+	Errors   map[string]int64 `json:"errors,omitempty"`
+}
+
+// ErrorRate is the percentage of the requests that failed.
+func (s StatsSummary) ErrorRate() float64 {
+	return percent(s.Failed, s.Count)
+}
+
+type FailureSummary struct {
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"`
+	Scenario string    `json:"scenario,omitempty"`
+	Request  string    `json:"request"`
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	Status   int       `json:"status,omitempty"`
+	Latency  float64   `json:"latency_ms"`
+	Error    string    `json:"error,omitempty"`
+	Failures []string  `json:"failures,omitempty"`
+	Body     string    `json:"body,omitempty"`
+}
+
+// AnalysisSummary holds the analysis of a preset run.
+type AnalysisSummary struct {
+	Drift      []Drift            `json:"drift,omitempty"`
+	Spike      *SpikeSummary      `json:"spike,omitempty"`
+	Breakpoint *BreakpointSummary `json:"breakpoint,omitempty"`
+}
+
+type SpikeSummary struct {
+	BaselineP95       float64 `json:"baseline_p95_ms"`
+	PeakP95           float64 `json:"peak_p95_ms"`
+	BaselineErrorRate float64 `json:"baseline_error_rate"`
+	PeakErrorRate     float64 `json:"peak_error_rate"`
+	Recovered         bool    `json:"recovered"`
+	Recovery          float64 `json:"recovery_ms"`
+}
+
+type BreakpointSummary struct {
+	Broken bool    `json:"broken"`
+	At     float64 `json:"at_ms,omitempty"`
+	Rate   float64 `json:"rate,omitempty"`
+	Reason string  `json:"reason,omitempty"`
+	MaxRPS float64 `json:"max_rps"`
+}
+
+type ThresholdSummary struct {
+	Source string  `json:"source"`
+	Metric string  `json:"metric"`
+	Actual string  `json:"actual"`
+	Passed bool    `json:"passed"`
+	Value  float64 `json:"value"`
+}
+
+// NewSummary summarises r under the given title.
+func NewSummary(title string, r *LoadTestResult) *Summary {
+	s := &Summary{
+		Title:      title,
+		Duration:   millis(r.Duration),
+		Requests:   r.TotalRequests,
+		Failed:     r.FailedRequests,
+		ErrorRate:  r.ErrorRate(),
+		RPS:        r.RPS,
+		Iterations: r.Iterations,
+		Dropped:    r.DroppedIterations,
+		NewConns:   r.NewConnections,
+		Reused:     r.ReusedConnections,
+		Unfinished: r.Interrupted,
+		Latency: summarise(Stats{
+			Count: r.TotalRequests, Failed: r.FailedRequests,
+			Avg: r.AvgLatency, Min: r.MinLatency, Max: r.MaxLatency,
+			P50: r.P50Latency, P95: r.P95Latency, P99: r.P99Latency,
+		}),
+		Errors:    r.Errors,
+		AbortedBy: r.AbortedBy,
+		StoppedBy: r.StopReason,
+	}
+	if len(r.Statuses) > 0 {
+		s.Statuses = make(map[string]int64, len(r.Statuses))
+		for _, st := range r.Statuses {
+			s.Statuses[st.Name] = st.Count
+		}
+	}
+	for _, t := range r.Thresholds {
+		s.Thresholds = append(s.Thresholds, ThresholdSummary{
+			Source: t.Source, Metric: t.Metric, Actual: t.format(t.Actual), Passed: t.Passed, Value: t.Actual,
+		})
+	}
+	for _, st := range r.Scenarios {
+		s.Scenarios = append(s.Scenarios, summarise(st))
+	}
+	for _, st := range r.Requests {
+		s.Endpoints = append(s.Endpoints, summarise(st))
+	}
+	for _, sample := range r.TimeSeries {
+		s.TimeSeries = append(s.TimeSeries, newJSONSample(sample))
+	}
+	for _, f := range r.FailureSamples {
+		s.Failures = append(s.Failures, FailureSummary{
+			Time: f.Time.UTC(), Kind: f.Kind, Scenario: f.Scenario, Request: f.Request, Method: f.Method, URL: f.URL,
+			Status: f.Status, Latency: millis(f.Latency), Error: f.Error, Failures: f.Failures, Body: f.Body,
+		})
+	}
+	if len(r.Drift) > 0 || r.Spike != nil || r.Breakpoint != nil {
+		s.Analysis = &AnalysisSummary{Drift: r.Drift}
+	}
+	if sp := r.Spike; sp != nil {
+		s.Analysis.Spike = &SpikeSummary{
+			BaselineP95: millis(sp.BaselineP95), PeakP95: millis(sp.PeakP95),
+			BaselineErrorRate: sp.BaselineErrorRate, PeakErrorRate: sp.PeakErrorRate,
+			Recovered: sp.Recovered, Recovery: millis(sp.Recovery),
+		}
+	}
+	if b := r.Breakpoint; b != nil {
+		s.Analysis.Breakpoint = &BreakpointSummary{
+			Broken: b.Broken, At: millis(b.At), Rate: b.Rate, Reason: b.Reason, MaxRPS: b.MaxRPS,
+		}
+	}
+	return s
+}
+
+func summarise(st Stats) StatsSummary {
+	return StatsSummary{
+		Name: st.Name, Scenario: st.Scenario, Count: st.Count, Failed: st.Failed, Errors: st.Errors,
+		Avg: millis(st.Avg), Min: millis(st.Min), Max: millis(st.Max),
+		P50: millis(st.P50), P95: millis(st.P95), P99: millis(st.P99),
+	}
+}
+
+// WriteReport writes r to path as an HTML report or, for a .json path, as
+// a Summary.
+func WriteReport(path, title string, r *LoadTestResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+	s := NewSummary(title, r)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = s.WriteJSON(f)
+	} else {
+		err = s.WriteHTML(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+func (s *Summary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ReadSummary reads a summary written by WriteReport.
+func ReadSummary(path string) (*Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read summary: %w", err)
+	}
+	var s Summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse summary %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// WriteHTML writes a self-contained HTML report with charts of the time
+// series.
+func (s *Summary) WriteHTML(w io.Writer) error {
+	latency := []chartSeries{
+		{"p50", "#4c9be8", func(x jsonSample) float64 { return x.P50 }},
+		{"p95", "#e8a33c", func(x jsonSample) float64 { return x.P95 }},
+		{"p99", "#d9534f", func(x jsonSample) float64 { return x.P99 }},
+	}
+	data := struct {
+		*Summary
+		Charts []chart
+		Passed bool
+		Ms     func(float64) time.Duration
+	}{
+		Summary: s,
+		Charts: []chart{
+			newChart("Latency (ms)", s.TimeSeries, latency...),
+			newChart("Requests per second", s.TimeSeries, chartSeries{"rps", "#5cb85c", func(x jsonSample) float64 { return x.RPS }}),
+			newChart("Errors per second", s.TimeSeries,
+				chartSeries{"failed", "#d9534f", func(x jsonSample) float64 { return float64(x.Failed) }},
+				chartSeries{"dropped", "#999999", func(x jsonSample) float64 { return float64(x.Dropped) }}),
+			newChart("Virtual users", s.TimeSeries, chartSeries{"vus", "#8e6cc9", func(x jsonSample) float64 { return float64(x.VUs) }}),
+		},
+		Passed: true,
+		Ms: func(v float64) time.Duration {
+			return time.Duration(v * float64(time.Millisecond)).Round(time.Microsecond)
+		},
+	}
+	for _, t := range s.Thresholds {
+		data.Passed = data.Passed && t.Passed
+	}
+	return reportTemplate.Execute(w, data)
+}
+
+type chartSeries struct {
+	Name  string
+	Color string
+	value func(jsonSample) float64
+}
+
+type chart struct {
+	Title string
+	Max   float64
+	Lines []chartLine
+}
+
+type chartLine struct {
+	Name, Color, Points string
+}
+
+const chartWidth, chartHeight = 600.0, 160.0
+
+func newChart(title string, samples []jsonSample, series ...chartSeries) chart {
+	c := chart{Title: title}
+	for _, x := range samples {
+		for _, sr := range series {
+			c.Max = max(c.Max, sr.value(x))
+		}
+	}
+	top := c.Max
+	if top == 0 {
+		top = 1
+	}
+	step := chartWidth / float64(max(len(samples)-1, 1))
+	for _, sr := range series {
+		points := make([]string, len(samples))
+		for i, x := range samples {
+			points[i] = fmt.Sprintf("%.1f,%.1f", float64(i)*step, chartHeight-sr.value(x)/top*chartHeight)
+		}
+		c.Lines = append(c.Lines, chartLine{sr.Name, sr.Color, strings.Join(points, " ")})
+	}
+	return c
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} – load test report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; margin: 2em auto; max-width: 1000px; color: #222; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #666; }
+.cards { display: flex; flex-wrap: wrap; gap: 1em; margin: 1.5em 0; }
+.card { border: 1px solid #ddd; border-radius: 6px; padding: 0.6em 1em; min-width: 7em; }
+.card b { display: block; font-size: 1.4em; }
+.charts { display: grid; grid-template-columns: 1fr 1fr; gap: 1.5em; }
+svg { width: 100%; height: auto; background: #fafafa; border: 1px solid #eee; }
+table { border-collapse: collapse; width: 100%; margin: 1em 0; }
+th, td { text-align: right; padding: 0.3em 0.6em; border-bottom: 1px solid #eee; }
+th:first-child, td:first-child { text-align: left; }
+.pass { color: #2e7d32; } .fail { color: #c62828; }
+pre { background: #f5f5f5; padding: 0.6em; overflow-x: auto; }
+details { margin: 0.4em 0; }
+.legend span { margin-right: 1em; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Duration {{call .Ms .Duration}} · {{.Iterations}} iterations
+{{- if .Thresholds}} · {{if .Passed}}<span class="pass">thresholds passed</span>{{else}}<span class="fail">thresholds failed</span>{{end}}{{end}}
+{{- if .AbortedBy}} · <span class="fail">aborted by {{.AbortedBy}}</span>{{end}}
+{{- if .StoppedBy}} · <span class="fail">stopped early: {{.StoppedBy}}</span>{{end}}</p>
+
+<div class="cards">
+<div class="card">Requests<b>{{.Requests}}</b></div>
+<div class="card">Failed<b>{{.Failed}} ({{printf "%.2f" .ErrorRate}}%)</b></div>
+<div class="card">RPS<b>{{printf "%.1f" .RPS}}</b></div>
+<div class="card">p50<b>{{call .Ms .Latency.P50}}</b></div>
+<div class="card">p95<b>{{call .Ms .Latency.P95}}</b></div>
+<div class="card">p99<b>{{call .Ms .Latency.P99}}</b></div>
+{{- if .Dropped}}<div class="card">Dropped<b>{{.Dropped}}</b></div>{{end}}
+{{- if .NewConns}}<div class="card">Connections<b>{{.NewConns}} new, {{.Reused}} reused</b></div>{{end}}
+</div>
+
+{{if .TimeSeries}}<div class="charts">
+{{range .Charts}}<div>
+<h3>{{.Title}}</h3>
+<svg viewBox="-4 -4 608 168" preserveAspectRatio="none">
+{{range .Lines}}<polyline fill="none" stroke="{{.Color}}" stroke-width="1.5" points="{{.Points}}"/>
+{{end}}</svg>
+<div class="legend">{{range .Lines}}<span style="color: {{.Color}}">■ {{.Name}}</span>{{end}}<span>max {{printf "%.2f" .Max}}</span></div>
+</div>
+{{end}}</div>{{end}}
+
+{{with .Analysis}}<h2>Analysis</h2>
+{{if .Drift}}<table>
+<tr><th>Drift over the steady phase</th></tr>
+{{range .Drift}}<tr><td{{if .Drifting}} class="fail"{{end}}>{{.}}</td></tr>
+{{end}}</table>{{end}}
+{{with .Spike}}<table>
+<tr><th>Spike</th><th>Baseline</th><th>Peak</th></tr>
+<tr><td>p95</td><td>{{call $.Ms .BaselineP95}}</td><td>{{call $.Ms .PeakP95}}</td></tr>
+<tr><td>Error rate</td><td>{{printf "%.2f" .BaselineErrorRate}}%</td><td>{{printf "%.2f" .PeakErrorRate}}%</td></tr>
+<tr><td>Recovery</td><td colspan="2">{{if .Recovered}}<span class="pass">✅ {{call $.Ms .Recovery}}</span>{{else}}<span class="fail">❌ did not recover</span>{{end}}</td></tr>
+</table>{{end}}
+{{with .Breakpoint}}<table>
+<tr><th>Breakpoint</th><th></th></tr>
+<tr><td>Max sustainable RPS</td><td>{{printf "%.1f" .MaxRPS}}</td></tr>
+{{if .Broken}}<tr><td>Broke at</td><td class="fail">{{call $.Ms .At}}, {{printf "%.1f" .Rate}} iterations/s: {{.Reason}}</td></tr>
+{{else}}<tr><td>Broke at</td><td class="pass">did not break</td></tr>{{end}}
+</table>{{end}}
+{{end}}
+
+{{if .Thresholds}}<h2>Thresholds</h2>
+<table>
+<tr><th>Threshold</th><th>Actual</th><th>Result</th></tr>
+{{range .Thresholds}}<tr><td>{{.Source}}</td><td>{{.Metric}} = {{.Actual}}</td>
+<td>{{if .Passed}}<span class="pass">✅ passed</span>{{else}}<span class="fail">❌ failed</span>{{end}}</td></tr>
+{{end}}</table>{{end}}
+
+{{if .Endpoints}}<h2>Requests</h2>
+<table>
+<tr><th>Request</th><th>Count</th><th>Failed</th><th>Avg</th><th>p50</th><th>p95</th><th>p99</th><th>Max</th></tr>
+{{range .Endpoints}}<tr><td>{{if .Scenario}}{{.Scenario}} › {{end}}{{.Name}}</td><td>{{.Count}}</td><td>{{.Failed}}</td>
+<td>{{call $.Ms .Avg}}</td><td>{{call $.Ms .P50}}</td><td>{{call $.Ms .P95}}</td><td>{{call $.Ms .P99}}</td><td>{{call $.Ms .Max}}</td></tr>
+{{end}}</table>{{end}}
+
+{{if .Scenarios}}<h2>Scenarios (iterations)</h2>
+<table>
+<tr><th>Scenario</th><th>Count</th><th>Failed</th><th>Avg</th><th>p95</th><th>Max</th></tr>
+{{range .Scenarios}}<tr><td>{{.Name}}</td><td>{{.Count}}</td><td>{{.Failed}}</td>
+<td>{{call $.Ms .Avg}}</td><td>{{call $.Ms .P95}}</td><td>{{call $.Ms .Max}}</td></tr>
+{{end}}</table>{{end}}
+
+{{if .Statuses}}<h2>Status codes</h2>
+<table>
+<tr><th>Status</th><th>Count</th></tr>
+{{range $code, $n := .Statuses}}<tr><td>{{$code}}</td><td>{{$n}}</td></tr>
+{{end}}</table>{{end}}
+
+{{if .Errors}}<h2>Errors</h2>
+<table>
+<tr><th>Kind</th><th>Count</th></tr>
+{{range $kind, $n := .Errors}}<tr><td>{{$kind}}</td><td>{{$n}}</td></tr>
+{{end}}</table>{{end}}
+
+{{if .Failures}}<h2>Failure samples</h2>
+{{range .Failures}}<details>
+<summary><b>{{.Kind}}</b> {{.Request}}: {{.Method}} {{.URL}}{{if .Status}} → {{.Status}}{{end}} in {{call $.Ms .Latency}}</summary>
+{{if .Error}}<p class="fail">{{.Error}}</p>{{end}}
+{{if .Failures}}<ul>{{range .Failures}}<li class="fail">{{.}}</li>{{end}}</ul>{{end}}
+{{if .Body}}<pre>{{.Body}}</pre>{{end}}
+</details>
+{{end}}{{end}}
+</body>
+</html>
+`))
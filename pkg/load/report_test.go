@@ -0,0 +1,109 @@
+package load_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+func TestWriteReports(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	engine := load.NewEngine(&load.Config{
+		VirtualUsers: 2,
+		Duration:     1200 * time.Millisecond,
+		Thresholds:   []string{"p95 < 10s"},
+	}, collection.NewRunner("dev"))
+	result, err := engine.Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	htmlPath, jsonPath := filepath.Join(dir, "report.html"), filepath.Join(dir, "summary.json")
+	for _, path := range []string{htmlPath, jsonPath} {
+		if err := load.WriteReport(path, "Smoke <test>", result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	html, _ := os.ReadFile(htmlPath)
+	for _, want := range []string{"Smoke &lt;test&gt;", "<polyline", "Requests per second", "p95 &lt; 10s", "✅ passed", "<td>ping</td>"} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	if strings.Contains(string(html), "<script") {
+		t.Error("report should not need scripts")
+	}
+
+	s, err := load.ReadSummary(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Requests != result.TotalRequests || s.Statuses["200"] != result.TotalRequests || len(s.Endpoints) != 1 {
+		t.Errorf("summary = %+v", s)
+	}
+	if len(s.TimeSeries) == 0 || len(s.Thresholds) != 1 || !s.Thresholds[0].Passed {
+		t.Errorf("series = %d, thresholds = %+v", len(s.TimeSeries), s.Thresholds)
+	}
+	if s.Latency.P95 <= 0 || s.Latency.P95 != float64(result.P95Latency)/float64(time.Millisecond) {
+		t.Errorf("p95 = %vms, want %v", s.Latency.P95, result.P95Latency)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := &load.Summary{
+		Title:   "base",
+		RPS:     1000,
+		Latency: load.StatsSummary{Count: 1000, Avg: 10, P50: 8, P95: 20, P99: 40},
+		Endpoints: []load.StatsSummary{
+			{Name: "List", Count: 500, Avg: 10, P50: 8, P95: 20, P99: 40},
+			{Name: "Create", Count: 500, Avg: 10, P50: 8, P95: 20, P99: 40},
+		},
+	}
+	current := &load.Summary{
+		Title:   "current",
+		RPS:     950,
+		Latency: load.StatsSummary{Count: 1000, Failed: 30, Avg: 10.5, P50: 8, P95: 25, P99: 41},
+		Endpoints: []load.StatsSummary{
+			{Name: "List", Count: 500, Avg: 10, P50: 8, P95: 20, P99: 40},
+		},
+	}
+
+	c := load.Compare(base, current, load.DefaultTolerance)
+	var got []string
+	for _, d := range c.Regressions() {
+		got = append(got, d.Name+" "+d.Metric)
+	}
+	if strings.Join(got, ", ") != "total p95, total error_rate, Create missing" {
+		t.Errorf("regressions = %v", got)
+	}
+	if len(c.Missing) != 1 || c.Missing[0] != "Create" {
+		t.Errorf("missing = %v", c.Missing)
+	}
+	if out := c.String(); !strings.Contains(out, "+25.0%") || !strings.Contains(out, "+3.00pp") || !strings.Contains(out, "❌ 3 regressions") {
+		t.Errorf("comparison:\n%s", out)
+	}
+
+	loose := load.Compare(base, current, load.Tolerance{Latency: 50, RPS: 50, ErrorRate: 5})
+	if r := loose.Regressions(); len(r) != 1 || r[0].Name != "Create" || r[0].Metric != "missing" {
+		t.Errorf("regressions with a loose tolerance: %+v", r)
+	}
+	if out := loose.String(); !strings.Contains(out, "❌ 1 regressions") {
+		t.Errorf("comparison with a missing request:\n%s", out)
+	}
+
+	current.Endpoints = append(current.Endpoints, base.Endpoints[1])
+	if r := load.Compare(base, current, load.Tolerance{Latency: 50, RPS: 50, ErrorRate: 5}).Regressions(); len(r) != 0 {
+		t.Errorf("regressions with every request present: %+v", r)
+	}
+}
@@ -0,0 +1,143 @@
+package load
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+)
+
+// DataMode controls how data rows are handed to virtual users.
+type DataMode string
+
+const (
+	// DataShared gives each iteration the next row across all VUs.
+	DataShared DataMode = "shared"
+	// DataPerVU pins one row to each VU, e.g. a set of login credentials.
+	DataPerVU DataMode = "per-vu"
+)
+
+// Scenario is a user journey such as login, browse, checkout. Each
+// iteration runs Requests in order; values a request extracts are visible
+// to the requests after it in the same VU.
+type Scenario struct {
+	Name     string
+	Requests []collection.Request
+	// Weight is the scenario's share of the virtual users; defaults to 1.
+	Weight int
+	// ThinkTime is the pause after each request.
+	ThinkTime time.Duration
+	// Data rows are set as variables at the start of each iteration.
+	Data     []map[string]string
+	DataMode DataMode
+}
+
+func (sc *Scenario) validate() error {
+	if len(sc.Requests) == 0 {
+		return fmt.Errorf("scenario %s has no requests", sc.Name)
+	}
+	switch sc.DataMode {
+	case "", DataShared, DataPerVU:
+		return nil
+	default:
+		return fmt.Errorf("scenario %s: unknown data mode %s", sc.Name, sc.DataMode)
+	}
+}
+
+type scenarioRun struct {
+	*Scenario
+	metrics *Metrics
+	cursor  atomic.Int64
+}
+
+func (sc *scenarioRun) row(vu int) map[string]string {
+	if len(sc.Data) == 0 {
+		return nil
+	}
+	if sc.DataMode == DataPerVU {
+		return sc.Data[vu%len(sc.Data)]
+	}
+	return sc.Data[int(sc.cursor.Add(1)-1)%len(sc.Data)]
+}
+
+// CollectionScenarios builds one scenario per folder, in the order folders
+// first appear. With no folders given, the whole collection is one scenario.
+func CollectionScenarios(coll *collection.Collection, folders ...string) ([]*Scenario, error) {
+	if len(folders) == 0 {
+		if len(coll.Requests) == 0 {
+			return nil, fmt.Errorf("collection %s has no requests", coll.Name)
+		}
+		return []*Scenario{{Name: coll.Name, Requests: coll.Requests}}, nil
+	}
+
+	var scenarios []*Scenario
+	for _, folder := range folders {
+		sc := &Scenario{Name: folder}
+		for _, req := range coll.Requests {
+			if strings.EqualFold(req.Folder, folder) {
+				sc.Requests = append(sc.Requests, req)
+			}
+		}
+		if len(sc.Requests) == 0 {
+			return nil, fmt.Errorf("folder %q has no requests", folder)
+		}
+		scenarios = append(scenarios, sc)
+	}
+	return scenarios, nil
+}
+
+// LoadData reads data rows from a CSV file with a header row, or a JSON
+// array of objects.
+func LoadData(path string) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open data: %w", err)
+	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		var items []map[string]interface{}
+		if err := json.NewDecoder(f).Decode(&items); err != nil {
+			return nil, fmt.Errorf("decode data: %w", err)
+		}
+		rows := make([]map[string]string, len(items))
+		for i, item := range items {
+			rows[i] = make(map[string]string, len(item))
+			for k, v := range item {
+				if s, ok := v.(string); ok {
+					rows[i][k] = s
+				} else {
+					out, _ := json.Marshal(v)
+					rows[i][k] = string(out)
+				}
+			}
+		}
+		return rows, nil
+	}
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%s needs a header row and at least one data row", path)
+	}
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(rec) {
+				row[strings.TrimSpace(name)] = rec[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,127 @@
+package load_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+// newShop serves a login that issues a per-user token and a cart that
+// requires it.
+func newShop(t *testing.T) (*httptest.Server, *sync.Map) {
+	t.Helper()
+	carts := &sync.Map{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			fmt.Fprintf(w, `{"token": "tok-%s"}`, r.URL.Query().Get("user"))
+		case "/cart":
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !strings.HasPrefix(token, "tok-") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			carts.Store(token, true)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, carts
+}
+
+func TestScenarioChainsVariablesPerVU(t *testing.T) {
+	srv, carts := newShop(t)
+	sc := &load.Scenario{
+		Name: "checkout",
+		Requests: []collection.Request{
+			{
+				Name:    "login",
+				Method:  "POST",
+				URL:     srv.URL + "/login?user={{user}}",
+				Extract: map[string]string{"token": "body.token"},
+			},
+			{
+				Name:    "cart",
+				Method:  "GET",
+				URL:     srv.URL + "/cart",
+				Headers: map[string]string{"Authorization": "Bearer {{token}}"},
+			},
+		},
+		Data:     []map[string]string{{"user": "ada"}, {"user": "bob"}, {"user": "cy"}},
+		DataMode: load.DataPerVU,
+	}
+
+	engine := load.NewEngine(&load.Config{VirtualUsers: 3, Iterations: 12}, collection.NewRunner("dev"))
+	result, err := engine.RunScenarios(context.Background(), sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Iterations != 12 || result.TotalRequests != 24 || result.FailedRequests != 0 {
+		t.Fatalf("iterations %d, requests %d, failed %d", result.Iterations, result.TotalRequests, result.FailedRequests)
+	}
+	for _, user := range []string{"ada", "bob", "cy"} {
+		if _, ok := carts.Load("tok-" + user); !ok {
+			t.Errorf("no cart request with %s's token", user)
+		}
+	}
+	if len(result.Requests) != 2 || result.Requests[0].Name != "cart" || result.Requests[0].Count != 12 {
+		t.Errorf("per-request stats = %+v", result.Requests)
+	}
+	if len(result.Scenarios) != 1 || result.Scenarios[0].Name != "checkout" {
+		t.Errorf("scenario stats = %+v", result.Scenarios)
+	}
+}
+
+func TestScenarioWeightsAndFailures(t *testing.T) {
+	srv, _ := newShop(t)
+	browse := &load.Scenario{Name: "browse", Weight: 3, Requests: []collection.Request{
+		{Name: "login", Method: "POST", URL: srv.URL + "/login"},
+	}}
+	anonymous := &load.Scenario{Name: "anonymous", Requests: []collection.Request{
+		{Name: "cart", Method: "GET", URL: srv.URL + "/cart"},
+	}}
+
+	engine := load.NewEngine(&load.Config{VirtualUsers: 4, Iterations: 40}, collection.NewRunner("dev"))
+	result, err := engine.RunScenarios(context.Background(), browse, anonymous)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := map[string]load.Stats{}
+	for _, s := range result.Scenarios {
+		stats[s.Name] = s
+	}
+	if stats["browse"].Count == 0 || stats["anonymous"].Count == 0 {
+		t.Fatalf("both scenarios should run: %+v", result.Scenarios)
+	}
+	if stats["anonymous"].Failed != stats["anonymous"].Count || stats["browse"].Failed != 0 {
+		t.Errorf("failures = %+v", result.Scenarios)
+	}
+	if !strings.Contains(result.String(), "anonymous › cart") {
+		t.Errorf("summary lacks per-request breakdown:\n%s", result)
+	}
+}
+
+func TestLoadDataCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	os.WriteFile(path, []byte("user,password\nada,secret\nbob,hunter2\n"), 0644)
+
+	rows, err := load.LoadData(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1]["user"] != "bob" || rows[1]["password"] != "hunter2" {
+		t.Errorf("rows = %v", rows)
+	}
+}
@@ -0,0 +1,164 @@
+package load
+
+// Snapshots are the wire form of an engine's metrics. Agents send them to
+// the coordinator, which merges the histograms bucket by bucket, so merged
+// percentiles are as accurate as local ones.
+
+type histSnapshot struct {
+	Counts map[int]int64 `json:"counts,omitempty"`
+	Sum    int64         `json:"sum"`
+	Min    int64         `json:"min"`
+	Max    int64         `json:"max"`
+}
+
+type metricsSnapshot struct {
+	Failed   int64                `json:"failed"`
+	Latency  histSnapshot         `json:"latency"`
+	Statuses map[int]histSnapshot `json:"statuses,omitempty"`
+	Errors   map[string]int64     `json:"errors,omitempty"`
+}
+
+type requestSnapshot struct {
+	Scenario string          `json:"scenario"`
+	Request  string          `json:"request"`
+	Metrics  metricsSnapshot `json:"metrics"`
+}
+
+type engineSnapshot struct {
+	VUs       int64                      `json:"vus"`
+	Dropped   int64                      `json:"dropped"`
+	NewConns  int64                      `json:"new_conns"`
+	Reused    int64                      `json:"reused_conns"`
+	Cut       int64                      `json:"interrupted"`
+	Total     metricsSnapshot            `json:"total"`
+	Scenarios map[string]metricsSnapshot `json:"scenarios,omitempty"`
+	Requests  []requestSnapshot          `json:"requests,omitempty"`
+	Failures  []FailureSample            `json:"failures,omitempty"`
+}
+
+func (h *histogram) snapshot() histSnapshot {
+	s := histSnapshot{Counts: make(map[int]int64), Sum: h.sum.Load(), Min: h.lowest(), Max: h.max.Load()}
+	for i := range h.counts {
+		if n := h.counts[i].Load(); n > 0 {
+			s.Counts[i] = n
+		}
+	}
+	return s
+}
+
+func (h *histogram) merge(s histSnapshot) {
+	var n int64
+	for i, c := range s.Counts {
+		if i >= 0 && i < histBuckets && c > 0 {
+			h.counts[i].Add(c)
+			n += c
+		}
+	}
+	if n == 0 {
+		return
+	}
+	h.bound(s.Min, s.Max)
+	h.sum.Add(s.Sum)
+	h.count.Add(n)
+}
+
+// since is what was recorded between prev and s. Min and max are only
+// known to bucket precision.
+func (s histSnapshot) since(prev histSnapshot) histSnapshot {
+	d := histSnapshot{Counts: make(map[int]int64), Sum: s.Sum - prev.Sum}
+	lo, hi := histBuckets, -1
+	for i, c := range s.Counts {
+		if c -= prev.Counts[i]; c > 0 {
+			d.Counts[i] = c
+			lo, hi = min(lo, i), max(hi, i)
+		}
+	}
+	if hi >= 0 {
+		d.Min, d.Max = histValue(lo), histValue(hi)
+	}
+	return d
+}
+
+func (m *Metrics) snapshot() metricsSnapshot {
+	s := metricsSnapshot{Failed: m.failed.Load(), Latency: m.latency.snapshot(), Errors: m.failureCounts()}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.statuses) > 0 {
+		s.Statuses = make(map[int]histSnapshot, len(m.statuses))
+		for code, h := range m.statuses {
+			s.Statuses[code] = h.snapshot()
+		}
+	}
+	return s
+}
+
+func (m *Metrics) merge(s metricsSnapshot) {
+	m.failed.Add(s.Failed)
+	m.latency.merge(s.Latency)
+	for kind, n := range s.Errors {
+		m.failureCount(kind).Add(n)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for code, hs := range s.Statuses {
+		h, ok := m.statuses[code]
+		if !ok {
+			h = newHistogram()
+			m.statuses[code] = h
+		}
+		h.merge(hs)
+	}
+}
+
+func (e *Engine) snapshot() engineSnapshot {
+	s := engineSnapshot{
+		VUs:      e.activeVUs.Load(),
+		Dropped:  e.dropped.Load(),
+		NewConns: e.newConns.Load(),
+		Reused:   e.reusedConns.Load(),
+		Cut:      e.interrupted.Load(),
+		Total:    e.metrics.snapshot(),
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s.Scenarios = make(map[string]metricsSnapshot, len(e.runs))
+	for _, run := range e.runs {
+		s.Scenarios[run.Name] = run.metrics.snapshot()
+	}
+	for key, m := range e.requests {
+		s.Requests = append(s.Requests, requestSnapshot{Scenario: key.scenario, Request: key.request, Metrics: m.snapshot()})
+	}
+	s.Failures = append(s.Failures, e.failures...)
+	return s
+}
+
+// merge adds an agent's snapshot to an aggregate engine.
+func (e *Engine) merge(s engineSnapshot) {
+	e.activeVUs.Add(s.VUs)
+	e.dropped.Add(s.Dropped)
+	e.newConns.Add(s.NewConns)
+	e.reusedConns.Add(s.Reused)
+	e.interrupted.Add(s.Cut)
+	e.metrics.merge(s.Total)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, run := range e.runs {
+		if ms, ok := s.Scenarios[run.Name]; ok {
+			run.metrics.merge(ms)
+		}
+	}
+	for _, rs := range s.Requests {
+		key := requestKey{rs.Scenario, rs.Request}
+		m, ok := e.requests[key]
+		if !ok {
+			m = newMetrics()
+			e.requests[key] = m
+		}
+		m.merge(rs.Metrics)
+	}
+	for _, f := range s.Failures {
+		if e.keepFailure(f.Kind) {
+			e.failures = append(e.failures, f)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package load
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Threshold is a pass/fail check on a run, written as
+// "[name:] metric op value [abort [after duration]]":
+//
+//	p95 < 300ms
+//	error_rate < 1%
+//	rps > 500
+//	Login: p99 <= 1s abort after 30s
+//
+// A name limits the check to requests, or failing that scenarios, with
+// that name. Metrics are avg, min, max, med, pNN, error_rate (percent),
+// rps, requests, failed and dropped_iterations. Abort thresholds are
+// checked every second, after the optional delay, and stop the run as
+// soon as they fail.
+type Threshold struct {
+	Source     string
+	Target     string
+	Metric     string
+	Op         string
+	Value      float64
+	Abort      bool
+	AbortDelay time.Duration
+}
+
+var thresholdRe = regexp.MustCompile(`^(?:(.+):)?\s*([a-z_]+|p\d+(?:\.\d+)?)\s*(<=|>=|<|>|==)\s*(\S+)(?:\s+(abort)(?:\s+after\s+(\S+))?)?$`)
+
+// ParseThreshold parses one threshold expression.
+func ParseThreshold(s string) (Threshold, error) {
+	m := thresholdRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Threshold{}, fmt.Errorf("threshold %q: want [name:] metric op value [abort [after duration]]", s)
+	}
+	t := Threshold{
+		Source: strings.TrimSpace(s),
+		Target: strings.TrimSpace(m[1]),
+		Metric: m[2],
+		Op:     m[3],
+		Abort:  m[5] != "",
+	}
+
+	var err error
+	switch {
+	case t.isLatency():
+		if t.Value, err = strconv.ParseFloat(m[4], 64); err == nil {
+			t.Value *= float64(time.Millisecond)
+		} else {
+			var d time.Duration
+			d, err = time.ParseDuration(m[4])
+			t.Value = float64(d)
+		}
+	case t.Metric == "error_rate", t.Metric == "rps", t.Metric == "requests", t.Metric == "failed", t.Metric == "dropped_iterations":
+		t.Value, err = strconv.ParseFloat(strings.TrimSuffix(m[4], "%"), 64)
+	default:
+		return t, fmt.Errorf("threshold %q: unknown metric %s", s, t.Metric)
+	}
+	if err != nil {
+		return t, fmt.Errorf("threshold %q: bad value %s", s, m[4])
+	}
+	if t.Metric == "dropped_iterations" && t.Target != "" {
+		return t, fmt.Errorf("threshold %q: dropped_iterations applies to the whole run", s)
+	}
+	if m[6] != "" {
+		if t.AbortDelay, err = time.ParseDuration(m[6]); err != nil {
+			return t, fmt.Errorf("threshold %q: %w", s, err)
+		}
+	}
+	return t, nil
+}
+
+func (t Threshold) isLatency() bool {
+	switch t.Metric {
+	case "avg", "min", "max", "med":
+		return true
+	}
+	_, ok := t.percentile()
+	return ok
+}
+
+func (t Threshold) percentile() (float64, bool) {
+	if !strings.HasPrefix(t.Metric, "p") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(t.Metric[1:], 64)
+	return p / 100, err == nil && p > 0 && p <= 100
+}
+
+func (t Threshold) passes(v float64) bool {
+	switch t.Op {
+	case "<":
+		return v < t.Value
+	case "<=":
+		return v <= t.Value
+	case ">":
+		return v > t.Value
+	case ">=":
+		return v >= t.Value
+	default:
+		return v == t.Value
+	}
+}
+
+// worse reports whether a is closer to failing t than b.
+func (t Threshold) worse(a, b float64) bool {
+	if t.Op == ">" || t.Op == ">=" {
+		return a < b
+	}
+	return a > b
+}
+
+func (t Threshold) format(v float64) string {
+	switch {
+	case t.isLatency():
+		return time.Duration(v).Round(time.Microsecond).String()
+	case t.Metric == "error_rate":
+		return fmt.Sprintf("%.2f%%", v)
+	case t.Metric == "rps":
+		return fmt.Sprintf("%.2f", v)
+	default:
+		return fmt.Sprintf("%.0f", v)
+	}
+}
+
+// ThresholdResult is the outcome of a threshold. When its name matches
+// several requests, Actual is the value closest to failing.
+type ThresholdResult struct {
+	Threshold
+	Actual float64
+	Passed bool
+}
+
+func (r ThresholdResult) String() string {
+	mark := "✅"
+	if !r.Passed {
+		mark = "❌"
+	}
+	return fmt.Sprintf("%s %s (%s = %s)", mark, r.Source, r.Metric, r.format(r.Actual))
+}
+
+func parseThresholds(exprs []string) ([]Threshold, error) {
+	thresholds := make([]Threshold, 0, len(exprs))
+	for _, expr := range exprs {
+		t, err := ParseThreshold(expr)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, nil
+}
+
+// checkTargets makes sure every named threshold matches a request or a
+// scenario, so a typo fails the run up front rather than passing silently.
+func checkTargets(thresholds []Threshold, scenarios []*Scenario) error {
+	for _, t := range thresholds {
+		if t.Target == "" {
+			continue
+		}
+		found := false
+		for _, sc := range scenarios {
+			found = found || strings.EqualFold(sc.Name, t.Target)
+			for _, req := range sc.Requests {
+				found = found || strings.EqualFold(req.Name, t.Target)
+			}
+		}
+		if !found {
+			return fmt.Errorf("threshold %q: no request or scenario named %s", t.Source, t.Target)
+		}
+	}
+	return nil
+}
+
+// evaluate checks t against the metrics collected so far.
+func (e *Engine) evaluate(t Threshold, elapsed time.Duration) ThresholdResult {
+	r := ThresholdResult{Threshold: t, Passed: true}
+	if t.Metric == "dropped_iterations" {
+		r.Actual = float64(e.dropped.Load())
+		r.Passed = t.passes(r.Actual)
+		return r
+	}
+	for i, m := range e.thresholdMetrics(t.Target) {
+		v := metricValue(m, t, elapsed)
+		if i == 0 || t.worse(v, r.Actual) {
+			r.Actual = v
+		}
+		r.Passed = r.Passed && t.passes(v)
+	}
+	return r
+}
+
+func (e *Engine) thresholdMetrics(target string) []*Metrics {
+	if target == "" {
+		return []*Metrics{e.metrics}
+	}
+	var ms []*Metrics
+	e.mu.Lock()
+	for key, m := range e.requests {
+		if strings.EqualFold(key.request, target) {
+			ms = append(ms, m)
+		}
+	}
+	e.mu.Unlock()
+	if len(ms) > 0 {
+		return ms
+	}
+	for _, run := range e.runs {
+		if strings.EqualFold(run.Name, target) {
+			ms = append(ms, run.metrics)
+		}
+	}
+	if len(ms) == 0 {
+		// The request has not run yet.
+		ms = append(ms, newMetrics())
+	}
+	return ms
+}
+
+func metricValue(m *Metrics, t Threshold, elapsed time.Duration) float64 {
+	count := m.latency.count.Load()
+	switch t.Metric {
+	case "avg":
+		return float64(m.latency.mean())
+	case "min":
+		return float64(m.latency.lowest())
+	case "max":
+		return float64(m.latency.max.Load())
+	case "med":
+		return float64(m.latency.percentile(0.5))
+	case "error_rate":
+		return percent(m.failed.Load(), count)
+	case "rps":
+		if elapsed <= 0 {
+			return 0
+		}
+		return float64(count) / elapsed.Seconds()
+	case "requests":
+		return float64(count)
+	case "failed":
+		return float64(m.failed.Load())
+	}
+	p, _ := t.percentile()
+	return float64(m.latency.percentile(p))
+}
+
+// checkAborts stops the run when an abort threshold fails.
+func (e *Engine) checkAborts(elapsed time.Duration) {
+	if e.abortedBy != "" {
+		return
+	}
+	for _, t := range e.thresholds {
+		if !t.Abort || elapsed < t.AbortDelay {
+			continue
+		}
+		if !e.evaluate(t, elapsed).Passed {
+			e.abortedBy = t.Source
+			e.abort(errAborted)
+			return
+		}
+	}
+}
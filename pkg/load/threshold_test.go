@@ -0,0 +1,185 @@
+package load_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		expr string
+		want load.Threshold
+	}{
+		{"p95 < 300ms", load.Threshold{Metric: "p95", Op: "<", Value: float64(300 * time.Millisecond)}},
+		{"error_rate<1%", load.Threshold{Metric: "error_rate", Op: "<", Value: 1}},
+		{"rps >= 500", load.Threshold{Metric: "rps", Op: ">=", Value: 500}},
+		{"p99.9 < 250", load.Threshold{Metric: "p99.9", Op: "<", Value: float64(250 * time.Millisecond)}},
+		{"List Users: avg <= 1s abort after 10s", load.Threshold{
+			Target: "List Users", Metric: "avg", Op: "<=", Value: float64(time.Second), Abort: true, AbortDelay: 10 * time.Second,
+		}},
+	}
+	for _, tt := range tests {
+		got, err := load.ParseThreshold(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		tt.want.Source = tt.expr
+		if got != tt.want {
+			t.Errorf("%s = %+v, want %+v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"p95", "latency < 1s", "p95 < fast", "p0 < 1s", "Login: dropped_iterations < 1", "rps > 5 abort after soon"} {
+		if _, err := load.ParseThreshold(bad); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestThresholdsEvaluatedAtEnd(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+
+	engine := load.NewEngine(&load.Config{
+		VirtualUsers: 2,
+		Iterations:   20,
+		Thresholds:   []string{"p95 < 10s", "error_rate < 1%", "ping: requests == 20", "rps > 1000000"},
+	}, collection.NewRunner("dev"))
+	result, err := engine.Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Thresholds) != 4 {
+		t.Fatalf("thresholds = %+v", result.Thresholds)
+	}
+	for i, want := range []bool{true, true, true, false} {
+		if got := result.Thresholds[i]; got.Passed != want {
+			t.Errorf("%s passed = %v, want %v", got.Source, got.Passed, want)
+		}
+	}
+	if result.ThresholdsPassed() {
+		t.Error("ThresholdsPassed with a failing threshold")
+	}
+	if out := result.String(); !strings.Contains(out, "❌ rps > 1000000 (rps = ") || !strings.Contains(out, "✅ ping: requests == 20 (requests = 20)") {
+		t.Errorf("summary:\n%s", out)
+	}
+}
+
+func TestThresholdUnknownTarget(t *testing.T) {
+	engine := load.NewEngine(&load.Config{
+		VirtualUsers: 1,
+		Iterations:   1,
+		Thresholds:   []string{"Checkout: p95 < 1s"},
+	}, collection.NewRunner("dev"))
+	_, err := engine.Run(context.Background(), collection.Request{Name: "ping", URL: "http://localhost"})
+	if err == nil || !strings.Contains(err.Error(), "Checkout") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestAbortThresholdStopsRun(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+	req.URL += "?fail=1"
+
+	engine := load.NewEngine(&load.Config{
+		VirtualUsers: 2,
+		Duration:     10 * time.Second,
+		Thresholds:   []string{"error_rate < 5% abort"},
+	}, collection.NewRunner("dev"))
+	result, err := engine.Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.AbortedBy != "error_rate < 5% abort" {
+		t.Errorf("aborted by %q", result.AbortedBy)
+	}
+	if result.Duration > 3*time.Second {
+		t.Errorf("run took %v after the threshold failed", result.Duration)
+	}
+	if result.ThresholdsPassed() {
+		t.Error("aborted run passed its thresholds")
+	}
+}
+
+// slowOutput takes as long as a remote-write call to a host that does
+// not answer.
+type slowOutput struct{ writes atomic.Int64 }
+
+func (o *slowOutput) Write(load.Sample) error {
+	o.writes.Add(1)
+	time.Sleep(2 * time.Second)
+	return nil
+}
+
+func (o *slowOutput) Close() error { return nil }
+
+func TestAbortThresholdNotDelayedByOutputs(t *testing.T) {
+	var hits atomic.Int64
+	req := newTarget(t, &hits)
+	req.URL += "?fail=1"
+
+	out := &slowOutput{}
+	engine := load.NewEngine(&load.Config{
+		VirtualUsers: 2,
+		Duration:     10 * time.Second,
+		Thresholds:   []string{"error_rate < 5% abort"},
+		Outputs:      []load.Output{out},
+	}, collection.NewRunner("dev"))
+	result, err := engine.Run(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AbortedBy == "" || result.Duration > 2*time.Second {
+		t.Errorf("aborted by %q after %v", result.AbortedBy, result.Duration)
+	}
+	if n := out.writes.Load(); n != int64(len(result.TimeSeries)) {
+		t.Errorf("output got %d of %d samples", n, len(result.TimeSeries))
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "smoke.yaml")
+	os.WriteFile(path, []byte(`
+executor: ramping-arrival-rate
+rate: 5
+stages:
+  - {duration: 30s, target: 50}
+  - {duration: 1m, target: 50}
+maxVUs: 20
+thinkTime: 250ms
+thresholds:
+  - p95 < 300ms
+`), 0o644)
+
+	profile, err := load.LoadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := profile.Config()
+	if cfg.Executor != load.ExecutorRampingArrivalRate || cfg.Rate != 5 || cfg.MaxVUs != 20 {
+		t.Errorf("config = %+v", cfg)
+	}
+	if len(cfg.Stages) != 2 || cfg.Stages[1] != (load.Stage{Duration: time.Minute, Target: 50}) {
+		t.Errorf("stages = %+v", cfg.Stages)
+	}
+	if profile.ThinkTime != 250*time.Millisecond || len(cfg.Thresholds) != 1 {
+		t.Errorf("profile = %+v", profile)
+	}
+
+	os.WriteFile(path, []byte("thresholds: [p95 < soon]\n"), 0o644)
+	if _, err := load.LoadProfile(path); err == nil {
+		t.Error("expected an error for a bad threshold")
+	}
+}
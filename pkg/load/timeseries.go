@@ -0,0 +1,162 @@
+package load
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Sample aggregates the requests completed in one interval of a running
+// test, normally a second. Time is the end of the interval.
+type Sample struct {
+	Time     time.Time
+	VUs      int
+	Requests int64
+	Failed   int64
+	Dropped  int64
+	// NewConns counts responses that needed a new connection.
+	NewConns int64
+	RPS      float64
+	Avg      time.Duration
+	P50      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+// Output receives each sample as soon as its interval ends.
+type Output interface {
+	Write(Sample) error
+	Close() error
+}
+
+type window struct {
+	start    time.Time
+	latency  *histogram
+	failed   atomic.Int64
+	newConns atomic.Int64
+}
+
+func newWindow(start time.Time) *window {
+	return &window{start: start, latency: newHistogram()}
+}
+
+func (w *window) record(latency time.Duration, ok bool) {
+	w.latency.record(latency)
+	if !ok {
+		w.failed.Add(1)
+	}
+}
+
+// collect closes a window every second until stop is closed, then waits
+// for the outputs to catch up.
+func (e *Engine) collect(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	outputs := startOutputs(e.config.Outputs)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.flush(outputs, false)
+		case <-stop:
+			e.flush(outputs, true)
+			e.outputErr = outputs.close()
+			return
+		}
+	}
+}
+
+// flush turns the current window into a sample, starts the next one,
+// checks abort thresholds and queues the sample for the outputs. The last,
+// partial window of a run is skipped when nothing happened in it.
+func (e *Engine) flush(outputs *outputQueue, last bool) {
+	now := time.Now()
+	w := e.window.Swap(newWindow(now))
+	dropped := e.dropped.Load()
+	s := Sample{
+		Time:     now,
+		VUs:      int(e.activeVUs.Load()),
+		Requests: w.latency.count.Load(),
+		Failed:   w.failed.Load(),
+		Dropped:  dropped - e.flushedDropped,
+		NewConns: w.newConns.Load(),
+		Avg:      w.latency.mean(),
+		P50:      w.latency.percentile(0.50),
+		P95:      w.latency.percentile(0.95),
+		P99:      w.latency.percentile(0.99),
+		Max:      time.Duration(w.latency.max.Load()),
+	}
+	e.flushedDropped = dropped
+	if last && s.Requests == 0 && s.Dropped == 0 {
+		return
+	}
+	if d := now.Sub(w.start); d > 0 {
+		s.RPS = float64(s.Requests) / d.Seconds()
+	}
+
+	e.mu.Lock()
+	e.series = append(e.series, s)
+	e.mu.Unlock()
+	if !last {
+		e.checkAborts(now.Sub(e.started))
+		e.checkBreak()
+	}
+	outputs.write(s)
+}
+
+// outputQueueSize is how many samples the outputs may fall behind by
+// before samples are dropped.
+const outputQueueSize = 16
+
+// outputQueue writes samples to outputs on its own goroutine, so that a
+// slow output, such as an unreachable remote-write endpoint, never holds
+// up the per-second windows or threshold aborts.
+type outputQueue struct {
+	outputs []Output
+	samples chan Sample
+	done    chan struct{}
+	dropped int
+	// err is set by the writer and read once done is closed.
+	err error
+}
+
+func startOutputs(outputs []Output) *outputQueue {
+	q := &outputQueue{outputs: outputs, samples: make(chan Sample, outputQueueSize), done: make(chan struct{})}
+	go q.run()
+	return q
+}
+
+func (q *outputQueue) run() {
+	defer close(q.done)
+	for s := range q.samples {
+		for _, out := range q.outputs {
+			if err := out.Write(s); err != nil && q.err == nil {
+				q.err = fmt.Errorf("write output: %w", err)
+			}
+		}
+	}
+}
+
+// write queues s, dropping it if the outputs have fallen too far behind.
+func (q *outputQueue) write(s Sample) {
+	if len(q.outputs) == 0 {
+		return
+	}
+	select {
+	case q.samples <- s:
+	default:
+		q.dropped++
+	}
+}
+
+// close waits for the queued samples to be written and returns the first
+// error, if any.
+func (q *outputQueue) close() error {
+	close(q.samples)
+	<-q.done
+	if q.err == nil && q.dropped > 0 {
+		return fmt.Errorf("write output: dropped %d samples while outputs fell behind", q.dropped)
+	}
+	return q.err
+}
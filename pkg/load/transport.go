@@ -0,0 +1,108 @@
+package load
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	nexushttp "github.com/nexusapi/nexus/pkg/http"
+)
+
+// Transport controls the HTTP connections of a load test. Without one,
+// VUs share the runner's client.
+type Transport struct {
+	// DisableKeepAlives opens a connection for every request.
+	DisableKeepAlives bool `yaml:"disableKeepAlives"`
+	// PerVU gives each VU its own connection pool, as separate clients
+	// would have; by default VUs share one.
+	PerVU bool `yaml:"perVU"`
+	// MaxConnsPerHost caps the connections of each pool to a host.
+	MaxConnsPerHost int `yaml:"maxConnsPerHost"`
+	// Protocol forces "http1" or "http2".
+	Protocol             string `yaml:"protocol"`
+	TLSSessionResumption bool   `yaml:"tlsSessionResumption"`
+	InsecureSkipVerify   bool   `yaml:"insecureSkipVerify"`
+	// LocalAddrs are source IP addresses, handed out to VUs in turn.
+	LocalAddrs []string      `yaml:"localAddrs"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+// maxIdlePerHost keeps every connection a pool opens available for reuse.
+const maxIdlePerHost = 1 << 16
+
+func (t *Transport) validate() error {
+	switch t.Protocol {
+	case "", "http1", "http2":
+	default:
+		return fmt.Errorf("unknown protocol %q; use http1 or http2", t.Protocol)
+	}
+	for _, addr := range t.LocalAddrs {
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("local address %q is not an IP address", addr)
+		}
+	}
+	if t.MaxConnsPerHost < 0 {
+		return fmt.Errorf("max connections must not be negative")
+	}
+	return nil
+}
+
+func (t *Transport) clientConfig(localAddr string) *nexushttp.Config {
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &nexushttp.Config{
+		Timeout:               timeout,
+		InsecureSkipVerify:    t.InsecureSkipVerify,
+		MaxConnsPerHost:       maxIdlePerHost,
+		EnableHTTP2:           true,
+		MaxActiveConnsPerHost: t.MaxConnsPerHost,
+		DisableKeepAlives:     t.DisableKeepAlives,
+		Protocol:              t.Protocol,
+		TLSSessionResumption:  t.TLSSessionResumption,
+		LocalAddr:             localAddr,
+	}
+}
+
+// client returns the HTTP client of VU id: its own with PerVU, otherwise
+// the one shared by VUs with the same source address.
+func (e *Engine) client(id int) *nexushttp.Client {
+	t := e.config.Transport
+	var addr string
+	key := 0
+	if n := len(t.LocalAddrs); n > 0 {
+		key = id % n
+		addr = t.LocalAddrs[key]
+	}
+	if t.PerVU {
+		key = id
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.clients[key]
+	if !ok {
+		c = nexushttp.NewClient(t.clientConfig(addr))
+		e.clients[key] = c
+	}
+	return c
+}
+
+func (e *Engine) closeClients() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, c := range e.clients {
+		c.CloseIdleConnections()
+	}
+}
+
+// recordConn counts whether a response came over a new connection.
+func (e *Engine) recordConn(reused bool) {
+	if reused {
+		e.reusedConns.Add(1)
+	} else {
+		e.newConns.Add(1)
+		e.window.Load().newConns.Add(1)
+	}
+}
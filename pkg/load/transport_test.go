@@ -0,0 +1,79 @@
+package load_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+// newConnTarget serves a request and counts the connections it accepts.
+func newConnTarget(t *testing.T) (collection.Request, *atomic.Int64) {
+	t.Helper()
+	var conns atomic.Int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return collection.Request{Name: "ping", Method: "GET", URL: srv.URL}, &conns
+}
+
+func TestTransportConnections(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport load.Transport
+		check     func(t *testing.T, r *load.LoadTestResult, conns int64)
+	}{
+		{"shared", load.Transport{}, func(t *testing.T, r *load.LoadTestResult, conns int64) {
+			// A dial that loses the race to an idle connection is pooled
+			// unused, so the server may see more than were used; no more
+			// than one per VU are ever needed.
+			if r.NewConnections > 4 || r.NewConnections > conns || r.NewConnections+r.ReusedConnections != 40 {
+				t.Errorf("conns = %d, new = %d, reused = %d", conns, r.NewConnections, r.ReusedConnections)
+			}
+		}},
+		{"per VU", load.Transport{PerVU: true, MaxConnsPerHost: 1}, func(t *testing.T, r *load.LoadTestResult, conns int64) {
+			if conns != 4 || r.NewConnections != 4 {
+				t.Errorf("conns = %d, new = %d, want one per VU", conns, r.NewConnections)
+			}
+		}},
+		{"no keep-alive", load.Transport{DisableKeepAlives: true}, func(t *testing.T, r *load.LoadTestResult, conns int64) {
+			if conns != 40 || r.NewConnections != 40 || r.ReusedConnections != 0 {
+				t.Errorf("conns = %d, new = %d, reused = %d", conns, r.NewConnections, r.ReusedConnections)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, conns := newConnTarget(t)
+			result := runRequest(t, load.Config{VirtualUsers: 4, Iterations: 40, Transport: &tt.transport}, req)
+			if result.FailedRequests != 0 {
+				t.Fatalf("failed = %d: %v", result.FailedRequests, result.Errors)
+			}
+			tt.check(t, result, conns.Load())
+			if !strings.Contains(result.String(), "Connections: ") {
+				t.Errorf("summary:\n%s", result)
+			}
+		})
+	}
+}
+
+func TestTransportValidated(t *testing.T) {
+	for _, tr := range []load.Transport{{Protocol: "spdy"}, {LocalAddrs: []string{"eth0"}}} {
+		cfg := &load.Config{VirtualUsers: 1, Iterations: 1, Transport: &tr}
+		_, err := load.NewEngine(cfg, collection.NewRunner("dev")).Run(context.Background(), collection.Request{URL: "http://localhost"})
+		if err == nil || !strings.HasPrefix(err.Error(), "transport: ") {
+			t.Errorf("%+v: err = %v", tr, err)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package mock
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AdminEndpoint is the admin API view of an endpoint or resource.
+type AdminEndpoint struct {
+	ID             string `json:"id" yaml:"id"`
+	EndpointConfig `yaml:",inline"`
+	// Dynamic endpoints, such as those generated from an OpenAPI spec,
+	// compute their responses in code and are left out of exports.
+	Dynamic bool `json:"dynamic,omitempty" yaml:"dynamic,omitempty"`
+}
+
+// Endpoints lists every endpoint and resource in the order they were added.
+func (s *Server) Endpoints() []AdminEndpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]AdminEndpoint, 0, len(s.endpoints)+len(s.resources))
+	for _, ep := range s.endpoints {
+		out = append(out, AdminEndpoint{ID: ep.ID, EndpointConfig: ep.config(), Dynamic: ep.Handler != nil && ep.GraphQL == nil})
+	}
+	for _, res := range s.resources {
+		out = append(out, AdminEndpoint{ID: res.ID, EndpointConfig: res.config()})
+	}
+	sort.Slice(out, func(i, j int) bool { return idLess(out[i].ID, out[j].ID) })
+	return out
+}
+
+// Export returns the server's current endpoints, resources and faults as a
+// config that Import or Apply can load again.
+func (s *Server) Export() *Config {
+	cfg := &Config{Endpoints: []EndpointConfig{}}
+	for _, ae := range s.Endpoints() {
+		if !ae.Dynamic {
+			cfg.Endpoints = append(cfg.Endpoints, ae.EndpointConfig)
+		}
+	}
+
+	s.mu.RLock()
+	cfg.Faults = s.faults
+	s.mu.RUnlock()
+
+	for _, sc := range s.Scenarios() {
+		if sc.InitialState != "" || sc.IsolateBy != "" {
+			cfg.Scenarios = append(cfg.Scenarios, sc)
+		}
+	}
+	return cfg
+}
+
+// Import loads cfg into the server. With replace, everything currently
+// registered is removed first. Nothing changes if cfg is invalid.
+func (s *Server) Import(cfg *Config, replace bool) error {
+	staged := NewServer()
+	if err := cfg.Apply(staged); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if replace {
+		s.endpoints = make(map[string]*Endpoint)
+		s.resources = nil
+		s.faults = nil
+		s.scenarios = make(map[string]*Scenario)
+	}
+	for name, sc := range staged.scenarios {
+		s.scenarios[name] = sc
+	}
+	for _, ep := range staged.endpoints {
+		ep.ID = ""
+		s.addEndpoint(ep)
+	}
+	for _, res := range staged.resources {
+		res.ID = ""
+		s.addResource(res)
+	}
+	if cfg.Faults != nil || replace {
+		s.faults = cfg.Faults
+	}
+	return nil
+}
+
+// AdminHandler serves only the admin routes, for exposing them on a
+// separate listener.
+func (s *Server) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := strings.CutPrefix(r.URL.Path, AdminPrefix)
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown admin route")
+			return
+		}
+		s.serveAdmin(w, r, route)
+	})
+}
+
+// handleEndpoints serves the endpoint admin routes:
+//
+//	GET    /endpoints       list endpoints and resources
+//	POST   /endpoints       create from an EndpointConfig (JSON or YAML)
+//	GET    /endpoints/{id}  get one
+//	PUT    /endpoints/{id}  replace, keeping the id
+//	DELETE /endpoints/{id}  remove
+func (s *Server) handleEndpoints(w http.ResponseWriter, r *http.Request, id string) {
+	if id == "" {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.Endpoints())
+		case http.MethodPost:
+			s.createEndpoint(w, r)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		for _, ae := range s.Endpoints() {
+			if ae.ID == id {
+				writeJSON(w, http.StatusOK, ae)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, fmt.Sprintf("endpoint %s not found", id))
+	case http.MethodPut:
+		s.updateEndpoint(w, r, id)
+	case http.MethodDelete:
+		if !s.removeByID(id) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("endpoint %s not found", id))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) createEndpoint(w http.ResponseWriter, r *http.Request) {
+	ep, res, err := decodeEndpoint(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if s.conflicts(ep, res, "") {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "an endpoint with the same method and path already exists")
+		return
+	}
+	var view AdminEndpoint
+	if res != nil {
+		s.addResource(res)
+		view = AdminEndpoint{ID: res.ID, EndpointConfig: res.config()}
+	} else {
+		s.addEndpoint(ep)
+		view = AdminEndpoint{ID: ep.ID, EndpointConfig: ep.config()}
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Location", AdminPrefix+"/endpoints/"+view.ID)
+	writeJSON(w, http.StatusCreated, view)
+}
+
+func (s *Server) updateEndpoint(w http.ResponseWriter, r *http.Request, id string) {
+	ep, res, err := decodeEndpoint(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasID(id) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("endpoint %s not found", id))
+		return
+	}
+	if s.conflicts(ep, res, id) {
+		writeError(w, http.StatusConflict, "an endpoint with the same method and path already exists")
+		return
+	}
+	s.removeLocked(id)
+
+	if res != nil {
+		res.ID = id
+		s.addResource(res)
+		writeJSON(w, http.StatusOK, AdminEndpoint{ID: id, EndpointConfig: res.config()})
+		return
+	}
+	ep.ID = id
+	s.addEndpoint(ep)
+	writeJSON(w, http.StatusOK, AdminEndpoint{ID: id, EndpointConfig: ep.config()})
+}
+
+func decodeEndpoint(r *http.Request) (*Endpoint, *Resource, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read body: %w", err)
+	}
+	// YAML is a superset of JSON, so this accepts both and lets durations
+	// be written as strings such as "250ms".
+	var ec EndpointConfig
+	if err := yaml.Unmarshal(data, &ec); err != nil {
+		return nil, nil, fmt.Errorf("decode endpoint: %w", err)
+	}
+	return (&Config{}).build(ec)
+}
+
+// conflicts reports whether another endpoint or resource, other than the
+// one with id except, is registered at the same route. Caller holds s.mu.
+func (s *Server) conflicts(ep *Endpoint, res *Resource, except string) bool {
+	if res != nil {
+		for _, existing := range s.resources {
+			if existing.Path == res.Path && existing.ID != except {
+				return true
+			}
+		}
+		return false
+	}
+	existing, ok := s.endpoints[endpointKey(ep)]
+	return ok && existing.ID != except
+}
+
+func (s *Server) hasID(id string) bool {
+	for _, ep := range s.endpoints {
+		if ep.ID == id {
+			return true
+		}
+	}
+	for _, res := range s.resources {
+		if res.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) removeByID(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.removeLocked(id)
+}
+
+func (s *Server) removeLocked(id string) bool {
+	for key, ep := range s.endpoints {
+		if ep.ID == id {
+			delete(s.endpoints, key)
+			return true
+		}
+	}
+	for i, res := range s.resources {
+		if res.ID == id {
+			s.resources = append(s.resources[:i], s.resources[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// handleConfig exports the server as YAML on GET, replaces everything with
+// the posted config on PUT and merges it in on POST.
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		data, err := yaml.Marshal(s.Export())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("marshal yaml: %v", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(data)
+	case http.MethodPut, http.MethodPost:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("read body: %v", err))
+			return
+		}
+		cfg, err := ParseConfig(data)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := s.Import(cfg, r.Method == http.MethodPut); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Endpoints())
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
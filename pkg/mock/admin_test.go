@@ -0,0 +1,160 @@
+package mock_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+)
+
+func adminDo(t *testing.T, method, url, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return res, data
+}
+
+func TestAdmin_EndpointCRUD(t *testing.T) {
+	srv := mock.NewServer()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	admin := ts.URL + mock.AdminPrefix + "/endpoints"
+
+	res, body := adminDo(t, "POST", admin, `{"method":"GET","path":"/greet","delay":"1ms","response":{"status":200,"body":{"msg":"hi"}}}`)
+	if res.StatusCode != 201 {
+		t.Fatalf("create: %d %s", res.StatusCode, body)
+	}
+	var created mock.AdminEndpoint
+	json.Unmarshal(body, &created)
+	if created.ID == "" || res.Header.Get("Location") != mock.AdminPrefix+"/endpoints/"+created.ID {
+		t.Fatalf("unexpected create response: %+v %s", created, res.Header.Get("Location"))
+	}
+
+	if res, _ := adminDo(t, "POST", admin, `{"method":"GET","path":"/greet"}`); res.StatusCode != 409 {
+		t.Fatalf("expected conflict, got %d", res.StatusCode)
+	}
+	if res, body := adminDo(t, "GET", ts.URL+"/greet", ""); res.StatusCode != 200 || !strings.Contains(string(body), `"hi"`) {
+		t.Fatalf("mock response: %d %s", res.StatusCode, body)
+	}
+
+	res, body = adminDo(t, "PUT", admin+"/"+created.ID, `{"method":"GET","path":"/greet","response":{"status":202,"body":"updated"}}`)
+	if res.StatusCode != 200 {
+		t.Fatalf("update: %d %s", res.StatusCode, body)
+	}
+	if res, body := adminDo(t, "GET", ts.URL+"/greet", ""); res.StatusCode != 202 || string(body) != "updated" {
+		t.Fatalf("updated response: %d %s", res.StatusCode, body)
+	}
+
+	res, body = adminDo(t, "GET", admin, "")
+	var list []mock.AdminEndpoint
+	json.Unmarshal(body, &list)
+	if len(list) != 1 || list[0].ID != created.ID || list[0].Response.Status != 202 {
+		t.Fatalf("unexpected list: %s", body)
+	}
+
+	if res, _ := adminDo(t, "DELETE", admin+"/"+created.ID, ""); res.StatusCode != 204 {
+		t.Fatalf("delete: %d", res.StatusCode)
+	}
+	if res, _ := adminDo(t, "GET", admin+"/"+created.ID, ""); res.StatusCode != 404 {
+		t.Fatalf("expected deleted endpoint to be gone, got %d", res.StatusCode)
+	}
+	if res, _ := adminDo(t, "GET", ts.URL+"/greet", ""); res.StatusCode != 404 {
+		t.Fatalf("expected mock route to be gone, got %d", res.StatusCode)
+	}
+	if res, _ := adminDo(t, "POST", admin, `{"method":"GET"}`); res.StatusCode != 400 {
+		t.Fatalf("expected validation error, got %d", res.StatusCode)
+	}
+}
+
+func TestAdmin_ExportImport(t *testing.T) {
+	srv := mock.NewServer()
+	cfg, err := mock.ParseConfig([]byte(`
+endpoints:
+  - method: POST
+    path: /login
+    delay: 5ms
+    match:
+      body: '"user":"admin"'
+    response:
+      status: 200
+      body: {token: abc}
+  - path: /api/todos
+    resource:
+      items:
+        - {id: 1, title: write tests}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Apply(srv); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	configURL := ts.URL + mock.AdminPrefix + "/config"
+
+	adminDo(t, "POST", ts.URL+"/api/todos", `{"title":"ship it"}`)
+	_, exported := adminDo(t, "GET", configURL, "")
+	for _, want := range []string{"delay: 5ms", `body: '"user":"admin"'`, "ship it"} {
+		if !strings.Contains(string(exported), want) {
+			t.Fatalf("export missing %q:\n%s", want, exported)
+		}
+	}
+
+	other := mock.NewServer()
+	other.AddEndpoint(&mock.Endpoint{Path: "/stale", Method: "GET", Response: mock.Response{StatusCode: 200}})
+	ts2 := httptest.NewServer(other)
+	defer ts2.Close()
+
+	if res, body := adminDo(t, "PUT", ts2.URL+mock.AdminPrefix+"/config", string(exported)); res.StatusCode != 200 {
+		t.Fatalf("import: %d %s", res.StatusCode, body)
+	}
+	if res, _ := adminDo(t, "GET", ts2.URL+"/stale", ""); res.StatusCode != 404 {
+		t.Fatalf("replace import kept old endpoint: %d", res.StatusCode)
+	}
+	if res, body := adminDo(t, "GET", ts2.URL+"/api/todos", ""); res.StatusCode != 200 || !strings.Contains(string(body), "ship it") {
+		t.Fatalf("imported resource: %d %s", res.StatusCode, body)
+	}
+	if res, _ := adminDo(t, "POST", ts2.URL+"/login", `{"user":"admin"}`); res.StatusCode != 200 {
+		t.Fatalf("imported endpoint: %d", res.StatusCode)
+	}
+
+	if res, _ := adminDo(t, "PUT", ts2.URL+mock.AdminPrefix+"/config", "endpoints:\n  - path: /x\n    match: {body: '('}\n"); res.StatusCode != 400 {
+		t.Fatalf("expected invalid import to fail, got %d", res.StatusCode)
+	}
+	if res, _ := adminDo(t, "GET", ts2.URL+"/api/todos", ""); res.StatusCode != 200 {
+		t.Fatalf("failed import changed the server: %d", res.StatusCode)
+	}
+}
+
+func TestAdmin_SeparateListener(t *testing.T) {
+	srv := mock.NewServer()
+	srv.SetAdmin(false)
+	mockTS := httptest.NewServer(srv)
+	defer mockTS.Close()
+	adminTS := httptest.NewServer(srv.AdminHandler())
+	defer adminTS.Close()
+
+	if res, _ := adminDo(t, "GET", mockTS.URL+mock.AdminPrefix+"/endpoints", ""); res.StatusCode != 404 {
+		t.Fatalf("admin routes should be disabled on the mock port, got %d", res.StatusCode)
+	}
+	if res, _ := adminDo(t, "POST", adminTS.URL+mock.AdminPrefix+"/endpoints", `{"path":"/ok"}`); res.StatusCode != 201 {
+		t.Fatalf("create via admin listener: %d", res.StatusCode)
+	}
+	if res, _ := adminDo(t, "GET", mockTS.URL+"/ok", ""); res.StatusCode != 200 {
+		t.Fatalf("endpoint created via admin listener not served: %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,333 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCallbackTimeout    = 10 * time.Second
+	defaultCallbackRetryDelay = 100 * time.Millisecond
+)
+
+// Callback is an outbound request fired after an endpoint responds, such as
+// a payment provider's webhook. URL, headers and body are templates; see
+// templateData for the available {{placeholders}}.
+type Callback struct {
+	Method  string            `json:"method,omitempty" yaml:"method,omitempty"`
+	URL     string            `json:"url" yaml:"url"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty" yaml:"body,omitempty"`
+	Delay   time.Duration     `json:"delay,omitempty" yaml:"delay,omitempty"`
+	// Retries is how many more attempts are made after a failure or non-2xx
+	// status, waiting RetryDelay and doubling it each time.
+	Retries    int           `json:"retries,omitempty" yaml:"retries,omitempty"`
+	RetryDelay time.Duration `json:"retryDelay,omitempty" yaml:"retryDelay,omitempty"`
+	Timeout    time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// CallbackResult is the outcome of a callback, attached to the journal entry
+// of the request that triggered it.
+type CallbackResult struct {
+	Method   string        `json:"method"`
+	URL      string        `json:"url"`
+	Time     time.Time     `json:"time"`
+	Attempts int           `json:"attempts"`
+	Status   int           `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (c *Callback) validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("callback missing url")
+	}
+	if c.Retries < 0 {
+		return fmt.Errorf("callback retries must not be negative")
+	}
+	return nil
+}
+
+// fireCallbacks schedules e's callbacks for the request recorded as entry id.
+func (s *Server) fireCallbacks(e *Endpoint, id int64, data *templateData) {
+	for _, cb := range e.Callbacks {
+		s.callbacks.Add(1)
+		go func() {
+			defer s.callbacks.Done()
+			result := cb.run(s.callbackCtx, data)
+			s.journal.attach(id, result)
+		}()
+	}
+}
+
+// Close cancels pending callbacks and waits for them to finish.
+func (s *Server) Close() {
+	s.stopCallbacks()
+	s.callbacks.Wait()
+}
+
+// WaitCallbacks blocks until every scheduled callback has finished or ctx
+// is done.
+func (s *Server) WaitCallbacks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.callbacks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Callback) run(ctx context.Context, data *templateData) CallbackResult {
+	method := c.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	result := CallbackResult{Method: method, URL: data.render(c.URL)}
+
+	if !sleepContext(ctx, c.Delay) {
+		result.Error = "cancelled"
+		return result
+	}
+
+	body, contentType, err := c.renderBody(data)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Time = time.Now()
+	wait := c.RetryDelay
+	if wait <= 0 {
+		wait = defaultCallbackRetryDelay
+	}
+	for {
+		result.Attempts++
+		result.Status, err = c.send(ctx, method, result.URL, body, contentType, data)
+		if err == nil && result.Status >= 200 && result.Status < 300 {
+			result.Error = ""
+			break
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Error = fmt.Sprintf("unexpected status %d", result.Status)
+		}
+		if result.Attempts > c.Retries || !sleepContext(ctx, wait) {
+			break
+		}
+		wait *= 2
+	}
+	result.Duration = time.Since(result.Time)
+	return result
+}
+
+func (c *Callback) renderBody(data *templateData) ([]byte, string, error) {
+	switch b := c.Body.(type) {
+	case nil:
+		return nil, "", nil
+	case string:
+		return []byte(data.render(b)), "", nil
+	default:
+		out, err := json.Marshal(data.renderValue(normalizeYAML(b)))
+		if err != nil {
+			return nil, "", fmt.Errorf("marshal callback body: %w", err)
+		}
+		return out, "application/json", nil
+	}
+}
+
+func (c *Callback) send(ctx context.Context, method, target string, body []byte, contentType string, data *templateData) (int, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultCallbackTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, data.render(v))
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	return res.StatusCode, nil
+}
+
+var placeholderPattern = regexp.MustCompile(`\{\{([^}]+)\}\}`)
+
+// templateData resolves placeholders from the triggering exchange:
+//
+//	{{request.method}} {{request.path}} {{request.url}} {{request.body}}
+//	{{request.body.<field.path>}} {{request.query.<name>}}
+//	{{request.headers.<name>}} {{request.params.<name>}}
+//	{{response.status}} {{response.body}} {{response.body.<field.path>}}
+//	{{response.headers.<name>}} {{$randomUUID}} {{$timestamp}}
+//
+// Unknown placeholders are left as is.
+type templateData struct {
+	method      string
+	url         *url.URL
+	headers     http.Header
+	body        []byte
+	params      map[string]string
+	status      int
+	respHeaders http.Header
+	respBody    []byte
+}
+
+func newTemplateData(e *Endpoint, r *http.Request, body []byte, resp *bufferedResponse) *templateData {
+	params, _ := matchTemplate(e.Path, r.URL.Path)
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := *r.URL
+	u.Scheme, u.Host = scheme, r.Host
+	return &templateData{
+		method:      r.Method,
+		url:         &u,
+		headers:     r.Header.Clone(),
+		body:        body,
+		params:      params,
+		status:      resp.status,
+		respHeaders: resp.header.Clone(),
+		respBody:    bytes.Clone(resp.body.Bytes()),
+	}
+}
+
+func (d *templateData) render(s string) string {
+	return placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		v, ok := d.lookup(strings.TrimSpace(match[2 : len(match)-2]))
+		if !ok {
+			return match
+		}
+		if str, isString := v.(string); isString {
+			return str
+		}
+		out, _ := json.Marshal(v)
+		return string(out)
+	})
+}
+
+// renderValue renders every string in v. A string that is exactly one
+// placeholder takes the looked-up value's JSON type, so "{{request.body.amount}}"
+// stays a number.
+func (d *templateData) renderValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		if m := placeholderPattern.FindStringSubmatch(val); m != nil && m[0] == val {
+			if found, ok := d.lookup(strings.TrimSpace(m[1])); ok {
+				return found
+			}
+		}
+		return d.render(val)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = d.renderValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = d.renderValue(item)
+		}
+		return out
+	default:
+		return val
+	}
+}
+
+func (d *templateData) lookup(key string) (interface{}, bool) {
+	switch key {
+	case "$randomUUID":
+		return newUUID(), true
+	case "$timestamp":
+		return strconv.FormatInt(time.Now().Unix(), 10), true
+	case "request.method":
+		return d.method, true
+	case "request.path":
+		return d.url.Path, true
+	case "request.url":
+		return d.url.String(), true
+	case "request.body":
+		return string(d.body), true
+	case "response.status":
+		return d.status, true
+	case "response.body":
+		return string(d.respBody), true
+	}
+
+	scope, rest, _ := strings.Cut(key, ".")
+	field, name, ok := strings.Cut(rest, ".")
+	if !ok {
+		return nil, false
+	}
+	switch {
+	case scope == "request" && field == "query" && d.url.Query().Has(name):
+		return d.url.Query().Get(name), true
+	case scope == "request" && field == "headers" && d.headers.Get(name) != "":
+		return d.headers.Get(name), true
+	case scope == "request" && field == "params" && d.params[name] != "":
+		return d.params[name], true
+	case scope == "request" && field == "body":
+		return jsonField(d.body, name)
+	case scope == "response" && field == "headers" && d.respHeaders.Get(name) != "":
+		return d.respHeaders.Get(name), true
+	case scope == "response" && field == "body":
+		return jsonField(d.respBody, name)
+	}
+	return nil, false
+}
+
+// jsonField looks up a dot-separated path such as "items.0.id" in a JSON
+// document.
+func jsonField(data []byte, path string) (interface{}, bool) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, false
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = node[part]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
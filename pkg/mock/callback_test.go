@@ -0,0 +1,141 @@
+package mock_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+)
+
+func TestCallback_FiresTemplatedRequest(t *testing.T) {
+	type delivery struct {
+		path, signature string
+		body            map[string]interface{}
+	}
+	received := make(chan delivery, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var d delivery
+		d.path = r.URL.Path
+		d.signature = r.Header.Get("X-Signature")
+		json.NewDecoder(r.Body).Decode(&d.body)
+		received <- d
+	}))
+	defer receiver.Close()
+
+	cfg, err := mock.ParseConfig([]byte(`
+endpoints:
+  - method: POST
+    path: /payments/{id}
+    response:
+      status: 202
+      body: {state: processing}
+    callbacks:
+      - url: "{{request.headers.X-Callback-Url}}/webhooks/{{request.params.id}}"
+        delay: 20ms
+        headers: {X-Signature: "sig-{{request.body.orderId}}"}
+        body:
+          orderId: "{{request.body.orderId}}"
+          amount: "{{request.body.amount}}"
+          status: paid
+          previous: "{{response.body.state}}"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := mock.NewServer()
+	if err := cfg.Apply(srv); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("POST", ts.URL+"/payments/p-1", strings.NewReader(`{"orderId":"o-9","amount":42.5}`))
+	req.Header.Set("X-Callback-Url", receiver.URL)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 202 {
+		t.Fatalf("expected 202, got %d", res.StatusCode)
+	}
+
+	select {
+	case d := <-received:
+		if d.path != "/webhooks/p-1" || d.signature != "sig-o-9" {
+			t.Fatalf("unexpected delivery %+v", d)
+		}
+		if d.body["orderId"] != "o-9" || d.body["amount"] != 42.5 || d.body["previous"] != "processing" {
+			t.Fatalf("unexpected body %v", d.body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback not received")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.WaitCallbacks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	entries := srv.Journal().Entries()
+	if len(entries) != 1 || len(entries[0].Callbacks) != 1 {
+		t.Fatalf("expected callback result in journal, got %+v", entries)
+	}
+	if cb := entries[0].Callbacks[0]; cb.Status != 200 || cb.Attempts != 1 || cb.Error != "" {
+		t.Fatalf("unexpected callback result %+v", cb)
+	}
+}
+
+func TestCallback_Retries(t *testing.T) {
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	srv := mock.NewServer()
+	srv.AddEndpoint(&mock.Endpoint{
+		Path:     "/orders",
+		Method:   "POST",
+		Response: mock.Response{StatusCode: 201},
+		Callbacks: []*mock.Callback{
+			{URL: receiver.URL, Body: "created", Retries: 3, RetryDelay: 5 * time.Millisecond},
+			{URL: receiver.URL + "/never", Method: "PUT", Retries: 1, RetryDelay: time.Millisecond, Timeout: time.Nanosecond},
+		},
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/orders", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.WaitCallbacks(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	results := map[string]mock.CallbackResult{}
+	for _, cb := range srv.Journal().Entries()[0].Callbacks {
+		results[cb.Method] = cb
+	}
+	if r := results["POST"]; r.Attempts != 3 || r.Status != 200 || r.Error != "" {
+		t.Fatalf("expected success on third attempt, got %+v", r)
+	}
+	if r := results["PUT"]; r.Attempts != 2 || r.Error == "" {
+		t.Fatalf("expected timed out callback to give up after 2 attempts, got %+v", r)
+	}
+}
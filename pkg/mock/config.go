@@ -0,0 +1,367 @@
+package mock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the file format used to describe a set of mock endpoints.
+type Config struct {
+	// Faults applies to every endpoint without its own profile.
+	Faults    *FaultProfile    `json:"faults,omitempty" yaml:"faults,omitempty"`
+	Scenarios []*Scenario      `json:"scenarios,omitempty" yaml:"scenarios,omitempty"`
+	Endpoints []EndpointConfig `json:"endpoints" yaml:"endpoints"`
+
+	baseDir string
+}
+
+type EndpointConfig struct {
+	Method   string         `json:"method,omitempty" yaml:"method,omitempty"`
+	Path     string         `json:"path" yaml:"path"`
+	Match    *MatchConfig   `json:"match,omitempty" yaml:"match,omitempty"`
+	Delay    time.Duration  `json:"delay,omitempty" yaml:"delay,omitempty"`
+	Faults   *FaultProfile  `json:"faults,omitempty" yaml:"faults,omitempty"`
+	Response ResponseConfig `json:"response,omitempty" yaml:"response,omitempty"`
+	// Sequence is served in order instead of Response, repeating the last.
+	Sequence []ResponseConfig `json:"sequence,omitempty" yaml:"sequence,omitempty"`
+	Resource *ResourceConfig  `json:"resource,omitempty" yaml:"resource,omitempty"`
+	// GraphQL serves the path as a GraphQL endpoint; Method defaults to POST.
+	GraphQL *GraphQLConfig `json:"graphql,omitempty" yaml:"graphql,omitempty"`
+	// Callbacks are fired after the endpoint responds.
+	Callbacks []*Callback `json:"callbacks,omitempty" yaml:"callbacks,omitempty"`
+
+	// Scenario binds the endpoint to a named state machine: it only matches
+	// in State (any state if empty) and moves the scenario to NewState.
+	Scenario string `json:"scenario,omitempty" yaml:"scenario,omitempty"`
+	State    string `json:"state,omitempty" yaml:"state,omitempty"`
+	NewState string `json:"newState,omitempty" yaml:"newState,omitempty"`
+}
+
+type MatchConfig struct {
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body    string            `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+type ResponseConfig struct {
+	Status  int               `json:"status,omitempty" yaml:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+type ResourceConfig struct {
+	Seed string `json:"seed,omitempty" yaml:"seed,omitempty"`
+	// Items is inline seed data, used when Seed is empty.
+	Items   []map[string]interface{} `json:"items,omitempty" yaml:"items,omitempty"`
+	IDField string                   `json:"idField,omitempty" yaml:"idField,omitempty"`
+	IDType  string                   `json:"idType,omitempty" yaml:"idType,omitempty"`
+}
+
+// LoadConfig reads a config file, or a directory of config files and
+// recordings merged in file name order.
+func LoadConfig(path string) (*Config, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return loadConfigDir(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg.baseDir = filepath.Dir(path)
+	return cfg, nil
+}
+
+// loadConfigDir merges the YAML files in dir. Recordings saved by a
+// Recorder are served as endpoints; see recordingEndpoints.
+func loadConfigDir(dir string) (*Config, error) {
+	files, err := recordingFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{baseDir: dir}
+	var recs []Recording
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		if rec, ok := parseRecording(data); ok {
+			recs = append(recs, *rec)
+			continue
+		}
+		part, err := ParseConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		if part.Faults != nil {
+			cfg.Faults = part.Faults
+		}
+		cfg.Scenarios = append(cfg.Scenarios, part.Scenarios...)
+		cfg.Endpoints = append(cfg.Endpoints, part.Endpoints...)
+	}
+	cfg.Endpoints = append(cfg.Endpoints, recordingEndpoints(recs)...)
+	return cfg, nil
+}
+
+// ParseConfig parses a config. A single recording is accepted too and
+// served as one endpoint.
+func ParseConfig(data []byte) (*Config, error) {
+	if rec, ok := parseRecording(data); ok {
+		return &Config{Endpoints: []EndpointConfig{rec.Endpoint()}}, nil
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Apply registers every endpoint and resource in the config on s.
+func (c *Config) Apply(s *Server) error {
+	if c.Faults != nil {
+		if err := c.Faults.Validate(); err != nil {
+			return fmt.Errorf("faults: %w", err)
+		}
+		s.SetFaults(c.Faults)
+	}
+
+	for i, sc := range c.Scenarios {
+		if sc.Name == "" {
+			return fmt.Errorf("scenario %d: missing name", i)
+		}
+		s.AddScenario(sc)
+	}
+
+	for i, ec := range c.Endpoints {
+		if ec.Path == "" {
+			return fmt.Errorf("endpoint %d: missing path", i)
+		}
+		ep, res, err := c.build(ec)
+		if err != nil {
+			return err
+		}
+		if res != nil {
+			s.AddResource(res)
+		} else {
+			s.AddEndpoint(ep)
+		}
+	}
+	return nil
+}
+
+// build turns one entry into either an endpoint or a resource.
+func (c *Config) build(ec EndpointConfig) (*Endpoint, *Resource, error) {
+	if ec.Path == "" {
+		return nil, nil, fmt.Errorf("missing path")
+	}
+	if ec.Faults != nil {
+		if err := ec.Faults.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("endpoint %s faults: %w", ec.Path, err)
+		}
+	}
+
+	if ec.Resource != nil {
+		res, err := c.buildResource(ec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("endpoint %s: %w", ec.Path, err)
+		}
+		return nil, res, nil
+	}
+	if ec.GraphQL != nil {
+		ep, err := c.buildGraphQL(ec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("endpoint %s: %w", ec.Path, err)
+		}
+		return ep, nil, nil
+	}
+
+	ep, err := ec.toEndpoint()
+	if err != nil {
+		return nil, nil, fmt.Errorf("endpoint %s %s: %w", ec.Method, ec.Path, err)
+	}
+	return ep, nil, nil
+}
+
+func (c *Config) resolvePath(p string) string {
+	if p == "" || filepath.IsAbs(p) || c.baseDir == "" {
+		return p
+	}
+	return filepath.Join(c.baseDir, p)
+}
+
+func (c *Config) buildResource(ec EndpointConfig) (*Resource, error) {
+	seed := ec.Resource.Items
+	if ec.Resource.Seed != "" {
+		var err error
+		seed, err = LoadSeed(c.resolvePath(ec.Resource.Seed))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := NewResource(ec.Path, seed)
+	res.Faults = ec.Faults
+	if ec.Resource.IDField != "" {
+		res.IDField = ec.Resource.IDField
+	}
+	switch ec.Resource.IDType {
+	case "", IDTypeInt:
+	case IDTypeUUID:
+		res.IDType = IDTypeUUID
+	default:
+		return nil, fmt.Errorf("unsupported id type: %s", ec.Resource.IDType)
+	}
+	res.Reset()
+
+	return res, nil
+}
+
+// buildGraphQL inlines the schema file so the endpoint can be exported
+// without its base directory.
+func (c *Config) buildGraphQL(ec EndpointConfig) (*Endpoint, error) {
+	gc := *ec.GraphQL
+	if gc.Schema != "" {
+		data, err := os.ReadFile(c.resolvePath(gc.Schema))
+		if err != nil {
+			return nil, fmt.Errorf("read schema: %w", err)
+		}
+		gc.SDL, gc.Schema = string(data), ""
+	}
+	handler, err := NewGraphQLHandler(&gc)
+	if err != nil {
+		return nil, err
+	}
+
+	if ec.Method == "" {
+		ec.Method = "POST"
+	}
+	ep, err := ec.toEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	ep.Handler, ep.GraphQL = handler, &gc
+	return ep, nil
+}
+
+func (ec EndpointConfig) toEndpoint() (*Endpoint, error) {
+	method := ec.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	ep := &Endpoint{
+		Path:     ec.Path,
+		Method:   method,
+		Delay:    ec.Delay,
+		Faults:   ec.Faults,
+		Response: ec.Response.toResponse(),
+	}
+	for _, cb := range ec.Callbacks {
+		if err := cb.validate(); err != nil {
+			return nil, err
+		}
+	}
+	ep.Callbacks = ec.Callbacks
+	for _, rc := range ec.Sequence {
+		ep.Sequence = append(ep.Sequence, rc.toResponse())
+	}
+	if ec.Scenario != "" {
+		ep.Scenario = &ScenarioBinding{Name: ec.Scenario, State: ec.State, NewState: ec.NewState}
+	} else if ec.State != "" || ec.NewState != "" {
+		return nil, fmt.Errorf("state and newState require a scenario")
+	}
+
+	if ec.Match != nil {
+		matcher := &Matcher{HeaderMatchers: make(map[string]*regexp.Regexp)}
+		for header, pattern := range ec.Match.Headers {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("header matcher %s: %w", header, err)
+			}
+			matcher.HeaderMatchers[header] = re
+		}
+		if ec.Match.Body != "" {
+			re, err := regexp.Compile(ec.Match.Body)
+			if err != nil {
+				return nil, fmt.Errorf("body matcher: %w", err)
+			}
+			matcher.BodyMatcher = re
+		}
+		ep.Matcher = matcher
+	}
+
+	return ep, nil
+}
+
+func (rc ResponseConfig) toResponse() Response {
+	status := rc.Status
+	if status == 0 {
+		status = 200
+	}
+	return Response{StatusCode: status, Headers: rc.Headers, Body: rc.Body}
+}
+
+func responseConfig(r Response) ResponseConfig {
+	return ResponseConfig{Status: r.StatusCode, Headers: r.Headers, Body: r.Body}
+}
+
+// config converts e back to its file representation.
+func (e *Endpoint) config() EndpointConfig {
+	ec := EndpointConfig{
+		Method:    e.Method,
+		Path:      e.Path,
+		Delay:     e.Delay,
+		Faults:    e.Faults,
+		Response:  responseConfig(e.Response),
+		Callbacks: e.Callbacks,
+		GraphQL:   e.GraphQL,
+	}
+	for _, r := range e.Sequence {
+		ec.Sequence = append(ec.Sequence, responseConfig(r))
+	}
+	if e.Scenario != nil {
+		ec.Scenario = e.Scenario.Name
+		ec.State = e.Scenario.State
+		ec.NewState = e.Scenario.NewState
+	}
+	if e.Matcher != nil {
+		match := &MatchConfig{}
+		if len(e.Matcher.HeaderMatchers) > 0 {
+			match.Headers = make(map[string]string, len(e.Matcher.HeaderMatchers))
+			for header, re := range e.Matcher.HeaderMatchers {
+				match.Headers[header] = re.String()
+			}
+		}
+		if e.Matcher.BodyMatcher != nil {
+			match.Body = e.Matcher.BodyMatcher.String()
+		}
+		ec.Match = match
+	}
+	return ec
+}
+
+// config converts res back to its file representation with the current
+// items inlined as seed data.
+func (res *Resource) config() EndpointConfig {
+	return EndpointConfig{
+		Path:   res.Path,
+		Faults: res.Faults,
+		Resource: &ResourceConfig{
+			Items:   res.Items(),
+			IDField: res.IDField,
+			IDType:  res.IDType,
+		},
+	}
+}
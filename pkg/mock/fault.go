@@ -0,0 +1,359 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FaultProfile describes failures injected into mock responses. A profile
+// can be attached to a single endpoint or installed server-wide.
+type FaultProfile struct {
+	Latency *Latency     `json:"latency,omitempty" yaml:"latency,omitempty"`
+	Errors  []ErrorFault `json:"errors,omitempty" yaml:"errors,omitempty"`
+	// ResetProbability drops the connection without a response.
+	ResetProbability float64 `json:"resetProbability,omitempty" yaml:"resetProbability,omitempty"`
+	// TruncateProbability sends only the first TruncateAt fraction of the
+	// body (default half) before dropping the connection.
+	TruncateProbability float64    `json:"truncateProbability,omitempty" yaml:"truncateProbability,omitempty"`
+	TruncateAt          float64    `json:"truncateAt,omitempty" yaml:"truncateAt,omitempty"`
+	Trickle             *Trickle   `json:"trickle,omitempty" yaml:"trickle,omitempty"`
+	RateLimit           *RateLimit `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+
+	limiterOnce sync.Once
+	limiter     *tokenBucket
+}
+
+const (
+	LatencyFixed       = "fixed"
+	LatencyUniform     = "uniform"
+	LatencyNormal      = "normal"
+	LatencyPercentiles = "percentiles"
+)
+
+// Latency is a response time distribution.
+type Latency struct {
+	Distribution string        `json:"distribution,omitempty" yaml:"distribution,omitempty"`
+	Fixed        time.Duration `json:"fixed,omitempty" yaml:"fixed,omitempty"`
+	Min          time.Duration `json:"min,omitempty" yaml:"min,omitempty"`
+	Max          time.Duration `json:"max,omitempty" yaml:"max,omitempty"`
+	Mean         time.Duration `json:"mean,omitempty" yaml:"mean,omitempty"`
+	StdDev       time.Duration `json:"stddev,omitempty" yaml:"stddev,omitempty"`
+	// Percentiles maps keys such as "p50" or "p99.9" to the latency at that
+	// percentile; samples are interpolated between the given points.
+	Percentiles map[string]time.Duration `json:"percentiles,omitempty" yaml:"percentiles,omitempty"`
+}
+
+type ErrorFault struct {
+	Status      int         `json:"status" yaml:"status"`
+	Probability float64     `json:"probability" yaml:"probability"`
+	Body        interface{} `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Trickle writes the body ChunkSize bytes at a time, pausing Interval
+// between chunks.
+type Trickle struct {
+	ChunkSize int           `json:"chunkSize,omitempty" yaml:"chunkSize,omitempty"`
+	Interval  time.Duration `json:"interval" yaml:"interval"`
+}
+
+// RateLimit allows RPS requests per second with bursts up to Burst and
+// answers the rest with 429 Too Many Requests.
+type RateLimit struct {
+	RPS   float64 `json:"rps" yaml:"rps"`
+	Burst int     `json:"burst,omitempty" yaml:"burst,omitempty"`
+}
+
+// Validate reports configuration errors such as probabilities outside [0,1].
+func (f *FaultProfile) Validate() error {
+	probs := map[string]float64{
+		"resetProbability":    f.ResetProbability,
+		"truncateProbability": f.TruncateProbability,
+	}
+	total := 0.0
+	for i, e := range f.Errors {
+		probs[fmt.Sprintf("errors[%d].probability", i)] = e.Probability
+		total += e.Probability
+		if e.Status < 100 || e.Status > 599 {
+			return fmt.Errorf("errors[%d]: invalid status %d", i, e.Status)
+		}
+	}
+	for name, p := range probs {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if total > 1 {
+		return fmt.Errorf("error probabilities sum to %.2f, must be <= 1", total)
+	}
+	if f.TruncateAt < 0 || f.TruncateAt > 1 {
+		return fmt.Errorf("truncateAt must be between 0 and 1")
+	}
+	if f.RateLimit != nil && f.RateLimit.RPS <= 0 {
+		return fmt.Errorf("rateLimit.rps must be positive")
+	}
+	if f.Latency != nil {
+		if _, err := f.Latency.points(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sample draws one latency from the distribution.
+func (l *Latency) Sample() time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	switch l.Distribution {
+	case LatencyUniform:
+		if l.Max <= l.Min {
+			return l.Min
+		}
+		return l.Min + time.Duration(rand.Int64N(int64(l.Max-l.Min)))
+	case LatencyNormal:
+		d := time.Duration(float64(l.Mean) + rand.NormFloat64()*float64(l.StdDev))
+		if d < 0 {
+			return 0
+		}
+		return d
+	case LatencyPercentiles:
+		points, err := l.points()
+		if err != nil || len(points) == 0 {
+			return 0
+		}
+		return interpolate(points, rand.Float64()*100)
+	default:
+		return l.Fixed
+	}
+}
+
+type percentilePoint struct {
+	p float64
+	d time.Duration
+}
+
+func (l *Latency) points() ([]percentilePoint, error) {
+	points := []percentilePoint{{p: 0, d: l.Min}}
+	for key, d := range l.Percentiles {
+		p, err := strconv.ParseFloat(strings.TrimPrefix(strings.ToLower(key), "p"), 64)
+		if err != nil || p <= 0 || p > 100 {
+			return nil, fmt.Errorf("invalid latency percentile %q", key)
+		}
+		points = append(points, percentilePoint{p: p, d: d})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].p < points[j].p })
+	for i := 1; i < len(points); i++ {
+		if points[i].d < points[i-1].d {
+			return nil, fmt.Errorf("latency percentiles must not decrease (p%g)", points[i].p)
+		}
+	}
+	return points, nil
+}
+
+func interpolate(points []percentilePoint, p float64) time.Duration {
+	for i := 1; i < len(points); i++ {
+		lo, hi := points[i-1], points[i]
+		if p <= hi.p {
+			frac := (p - lo.p) / (hi.p - lo.p)
+			return lo.d + time.Duration(frac*float64(hi.d-lo.d))
+		}
+	}
+	return points[len(points)-1].d
+}
+
+func (f *FaultProfile) allow() (bool, time.Duration) {
+	if f == nil || f.RateLimit == nil {
+		return true, 0
+	}
+	f.limiterOnce.Do(func() {
+		f.limiter = newTokenBucket(f.RateLimit.RPS, f.RateLimit.Burst)
+	})
+	return f.limiter.take()
+}
+
+func (f *FaultProfile) delay() time.Duration {
+	if f == nil {
+		return 0
+	}
+	return f.Latency.Sample()
+}
+
+// pickError returns the injected error for this request, if any.
+func (f *FaultProfile) pickError() *ErrorFault {
+	if f == nil || len(f.Errors) == 0 {
+		return nil
+	}
+	roll := rand.Float64()
+	for i := range f.Errors {
+		if roll < f.Errors[i].Probability {
+			return &f.Errors[i]
+		}
+		roll -= f.Errors[i].Probability
+	}
+	return nil
+}
+
+func chance(p float64) bool {
+	return p > 0 && rand.Float64() < p
+}
+
+// write sends resp to the client, applying connection-level faults, and
+// returns the status sent or 0 if the connection was reset.
+func (f *FaultProfile) write(w http.ResponseWriter, r *http.Request, resp *bufferedResponse) int {
+	if f == nil {
+		return resp.writeTo(w)
+	}
+
+	if chance(f.ResetProbability) {
+		resetConnection(w)
+		return 0
+	}
+
+	if e := f.pickError(); e != nil {
+		errResp := newBufferedResponse()
+		writeBody(errResp, e.Status, nil, e.Body)
+		resp = errResp
+	}
+
+	body := resp.body.Bytes()
+	truncate := chance(f.TruncateProbability)
+
+	for k, vs := range resp.header {
+		w.Header()[k] = vs
+	}
+	if resp.status == 0 {
+		resp.status = http.StatusOK
+	}
+	if truncate {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		at := f.TruncateAt
+		if at == 0 {
+			at = 0.5
+		}
+		body = body[:int(math.Floor(float64(len(body))*at))]
+	}
+	w.WriteHeader(resp.status)
+
+	if f.Trickle != nil && f.Trickle.Interval > 0 {
+		if !trickle(r.Context(), w, body, f.Trickle) {
+			return resp.status
+		}
+	} else {
+		w.Write(body)
+	}
+
+	if truncate {
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		resetConnection(w)
+	}
+	return resp.status
+}
+
+func trickle(ctx context.Context, w http.ResponseWriter, body []byte, t *Trickle) bool {
+	size := t.ChunkSize
+	if size <= 0 {
+		size = 1
+	}
+	flusher, _ := w.(http.Flusher)
+
+	for len(body) > 0 {
+		n := size
+		if n > len(body) {
+			n = len(body)
+		}
+		if _, err := w.Write(body[:n]); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		body = body[n:]
+		if len(body) > 0 && !sleepContext(ctx, t.Interval) {
+			return false
+		}
+	}
+	return true
+}
+
+// resetConnection aborts the exchange. On HTTP/1 the socket is closed with
+// SO_LINGER 0 so the client sees a TCP reset; HTTP/2 streams are reset by
+// aborting the handler.
+func resetConnection(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// sleepContext waits for d or until ctx is done, reporting whether the full
+// duration elapsed.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rps float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rps)))
+	}
+	return &tokenBucket{rate: rps, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// take consumes a token, or reports how long until one is available.
+func (b *tokenBucket) take() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+func writeRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+}
@@ -0,0 +1,132 @@
+package mock_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+)
+
+func TestFaults_DelayDoesNotBlock(t *testing.T) {
+	srv := mock.NewServer()
+	srv.AddEndpoint(&mock.Endpoint{
+		Path:     "/slow",
+		Method:   "GET",
+		Response: mock.Response{StatusCode: 200, Body: "slow"},
+		Faults:   &mock.FaultProfile{Latency: &mock.Latency{Fixed: 500 * time.Millisecond}},
+	})
+	srv.AddEndpoint(&mock.Endpoint{Path: "/fast", Method: "GET", Response: mock.Response{StatusCode: 200}})
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if res, err := http.Get(ts.URL + "/slow"); err == nil {
+			res.Body.Close()
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	srv.AddEndpoint(&mock.Endpoint{Path: "/new", Method: "GET", Response: mock.Response{StatusCode: 201}})
+	res, err := http.Get(ts.URL + "/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond || res.StatusCode != 201 {
+		t.Fatalf("update and request took %v (status %d) while a delayed request was in flight", elapsed, res.StatusCode)
+	}
+	<-done
+}
+
+func TestFaults_Injection(t *testing.T) {
+	srv := mock.NewServer()
+	add := func(path string, f *mock.FaultProfile) {
+		srv.AddEndpoint(&mock.Endpoint{
+			Path:     path,
+			Method:   "GET",
+			Response: mock.Response{StatusCode: 200, Body: "0123456789abcdefghij"},
+			Faults:   f,
+		})
+	}
+	add("/error", &mock.FaultProfile{Errors: []mock.ErrorFault{{Status: 503, Probability: 1}}})
+	add("/limited", &mock.FaultProfile{RateLimit: &mock.RateLimit{RPS: 0.5, Burst: 1}})
+	add("/reset", &mock.FaultProfile{ResetProbability: 1})
+	add("/truncate", &mock.FaultProfile{TruncateProbability: 1})
+	add("/trickle", &mock.FaultProfile{Trickle: &mock.Trickle{ChunkSize: 5, Interval: 20 * time.Millisecond}})
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	get := func(path string) (*http.Response, []byte, error) {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return res, body, err
+	}
+
+	if res, _, err := get("/error"); err != nil || res.StatusCode != 503 {
+		t.Fatalf("error fault: %v %v", res, err)
+	}
+
+	if res, _, _ := get("/limited"); res.StatusCode != 200 {
+		t.Fatalf("first request within burst should pass, got %d", res.StatusCode)
+	}
+	if res, _, _ := get("/limited"); res.StatusCode != 429 || res.Header.Get("Retry-After") == "" {
+		t.Fatalf("second request should be rate limited, got %d", res.StatusCode)
+	}
+
+	if _, _, err := get("/reset"); err == nil {
+		t.Fatal("expected connection reset error")
+	}
+
+	if _, body, err := get("/truncate"); err == nil || len(body) != 10 {
+		t.Fatalf("expected truncated body and read error, got %q %v", body, err)
+	}
+
+	start := time.Now()
+	if _, body, err := get("/trickle"); err != nil || string(body) != "0123456789abcdefghij" {
+		t.Fatalf("trickle: %q %v", body, err)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Fatalf("trickle finished too quickly: %v", elapsed)
+	}
+}
+
+func TestLatency_Percentiles(t *testing.T) {
+	l := &mock.Latency{
+		Distribution: mock.LatencyPercentiles,
+		Min:          10 * time.Millisecond,
+		Percentiles: map[string]time.Duration{
+			"p50": 20 * time.Millisecond,
+			"p99": 200 * time.Millisecond,
+			"100": 500 * time.Millisecond,
+		},
+	}
+	if err := (&mock.FaultProfile{Latency: l}).Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	below := 0
+	for i := 0; i < 2000; i++ {
+		d := l.Sample()
+		if d < 10*time.Millisecond || d > 500*time.Millisecond {
+			t.Fatalf("sample %v outside [10ms, 500ms]", d)
+		}
+		if d <= 20*time.Millisecond {
+			below++
+		}
+	}
+	if below < 800 || below > 1200 {
+		t.Fatalf("expected about half of samples at or below p50, got %d/2000", below)
+	}
+}
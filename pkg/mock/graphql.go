@@ -0,0 +1,800 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultGraphQLListLength = 2
+
+// GraphQLConfig describes a GraphQL endpoint. Queries are answered with data
+// generated from the schema's field types unless overridden.
+type GraphQLConfig struct {
+	// Schema is an SDL file; SDL holds the schema inline.
+	Schema string `json:"schema,omitempty" yaml:"schema,omitempty"`
+	SDL    string `json:"sdl,omitempty" yaml:"sdl,omitempty"`
+	// ListLength is the number of items in generated lists, unless the field
+	// takes a first, last or limit argument.
+	ListLength int `json:"listLength,omitempty" yaml:"listLength,omitempty"`
+	// Overrides maps "Type.field" to the value returned for that field.
+	// Objects are merged with generated data, so only the given fields change.
+	Overrides map[string]interface{} `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+	// Operations maps an operation name to a complete response served as is.
+	Operations map[string]interface{} `json:"operations,omitempty" yaml:"operations,omitempty"`
+}
+
+// GraphQLSchema is a parsed SDL schema, including the introspection types.
+type GraphQLSchema struct {
+	types      map[string]*gqlType
+	order      []string
+	directives []*gqlDirectiveDef
+
+	query, mutation, subscription string
+}
+
+func LoadGraphQLSchema(path string) (*GraphQLSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return ParseGraphQLSchema(string(data))
+}
+
+func ParseGraphQLSchema(sdl string) (*GraphQLSchema, error) {
+	s := &GraphQLSchema{types: make(map[string]*gqlType)}
+	if err := s.parseSDL(introspectionSDL); err != nil {
+		return nil, fmt.Errorf("introspection schema: %w", err)
+	}
+	if err := s.parseSDL(sdl); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+
+	roots := []*string{&s.query, &s.mutation, &s.subscription}
+	for i, name := range []string{"Query", "Mutation", "Subscription"} {
+		if *roots[i] == "" && s.types[name] != nil {
+			*roots[i] = name
+		}
+	}
+	if err := s.check(); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return s, nil
+}
+
+func (s *GraphQLSchema) addType(t *gqlType, extend bool) error {
+	existing := s.types[t.name]
+	if !extend {
+		if existing != nil {
+			return fmt.Errorf("type %s is defined more than once", t.name)
+		}
+		s.types[t.name] = t
+		s.order = append(s.order, t.name)
+		return nil
+	}
+
+	if existing == nil {
+		return fmt.Errorf("cannot extend unknown type %s", t.name)
+	}
+	if existing.kind != t.kind {
+		return fmt.Errorf("cannot extend %s %s with a different kind", strings.ToLower(existing.kind), t.name)
+	}
+	existing.fields = append(existing.fields, t.fields...)
+	existing.interfaces = append(existing.interfaces, t.interfaces...)
+	existing.members = append(existing.members, t.members...)
+	existing.enumValues = append(existing.enumValues, t.enumValues...)
+	existing.inputFields = append(existing.inputFields, t.inputFields...)
+	return nil
+}
+
+func (s *GraphQLSchema) addDirective(d *gqlDirectiveDef) {
+	for i, existing := range s.directives {
+		if existing.name == d.name {
+			s.directives[i] = d
+			return
+		}
+	}
+	s.directives = append(s.directives, d)
+}
+
+// check verifies that every referenced type exists and has the right kind.
+func (s *GraphQLSchema) check() error {
+	if s.query == "" {
+		return fmt.Errorf("missing Query type")
+	}
+	for _, root := range []string{s.query, s.mutation, s.subscription} {
+		if t := s.types[root]; root != "" && (t == nil || t.kind != "OBJECT") {
+			return fmt.Errorf("root type %s must be an object type", root)
+		}
+	}
+
+	input := func(ref *gqlTypeRef, where string) error {
+		t := s.types[ref.named()]
+		switch {
+		case t == nil:
+			return fmt.Errorf("unknown type %s in %s", ref.named(), where)
+		case t.isComposite():
+			return fmt.Errorf("%s must be an input type, got %s", where, t.name)
+		}
+		return nil
+	}
+
+	for _, name := range s.order {
+		t := s.types[name]
+		for _, f := range t.fields {
+			where := name + "." + f.name
+			ft := s.types[f.typ.named()]
+			if ft == nil {
+				return fmt.Errorf("unknown type %s in %s", f.typ.named(), where)
+			}
+			if ft.kind == "INPUT_OBJECT" {
+				return fmt.Errorf("%s must be an output type, got %s", where, ft.name)
+			}
+			for _, arg := range f.args {
+				if err := input(arg.typ, where+"("+arg.name+")"); err != nil {
+					return err
+				}
+			}
+		}
+		for _, f := range t.inputFields {
+			if err := input(f.typ, name+"."+f.name); err != nil {
+				return err
+			}
+		}
+		for _, iface := range t.interfaces {
+			if it := s.types[iface]; it == nil || it.kind != "INTERFACE" {
+				return fmt.Errorf("%s implements %s, which is not an interface", name, iface)
+			}
+		}
+		for _, member := range t.members {
+			if mt := s.types[member]; mt == nil || mt.kind != "OBJECT" {
+				return fmt.Errorf("union %s member %s is not an object type", name, member)
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	typenameField = &gqlField{name: "__typename", typ: &gqlTypeRef{Kind: "NON_NULL", OfType: &gqlTypeRef{Name: "String"}}}
+	schemaField   = &gqlField{name: "__schema", typ: &gqlTypeRef{Kind: "NON_NULL", OfType: &gqlTypeRef{Name: "__Schema"}}}
+	typeField     = &gqlField{
+		name: "__type",
+		args: []*gqlInputValue{{name: "name", typ: &gqlTypeRef{Kind: "NON_NULL", OfType: &gqlTypeRef{Name: "String"}}}},
+		typ:  &gqlTypeRef{Name: "__Type"},
+	}
+)
+
+// fieldDef looks up a field on t, including the meta fields.
+func (s *GraphQLSchema) fieldDef(t *gqlType, name string) *gqlField {
+	switch {
+	case name == "__typename":
+		return typenameField
+	case name == "__schema" && t.name == s.query:
+		return schemaField
+	case name == "__type" && t.name == s.query:
+		return typeField
+	case t.kind == "UNION":
+		return nil
+	}
+	return t.field(name)
+}
+
+// possibleTypes lists the object types t may resolve to.
+func (s *GraphQLSchema) possibleTypes(t *gqlType) []*gqlType {
+	var out []*gqlType
+	switch t.kind {
+	case "OBJECT":
+		out = append(out, t)
+	case "UNION":
+		for _, name := range t.members {
+			out = append(out, s.types[name])
+		}
+	case "INTERFACE":
+		for _, name := range s.order {
+			if obj := s.types[name]; obj.kind == "OBJECT" && contains(obj.interfaces, t.name) {
+				out = append(out, obj)
+			}
+		}
+	}
+	return out
+}
+
+// applies reports whether a fragment on condition applies to object type t.
+func (s *GraphQLSchema) applies(t *gqlType, condition string) bool {
+	if condition == "" || condition == t.name || contains(t.interfaces, condition) {
+		return true
+	}
+	ct := s.types[condition]
+	return ct != nil && ct.kind == "UNION" && contains(ct.members, t.name)
+}
+
+func (s *GraphQLSchema) rootType(op *gqlOperation) *gqlType {
+	switch op.kind {
+	case "mutation":
+		return s.types[s.mutation]
+	case "subscription":
+		return s.types[s.subscription]
+	default:
+		return s.types[s.query]
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+type graphqlHandler struct {
+	schema     *GraphQLSchema
+	listLength int
+	overrides  map[string]interface{}
+	operations map[string]interface{}
+}
+
+// NewGraphQLHandler serves queries against the schema in cfg over HTTP:
+// POST with a JSON {query, operationName, variables} body or an
+// application/graphql body, and GET with the same fields as query params.
+func NewGraphQLHandler(cfg *GraphQLConfig) (http.Handler, error) {
+	sdl := cfg.SDL
+	if sdl == "" {
+		if cfg.Schema == "" {
+			return nil, fmt.Errorf("graphql endpoint needs a schema or sdl")
+		}
+		data, err := os.ReadFile(cfg.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("read schema: %w", err)
+		}
+		sdl = string(data)
+	}
+	schema, err := ParseGraphQLSchema(sdl)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &graphqlHandler{
+		schema:     schema,
+		listLength: cfg.ListLength,
+		operations: make(map[string]interface{}, len(cfg.Operations)),
+		overrides:  make(map[string]interface{}, len(cfg.Overrides)),
+	}
+	if h.listLength <= 0 {
+		h.listLength = defaultGraphQLListLength
+	}
+	for key, v := range cfg.Overrides {
+		typeName, field, ok := strings.Cut(key, ".")
+		t := schema.types[typeName]
+		if !ok || t == nil || t.field(field) == nil {
+			return nil, fmt.Errorf("override %s: no such field", key)
+		}
+		h.overrides[key] = normalizeYAML(v)
+	}
+	for name, v := range cfg.Operations {
+		h.operations[name] = normalizeYAML(v)
+	}
+	return h, nil
+}
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (h *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQLErrors(w, http.StatusBadRequest, &GraphQLError{Message: "Variables are invalid JSON."})
+				return
+			}
+		}
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeGraphQLErrors(w, http.StatusBadRequest, &GraphQLError{Message: "Failed to read request body."})
+			return
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/graphql" {
+			req.Query = string(body)
+		} else if err := json.Unmarshal(body, &req); err != nil {
+			writeGraphQLErrors(w, http.StatusBadRequest, &GraphQLError{Message: "POST body must be a JSON object."})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeGraphQLErrors(w, http.StatusMethodNotAllowed, &GraphQLError{Message: "GraphQL only supports GET and POST requests."})
+		return
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		writeGraphQLErrors(w, http.StatusBadRequest, &GraphQLError{Message: "Must provide query string."})
+		return
+	}
+	status, resp := h.execute(r.Method, req)
+	writeJSON(w, status, resp)
+}
+
+func writeGraphQLErrors(w http.ResponseWriter, status int, errs ...*GraphQLError) {
+	writeJSON(w, status, map[string]interface{}{"errors": errs})
+}
+
+// execute runs one request and returns the HTTP status and response body.
+// Syntax and validation errors are request errors with no data.
+func (h *graphqlHandler) execute(method string, req graphqlRequest) (int, interface{}) {
+	doc, err := parseGraphQLQuery(req.Query)
+	if err != nil {
+		gqlErr, ok := err.(*GraphQLError)
+		if !ok {
+			gqlErr = &GraphQLError{Message: err.Error()}
+		}
+		return http.StatusBadRequest, map[string]interface{}{"errors": []*GraphQLError{gqlErr}}
+	}
+
+	op, errs := h.schema.validate(req.Query, doc, req.OperationName)
+	if len(errs) > 0 {
+		return http.StatusBadRequest, map[string]interface{}{"errors": errs}
+	}
+	if op.kind == "mutation" && method == http.MethodGet {
+		return http.StatusMethodNotAllowed, map[string]interface{}{"errors": []*GraphQLError{{
+			Message: "Can only perform a mutation operation from a POST request.",
+		}}}
+	}
+	if resp, ok := h.operations[op.name]; ok && op.name != "" {
+		return http.StatusOK, resp
+	}
+
+	vars, errs := h.schema.coerceVariables(req.Query, op, req.Variables)
+	if len(errs) > 0 {
+		return http.StatusBadRequest, map[string]interface{}{"errors": errs}
+	}
+
+	e := &gqlExecutor{h: h, schema: h.schema, doc: doc, src: req.Query, vars: vars}
+	resp := map[string]interface{}{"data": e.selectionSet(h.schema.rootType(op), op.selections, nil, nil, 1)}
+	if len(e.errors) > 0 {
+		resp["errors"] = e.errors
+	}
+	return http.StatusOK, resp
+}
+
+// gqlObject is a response object that keeps fields in selection order.
+type gqlObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *gqlObject) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+func (o *gqlObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+type gqlExecutor struct {
+	h      *graphqlHandler
+	schema *GraphQLSchema
+	doc    *gqlDocument
+	src    string
+	vars   map[string]interface{}
+	errors []*GraphQLError
+}
+
+// gqlFieldGroup is every selection sharing one response key.
+type gqlFieldGroup struct {
+	key    string
+	fields []*gqlSelection
+}
+
+// gqlFieldCtx is the field being completed.
+type gqlFieldCtx struct {
+	field *gqlField
+	args  map[string]interface{}
+	sels  []*gqlSelection
+	pos   int
+}
+
+func (e *gqlExecutor) collectFields(t *gqlType, sels []*gqlSelection, groups []*gqlFieldGroup, visited map[string]bool) []*gqlFieldGroup {
+	for _, sel := range sels {
+		if !e.included(sel.directives) {
+			continue
+		}
+		switch sel.kind {
+		case "field":
+			key := sel.responseKey()
+			found := false
+			for _, g := range groups {
+				if g.key == key {
+					g.fields = append(g.fields, sel)
+					found = true
+					break
+				}
+			}
+			if !found {
+				groups = append(groups, &gqlFieldGroup{key: key, fields: []*gqlSelection{sel}})
+			}
+		case "spread":
+			frag := e.doc.fragments[sel.name]
+			if visited[sel.name] || frag == nil || !e.schema.applies(t, frag.typeCondition) {
+				continue
+			}
+			visited[sel.name] = true
+			groups = e.collectFields(t, frag.selections, groups, visited)
+		case "inline":
+			if e.schema.applies(t, sel.typeCondition) {
+				groups = e.collectFields(t, sel.selections, groups, visited)
+			}
+		}
+	}
+	return groups
+}
+
+// included evaluates @skip and @include.
+func (e *gqlExecutor) included(dirs []*gqlDirective) bool {
+	for _, d := range dirs {
+		if d.name != "skip" && d.name != "include" {
+			continue
+		}
+		cond := false
+		for _, arg := range d.args {
+			if arg.name == "if" {
+				cond, _ = gqlLiteral(arg.value, e.vars).(bool)
+			}
+		}
+		if cond == (d.name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *gqlExecutor) selectionSet(t *gqlType, sels []*gqlSelection, src map[string]interface{}, path []interface{}, index int) *gqlObject {
+	out := &gqlObject{values: make(map[string]interface{})}
+	for _, g := range e.collectFields(t, sels, nil, make(map[string]bool)) {
+		sel := g.fields[0]
+		if sel.name == "__typename" {
+			out.set(g.key, t.name)
+			continue
+		}
+		def := e.schema.fieldDef(t, sel.name)
+		if def == nil {
+			continue
+		}
+
+		fc := &gqlFieldCtx{field: def, args: e.arguments(def.args, sel.args), pos: sel.pos}
+		for _, f := range g.fields {
+			fc.sels = append(fc.sels, f.selections...)
+		}
+		v, found := e.resolve(t, fc, src)
+		out.set(g.key, e.complete(fc, def.typ, v, found, append(path[:len(path):len(path)], g.key), index))
+	}
+	return out
+}
+
+func (e *gqlExecutor) arguments(defs []*gqlInputValue, given []*gqlArgument) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, d := range defs {
+		if d.defValue != nil {
+			out[d.name] = gqlLiteral(d.defValue, nil)
+		}
+	}
+	for _, arg := range given {
+		if arg.value.kind == "Variable" {
+			if v, ok := e.vars[arg.value.raw]; ok {
+				out[arg.name] = v
+			}
+			continue
+		}
+		out[arg.name] = gqlLiteral(arg.value, e.vars)
+	}
+	return out
+}
+
+// resolve finds the value of a field in its parent's source data or the
+// configured overrides. found is false when the value should be generated.
+func (e *gqlExecutor) resolve(t *gqlType, fc *gqlFieldCtx, src map[string]interface{}) (interface{}, bool) {
+	switch fc.field {
+	case schemaField:
+		return e.schema.schemaSource(), true
+	case typeField:
+		name, _ := fc.args["name"].(string)
+		if e.schema.types[name] == nil {
+			return nil, true
+		}
+		return e.schema.typeSource(name), true
+	}
+
+	if v, ok := src[fc.field.name]; ok {
+		if lazy, isLazy := v.(func() interface{}); isLazy {
+			v = lazy()
+		}
+		if strings.HasPrefix(t.name, "__") {
+			v = filterDeprecated(v, fc.args)
+		}
+		return v, true
+	}
+	if v, ok := e.h.overrides[t.name+"."+fc.field.name]; ok {
+		return v, true
+	}
+	return nil, false
+}
+
+func (e *gqlExecutor) complete(fc *gqlFieldCtx, ref *gqlTypeRef, v interface{}, found bool, path []interface{}, index int) interface{} {
+	switch ref.Kind {
+	case "NON_NULL":
+		out := e.complete(fc, ref.OfType, v, found, path, index)
+		if out == nil {
+			e.fail(fc, path, "Cannot return null for non-nullable field %s.", fc.field.name)
+		}
+		return out
+	case "LIST":
+		if found && v == nil {
+			return nil
+		}
+		var items []interface{}
+		if !found {
+			items = make([]interface{}, e.listLength(fc.args))
+		} else if list, ok := v.([]interface{}); ok {
+			items = list
+		} else {
+			items = []interface{}{v}
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			// Generated items are numbered uniquely across parent lists.
+			out[i] = e.complete(fc, ref.OfType, item, found, append(path[:len(path):len(path)], i), (index-1)*len(items)+i+1)
+		}
+		return out
+	}
+
+	t := e.schema.types[ref.Name]
+	if t.isLeaf() {
+		if found {
+			return v
+		}
+		return generateScalar(t, fc.field.name, index)
+	}
+	if found && v == nil {
+		return nil
+	}
+	src, _ := v.(map[string]interface{})
+	if !found {
+		src = e.seed(t, fc.args)
+	}
+	concrete := e.concreteType(t, src)
+	if concrete == nil {
+		e.fail(fc, path, "Abstract type %s has no possible types.", t.name)
+		return nil
+	}
+	return e.selectionSet(concrete, fc.sels, src, path, index)
+}
+
+func (e *gqlExecutor) concreteType(t *gqlType, src map[string]interface{}) *gqlType {
+	if name, ok := src["__typename"].(string); ok {
+		if ct := e.schema.types[name]; ct != nil && ct.kind == "OBJECT" && e.schema.applies(ct, t.name) {
+			return ct
+		}
+	}
+	if possible := e.schema.possibleTypes(t); len(possible) > 0 {
+		return possible[0]
+	}
+	return nil
+}
+
+// seed copies arguments that name a scalar field of t into the generated
+// object, so user(id: "7") returns a user whose id is "7". Values are
+// coerced to the field's type, so user(id: 7) does too.
+func (e *gqlExecutor) seed(t *gqlType, args map[string]interface{}) map[string]interface{} {
+	var src map[string]interface{}
+	for name, v := range args {
+		f := t.field(name)
+		if f == nil || v == nil {
+			continue
+		}
+		ref := f.typ
+		if ref.Kind == "NON_NULL" {
+			ref = ref.OfType
+		}
+		if ref.Kind != "" || !e.schema.types[ref.Name].isLeaf() {
+			continue
+		}
+		if src == nil {
+			src = make(map[string]interface{})
+		}
+		src[name] = coerceScalar(ref.Name, v)
+	}
+	return src
+}
+
+// coerceScalar converts an argument value to the result type of a built-in
+// scalar. Values that do not convert are returned as they are.
+func coerceScalar(typ string, v interface{}) interface{} {
+	switch typ {
+	case "ID", "String":
+		switch n := v.(type) {
+		case int64:
+			return strconv.FormatInt(n, 10)
+		case float64:
+			return strconv.FormatFloat(n, 'f', -1, 64)
+		}
+	case "Int":
+		switch n := v.(type) {
+		case float64:
+			if n == math.Trunc(n) {
+				return int64(n)
+			}
+		case string:
+			if i, err := strconv.ParseInt(n, 10, 64); err == nil {
+				return i
+			}
+		}
+	case "Float":
+		switch n := v.(type) {
+		case int64:
+			return float64(n)
+		case string:
+			if f, err := strconv.ParseFloat(n, 64); err == nil {
+				return f
+			}
+		}
+	}
+	return v
+}
+
+var listLengthArgs = []string{"first", "last", "limit", "count", "size", "take", "pageSize"}
+
+func (e *gqlExecutor) listLength(args map[string]interface{}) int {
+	for _, name := range listLengthArgs {
+		var n int
+		switch v := args[name].(type) {
+		case int64:
+			n = int(v)
+		case float64:
+			n = int(v)
+		default:
+			continue
+		}
+		return max(0, min(n, 100))
+	}
+	return e.h.listLength
+}
+
+func (e *gqlExecutor) fail(fc *gqlFieldCtx, path []interface{}, format string, args ...interface{}) {
+	err := gqlErrorAt(e.src, fc.pos, format, args...)
+	err.Path = path
+	e.errors = append(e.errors, err)
+}
+
+func filterDeprecated(v interface{}, args map[string]interface{}) interface{} {
+	list, ok := v.([]interface{})
+	if include, _ := args["includeDeprecated"].(bool); !ok || include {
+		return v
+	}
+	out := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		if m, isMap := item.(map[string]interface{}); isMap && m["isDeprecated"] == true {
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// gqlLiteral converts a literal to its JSON value.
+func gqlLiteral(v *gqlValue, vars map[string]interface{}) interface{} {
+	switch v.kind {
+	case "Variable":
+		return vars[v.raw]
+	case "Int":
+		if n, err := strconv.ParseInt(v.raw, 10, 64); err == nil {
+			return n
+		}
+		f, _ := strconv.ParseFloat(v.raw, 64)
+		return f
+	case "Float":
+		f, _ := strconv.ParseFloat(v.raw, 64)
+		return f
+	case "Boolean":
+		return v.raw == "true"
+	case "Null":
+		return nil
+	case "List":
+		out := make([]interface{}, len(v.list))
+		for i, item := range v.list {
+			out[i] = gqlLiteral(item, vars)
+		}
+		return out
+	case "Object":
+		out := make(map[string]interface{}, len(v.fields))
+		for _, f := range v.fields {
+			out[f.name] = gqlLiteral(f.value, vars)
+		}
+		return out
+	default:
+		return v.raw
+	}
+}
+
+// generateScalar returns a deterministic value for a leaf field, guessed
+// from the field and type names. index numbers items in generated lists.
+func generateScalar(t *gqlType, field string, index int) interface{} {
+	if t.kind == "ENUM" {
+		if len(t.enumValues) == 0 {
+			return nil
+		}
+		return t.enumValues[(index-1)%len(t.enumValues)].name
+	}
+
+	switch t.name {
+	case "ID":
+		return strconv.Itoa(index)
+	case "Int":
+		return index
+	case "Float":
+		return float64(index) + 0.5
+	case "Boolean":
+		return index%2 == 1
+	case "String":
+		return generateString(field, index)
+	}
+
+	name := strings.ToLower(t.name)
+	switch {
+	case strings.Contains(name, "date") || strings.Contains(name, "time"):
+		return "2024-01-01T00:00:00Z"
+	case strings.Contains(name, "json"):
+		return map[string]interface{}{}
+	}
+	return generateString(field, index)
+}
+
+func generateString(field string, index int) string {
+	lower := strings.ToLower(field)
+	switch {
+	case strings.Contains(lower, "email"):
+		return fmt.Sprintf("user%d@example.com", index)
+	case strings.Contains(lower, "url") || strings.Contains(lower, "link") || strings.Contains(lower, "website"):
+		return fmt.Sprintf("https://example.com/%s/%d", lower, index)
+	case strings.HasSuffix(field, "At") || strings.Contains(lower, "date") || strings.Contains(lower, "time"):
+		return "2024-01-01T00:00:00Z"
+	case strings.HasSuffix(field, "Id") || strings.HasSuffix(lower, "_id"):
+		return strconv.Itoa(index)
+	case strings.Contains(lower, "phone"):
+		return fmt.Sprintf("+1-555-%04d", index)
+	}
+	return fmt.Sprintf("%s %d", field, index)
+}
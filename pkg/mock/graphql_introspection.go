@@ -0,0 +1,261 @@
+package mock
+
+// introspectionSDL defines the built-in scalars, directives and the types
+// queried through __schema and __type.
+const introspectionSDL = `
+"The Int scalar type represents non-fractional signed whole numeric values."
+scalar Int
+"The Float scalar type represents signed double-precision fractional values."
+scalar Float
+"The String scalar type represents textual data as UTF-8 character sequences."
+scalar String
+"The Boolean scalar type represents true or false."
+scalar Boolean
+"The ID scalar type represents a unique identifier, serialized as a String."
+scalar ID
+
+"Directs the executor to include this field or fragment only when the if argument is true."
+directive @include(if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+"Directs the executor to skip this field or fragment when the if argument is true."
+directive @skip(if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+"Marks an element of a GraphQL schema as no longer supported."
+directive @deprecated(reason: String = "No longer supported") on FIELD_DEFINITION | ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION | ENUM_VALUE
+"Exposes a URL that specifies the behavior of this scalar."
+directive @specifiedBy(url: String!) on SCALAR
+
+type __Schema {
+  description: String
+  types: [__Type!]!
+  queryType: __Type!
+  mutationType: __Type
+  subscriptionType: __Type
+  directives: [__Directive!]!
+}
+
+type __Type {
+  kind: __TypeKind!
+  name: String
+  description: String
+  specifiedByURL: String
+  fields(includeDeprecated: Boolean = false): [__Field!]
+  interfaces: [__Type!]
+  possibleTypes: [__Type!]
+  enumValues(includeDeprecated: Boolean = false): [__EnumValue!]
+  inputFields(includeDeprecated: Boolean = false): [__InputValue!]
+  ofType: __Type
+  isOneOf: Boolean
+}
+
+enum __TypeKind {
+  SCALAR
+  OBJECT
+  INTERFACE
+  UNION
+  ENUM
+  INPUT_OBJECT
+  LIST
+  NON_NULL
+}
+
+type __Field {
+  name: String!
+  description: String
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+  type: __Type!
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __InputValue {
+  name: String!
+  description: String
+  type: __Type!
+  defaultValue: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __EnumValue {
+  name: String!
+  description: String
+  isDeprecated: Boolean!
+  deprecationReason: String
+}
+
+type __Directive {
+  name: String!
+  description: String
+  locations: [__DirectiveLocation!]!
+  args(includeDeprecated: Boolean = false): [__InputValue!]!
+  isRepeatable: Boolean!
+}
+
+enum __DirectiveLocation {
+  QUERY
+  MUTATION
+  SUBSCRIPTION
+  FIELD
+  FRAGMENT_DEFINITION
+  FRAGMENT_SPREAD
+  INLINE_FRAGMENT
+  VARIABLE_DEFINITION
+  SCHEMA
+  SCALAR
+  OBJECT
+  FIELD_DEFINITION
+  ARGUMENT_DEFINITION
+  INTERFACE
+  UNION
+  ENUM
+  ENUM_VALUE
+  INPUT_OBJECT
+  INPUT_FIELD_DEFINITION
+}
+`
+
+// The sources below feed introspection queries through the normal
+// executor. Nested types are func values resolved on demand, since the
+// type graph is cyclic.
+
+func (s *GraphQLSchema) schemaSource() map[string]interface{} {
+	return map[string]interface{}{
+		"description":      nil,
+		"queryType":        s.typeSource(s.query),
+		"mutationType":     s.optionalTypeSource(s.mutation),
+		"subscriptionType": s.optionalTypeSource(s.subscription),
+		"types": func() interface{} {
+			out := make([]interface{}, len(s.order))
+			for i, name := range s.order {
+				out[i] = s.typeSource(name)
+			}
+			return out
+		},
+		"directives": func() interface{} {
+			out := make([]interface{}, len(s.directives))
+			for i, d := range s.directives {
+				out[i] = map[string]interface{}{
+					"name":         d.name,
+					"description":  nullable(d.description),
+					"locations":    toInterfaces(d.locations),
+					"args":         s.inputValueSources(d.args),
+					"isRepeatable": d.repeatable,
+				}
+			}
+			return out
+		},
+	}
+}
+
+func (s *GraphQLSchema) optionalTypeSource(name string) interface{} {
+	if name == "" {
+		return nil
+	}
+	return s.typeSource(name)
+}
+
+func emptyTypeSource() map[string]interface{} {
+	src := make(map[string]interface{})
+	for _, f := range []string{"kind", "name", "description", "specifiedByURL", "fields", "interfaces", "possibleTypes", "enumValues", "inputFields", "ofType", "isOneOf"} {
+		src[f] = nil
+	}
+	return src
+}
+
+func (s *GraphQLSchema) typeSource(name string) map[string]interface{} {
+	t := s.types[name]
+	src := emptyTypeSource()
+	src["kind"] = t.kind
+	src["name"] = t.name
+	src["description"] = nullable(t.description)
+	src["specifiedByURL"] = nullable(t.specifiedBy)
+
+	switch t.kind {
+	case "OBJECT", "INTERFACE":
+		src["fields"] = func() interface{} {
+			out := make([]interface{}, len(t.fields))
+			for i, f := range t.fields {
+				out[i] = map[string]interface{}{
+					"name":              f.name,
+					"description":       nullable(f.description),
+					"args":              s.inputValueSources(f.args),
+					"type":              s.refSource(f.typ),
+					"isDeprecated":      f.deprecated,
+					"deprecationReason": nullable(f.deprecationReason),
+				}
+			}
+			return out
+		}
+		src["interfaces"] = func() interface{} {
+			out := make([]interface{}, len(t.interfaces))
+			for i, iface := range t.interfaces {
+				out[i] = s.typeSource(iface)
+			}
+			return out
+		}
+	case "ENUM":
+		out := make([]interface{}, len(t.enumValues))
+		for i, v := range t.enumValues {
+			out[i] = map[string]interface{}{
+				"name":              v.name,
+				"description":       nullable(v.description),
+				"isDeprecated":      v.deprecated,
+				"deprecationReason": nullable(v.deprecationReason),
+			}
+		}
+		src["enumValues"] = out
+	case "INPUT_OBJECT":
+		src["inputFields"] = func() interface{} { return s.inputValueSources(t.inputFields) }
+		src["isOneOf"] = false
+	}
+	if t.kind == "INTERFACE" || t.kind == "UNION" {
+		src["possibleTypes"] = func() interface{} {
+			possible := s.possibleTypes(t)
+			out := make([]interface{}, len(possible))
+			for i, pt := range possible {
+				out[i] = s.typeSource(pt.name)
+			}
+			return out
+		}
+	}
+	return src
+}
+
+func (s *GraphQLSchema) refSource(ref *gqlTypeRef) map[string]interface{} {
+	if ref.Kind == "" {
+		return s.typeSource(ref.Name)
+	}
+	src := emptyTypeSource()
+	src["kind"] = ref.Kind
+	src["ofType"] = func() interface{} { return s.refSource(ref.OfType) }
+	return src
+}
+
+func (s *GraphQLSchema) inputValueSources(values []*gqlInputValue) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = map[string]interface{}{
+			"name":              v.name,
+			"description":       nullable(v.description),
+			"type":              s.refSource(v.typ),
+			"defaultValue":      nullable(v.defRaw),
+			"isDeprecated":      false,
+			"deprecationReason": nil,
+		}
+	}
+	return out
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func toInterfaces(list []string) []interface{} {
+	out := make([]interface{}, len(list))
+	for i, v := range list {
+		out[i] = v
+	}
+	return out
+}
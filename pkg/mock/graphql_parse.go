@@ -0,0 +1,906 @@
+package mock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type gqlTokenKind int
+
+const (
+	gqlEOF gqlTokenKind = iota
+	gqlPunct
+	gqlName
+	gqlInt
+	gqlFloat
+	gqlString
+)
+
+type gqlToken struct {
+	kind  gqlTokenKind
+	value string
+	pos   int
+	end   int
+}
+
+// GraphQLError is an entry in the errors list of a GraphQL response.
+type GraphQLError struct {
+	Message   string            `json:"message"`
+	Locations []GraphQLLocation `json:"locations,omitempty"`
+	Path      []interface{}     `json:"path,omitempty"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (e *GraphQLError) Error() string {
+	if len(e.Locations) > 0 {
+		return fmt.Sprintf("%d:%d: %s", e.Locations[0].Line, e.Locations[0].Column, e.Message)
+	}
+	return e.Message
+}
+
+func gqlErrorAt(src string, pos int, format string, args ...interface{}) *GraphQLError {
+	line, col := 1, 1
+	for i, r := range src {
+		if i >= pos {
+			break
+		}
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return &GraphQLError{
+		Message:   fmt.Sprintf(format, args...),
+		Locations: []GraphQLLocation{{Line: line, Column: col}},
+	}
+}
+
+func lexGraphQL(src string) ([]gqlToken, error) {
+	var tokens []gqlToken
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case strings.HasPrefix(src[i:], "\ufeff"):
+			i += len("\ufeff")
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+			i++
+			tokens = append(tokens, gqlToken{kind: gqlPunct, value: string(c), pos: start, end: i})
+		case strings.HasPrefix(src[i:], "..."):
+			i += 3
+			tokens = append(tokens, gqlToken{kind: gqlPunct, value: "...", pos: start, end: i})
+		case c == '_' || isLetter(c):
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, gqlToken{kind: gqlName, value: src[start:i], pos: start, end: i})
+		case c == '-' || isDigit(c):
+			tok, err := lexNumber(src, i)
+			if err != nil {
+				return nil, err
+			}
+			i = tok.end
+			tokens = append(tokens, tok)
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			for end >= 0 && src[i+3+end-1] == '\\' {
+				next := strings.Index(src[i+3+end+3:], `"""`)
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += 3 + next
+			}
+			if end < 0 {
+				return nil, gqlErrorAt(src, start, "Syntax Error: Unterminated string.")
+			}
+			raw := strings.ReplaceAll(src[i+3:i+3+end], `\"""`, `"""`)
+			i += 3 + end + 3
+			tokens = append(tokens, gqlToken{kind: gqlString, value: blockStringValue(raw), pos: start, end: i})
+		case c == '"':
+			value, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, gqlToken{kind: gqlString, value: value, pos: start, end: i})
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, gqlErrorAt(src, start, "Syntax Error: Unexpected character %q.", r)
+		}
+	}
+	return append(tokens, gqlToken{kind: gqlEOF, pos: len(src), end: len(src)}), nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+func lexNumber(src string, i int) (gqlToken, error) {
+	start := i
+	kind := gqlInt
+	if src[i] == '-' {
+		i++
+	}
+	digits := func() bool {
+		from := i
+		for i < len(src) && isDigit(src[i]) {
+			i++
+		}
+		return i > from
+	}
+	if !digits() {
+		return gqlToken{}, gqlErrorAt(src, start, "Syntax Error: Invalid number.")
+	}
+	if i < len(src) && src[i] == '.' {
+		i++
+		kind = gqlFloat
+		if !digits() {
+			return gqlToken{}, gqlErrorAt(src, start, "Syntax Error: Invalid number.")
+		}
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		i++
+		kind = gqlFloat
+		if i < len(src) && (src[i] == '+' || src[i] == '-') {
+			i++
+		}
+		if !digits() {
+			return gqlToken{}, gqlErrorAt(src, start, "Syntax Error: Invalid number.")
+		}
+	}
+	if i < len(src) && (src[i] == '_' || src[i] == '.' || isLetter(src[i])) {
+		return gqlToken{}, gqlErrorAt(src, start, "Syntax Error: Invalid number.")
+	}
+	return gqlToken{kind: kind, value: src[start:i], pos: start, end: i}, nil
+}
+
+func lexString(src string, i int) (string, int, error) {
+	start := i
+	i++
+	var b strings.Builder
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '"':
+			return b.String(), i + 1, nil
+		case c == '\n' || c == '\r':
+			return "", 0, gqlErrorAt(src, start, "Syntax Error: Unterminated string.")
+		case c == '\\' && i+1 < len(src):
+			esc := src[i+1]
+			i += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if i+4 > len(src) {
+					return "", 0, gqlErrorAt(src, i, "Syntax Error: Invalid Unicode escape sequence.")
+				}
+				n, err := strconv.ParseUint(src[i:i+4], 16, 32)
+				if err != nil {
+					return "", 0, gqlErrorAt(src, i, "Syntax Error: Invalid Unicode escape sequence.")
+				}
+				b.WriteRune(rune(n))
+				i += 4
+			default:
+				return "", 0, gqlErrorAt(src, i-2, "Syntax Error: Invalid character escape sequence.")
+			}
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, gqlErrorAt(src, start, "Syntax Error: Unterminated string.")
+}
+
+// blockStringValue removes the common indentation and blank first and
+// last lines from a """block string""".
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gqlParser is a recursive descent parser shared by schemas and queries.
+// Syntax errors panic with *GraphQLError and are recovered by the entry
+// points.
+type gqlParser struct {
+	src    string
+	tokens []gqlToken
+	i      int
+}
+
+func newGQLParser(src string) (*gqlParser, error) {
+	tokens, err := lexGraphQL(src)
+	if err != nil {
+		return nil, err
+	}
+	return &gqlParser{src: src, tokens: tokens}, nil
+}
+
+func (p *gqlParser) recover(err *error) {
+	if r := recover(); r != nil {
+		gqlErr, ok := r.(*GraphQLError)
+		if !ok {
+			panic(r)
+		}
+		*err = gqlErr
+	}
+}
+
+func (p *gqlParser) fail(t gqlToken, format string, args ...interface{}) {
+	panic(gqlErrorAt(p.src, t.pos, "Syntax Error: "+format, args...))
+}
+
+func (p *gqlParser) peek() gqlToken { return p.tokens[p.i] }
+
+func (p *gqlParser) next() gqlToken {
+	t := p.tokens[p.i]
+	if t.kind != gqlEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *gqlParser) isPunct(v string) bool {
+	t := p.peek()
+	return t.kind == gqlPunct && t.value == v
+}
+
+func (p *gqlParser) isKeyword(v string) bool {
+	t := p.peek()
+	return t.kind == gqlName && t.value == v
+}
+
+func (p *gqlParser) skipPunct(v string) bool {
+	if p.isPunct(v) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *gqlParser) expectPunct(v string) gqlToken {
+	t := p.peek()
+	if t.kind != gqlPunct || t.value != v {
+		p.fail(t, "Expected %q, found %s.", v, describeToken(t))
+	}
+	return p.next()
+}
+
+func (p *gqlParser) expectName() string {
+	t := p.peek()
+	if t.kind != gqlName {
+		p.fail(t, "Expected Name, found %s.", describeToken(t))
+	}
+	return p.next().value
+}
+
+func (p *gqlParser) expectKeyword(v string) {
+	t := p.peek()
+	if t.kind != gqlName || t.value != v {
+		p.fail(t, "Expected %q, found %s.", v, describeToken(t))
+	}
+	p.next()
+}
+
+func describeToken(t gqlToken) string {
+	switch t.kind {
+	case gqlEOF:
+		return "<EOF>"
+	case gqlName:
+		return fmt.Sprintf("Name %q", t.value)
+	case gqlString:
+		return "String"
+	case gqlInt, gqlFloat:
+		return fmt.Sprintf("number %s", t.value)
+	default:
+		return fmt.Sprintf("%q", t.value)
+	}
+}
+
+// gqlTypeRef is a type reference such as [User!]!. Kind is LIST, NON_NULL
+// or empty for a named type.
+type gqlTypeRef struct {
+	Kind   string
+	Name   string
+	OfType *gqlTypeRef
+}
+
+func (t *gqlTypeRef) named() string {
+	for t.OfType != nil {
+		t = t.OfType
+	}
+	return t.Name
+}
+
+func (t *gqlTypeRef) String() string {
+	switch t.Kind {
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	default:
+		return t.Name
+	}
+}
+
+func (p *gqlParser) parseTypeRef() *gqlTypeRef {
+	var t *gqlTypeRef
+	if p.skipPunct("[") {
+		t = &gqlTypeRef{Kind: "LIST", OfType: p.parseTypeRef()}
+		p.expectPunct("]")
+	} else {
+		t = &gqlTypeRef{Name: p.expectName()}
+	}
+	if p.skipPunct("!") {
+		t = &gqlTypeRef{Kind: "NON_NULL", OfType: t}
+	}
+	return t
+}
+
+// gqlValue is a literal or variable in a query or schema.
+type gqlValue struct {
+	kind   string // Variable, Int, Float, String, Boolean, Null, Enum, List or Object
+	raw    string
+	list   []*gqlValue
+	fields []gqlObjectField
+	pos    int
+}
+
+type gqlObjectField struct {
+	name  string
+	value *gqlValue
+}
+
+func (p *gqlParser) parseValue(constant bool) *gqlValue {
+	t := p.peek()
+	v := &gqlValue{pos: t.pos}
+	switch {
+	case t.kind == gqlPunct && t.value == "$":
+		if constant {
+			p.fail(t, "Unexpected variable in constant value.")
+		}
+		p.next()
+		v.kind, v.raw = "Variable", p.expectName()
+	case t.kind == gqlPunct && t.value == "[":
+		p.next()
+		v.kind = "List"
+		for !p.skipPunct("]") {
+			v.list = append(v.list, p.parseValue(constant))
+		}
+	case t.kind == gqlPunct && t.value == "{":
+		p.next()
+		v.kind = "Object"
+		for !p.skipPunct("}") {
+			name := p.expectName()
+			p.expectPunct(":")
+			v.fields = append(v.fields, gqlObjectField{name: name, value: p.parseValue(constant)})
+		}
+	case t.kind == gqlInt:
+		p.next()
+		v.kind, v.raw = "Int", t.value
+	case t.kind == gqlFloat:
+		p.next()
+		v.kind, v.raw = "Float", t.value
+	case t.kind == gqlString:
+		p.next()
+		v.kind, v.raw = "String", t.value
+	case t.kind == gqlName:
+		p.next()
+		switch t.value {
+		case "true", "false":
+			v.kind = "Boolean"
+		case "null":
+			v.kind = "Null"
+		default:
+			v.kind = "Enum"
+		}
+		v.raw = t.value
+	default:
+		p.fail(t, "Unexpected %s.", describeToken(t))
+	}
+	return v
+}
+
+type gqlArgument struct {
+	name  string
+	value *gqlValue
+	pos   int
+}
+
+type gqlDirective struct {
+	name string
+	args []*gqlArgument
+	pos  int
+}
+
+func (p *gqlParser) parseArguments(constant bool) []*gqlArgument {
+	if !p.skipPunct("(") {
+		return nil
+	}
+	var args []*gqlArgument
+	for !p.skipPunct(")") {
+		pos := p.peek().pos
+		name := p.expectName()
+		p.expectPunct(":")
+		args = append(args, &gqlArgument{name: name, value: p.parseValue(constant), pos: pos})
+	}
+	return args
+}
+
+func (p *gqlParser) parseDirectives(constant bool) []*gqlDirective {
+	var dirs []*gqlDirective
+	for p.isPunct("@") {
+		pos := p.next().pos
+		dirs = append(dirs, &gqlDirective{name: p.expectName(), args: p.parseArguments(constant), pos: pos})
+	}
+	return dirs
+}
+
+// Executable documents.
+
+type gqlDocument struct {
+	operations []*gqlOperation
+	fragments  map[string]*gqlFragment
+	// fragmentOrder keeps fragments in source order for validation.
+	fragmentOrder []*gqlFragment
+}
+
+type gqlOperation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	vars       []*gqlVarDef
+	directives []*gqlDirective
+	selections []*gqlSelection
+	pos        int
+}
+
+type gqlVarDef struct {
+	name     string
+	typ      *gqlTypeRef
+	defValue *gqlValue
+	pos      int
+}
+
+type gqlFragment struct {
+	name          string
+	typeCondition string
+	directives    []*gqlDirective
+	selections    []*gqlSelection
+	pos           int
+}
+
+// gqlSelection is a field, a fragment spread (name is the fragment) or an
+// inline fragment.
+type gqlSelection struct {
+	kind          string // field, spread or inline
+	alias         string
+	name          string
+	args          []*gqlArgument
+	directives    []*gqlDirective
+	selections    []*gqlSelection
+	typeCondition string
+	pos           int
+}
+
+func (s *gqlSelection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+func parseGraphQLQuery(src string) (doc *gqlDocument, err error) {
+	p, err := newGQLParser(src)
+	if err != nil {
+		return nil, err
+	}
+	defer p.recover(&err)
+
+	doc = &gqlDocument{fragments: map[string]*gqlFragment{}}
+	if p.peek().kind == gqlEOF {
+		p.fail(p.peek(), "Unexpected <EOF>.")
+	}
+	for p.peek().kind != gqlEOF {
+		t := p.peek()
+		switch {
+		case t.kind == gqlPunct && t.value == "{":
+			doc.operations = append(doc.operations, &gqlOperation{kind: "query", selections: p.parseSelectionSet(), pos: t.pos})
+		case t.kind == gqlName && (t.value == "query" || t.value == "mutation" || t.value == "subscription"):
+			doc.operations = append(doc.operations, p.parseOperation())
+		case t.kind == gqlName && t.value == "fragment":
+			frag := p.parseFragment()
+			if _, dup := doc.fragments[frag.name]; dup {
+				panic(gqlErrorAt(src, frag.pos, "There can be only one fragment named %q.", frag.name))
+			}
+			doc.fragments[frag.name] = frag
+			doc.fragmentOrder = append(doc.fragmentOrder, frag)
+		default:
+			p.fail(t, "Unexpected %s.", describeToken(t))
+		}
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) parseOperation() *gqlOperation {
+	t := p.next()
+	op := &gqlOperation{kind: t.value, pos: t.pos}
+	if p.peek().kind == gqlName {
+		op.name = p.next().value
+	}
+	if p.skipPunct("(") {
+		for !p.skipPunct(")") {
+			pos := p.expectPunct("$").pos
+			v := &gqlVarDef{name: p.expectName(), pos: pos}
+			p.expectPunct(":")
+			v.typ = p.parseTypeRef()
+			if p.skipPunct("=") {
+				v.defValue = p.parseValue(true)
+			}
+			p.parseDirectives(true)
+			op.vars = append(op.vars, v)
+		}
+	}
+	op.directives = p.parseDirectives(false)
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+func (p *gqlParser) parseFragment() *gqlFragment {
+	pos := p.next().pos
+	frag := &gqlFragment{pos: pos}
+	if p.isKeyword("on") {
+		p.fail(p.peek(), "Unexpected Name \"on\".")
+	}
+	frag.name = p.expectName()
+	p.expectKeyword("on")
+	frag.typeCondition = p.expectName()
+	frag.directives = p.parseDirectives(false)
+	frag.selections = p.parseSelectionSet()
+	return frag
+}
+
+func (p *gqlParser) parseSelectionSet() []*gqlSelection {
+	p.expectPunct("{")
+	var sels []*gqlSelection
+	for !p.skipPunct("}") {
+		sels = append(sels, p.parseSelection())
+	}
+	if len(sels) == 0 {
+		p.fail(p.tokens[p.i-1], "Expected Name, found \"}\".")
+	}
+	return sels
+}
+
+func (p *gqlParser) parseSelection() *gqlSelection {
+	t := p.peek()
+	if p.skipPunct("...") {
+		if p.peek().kind == gqlName && !p.isKeyword("on") {
+			return &gqlSelection{kind: "spread", name: p.next().value, directives: p.parseDirectives(false), pos: t.pos}
+		}
+		sel := &gqlSelection{kind: "inline", pos: t.pos}
+		if p.isKeyword("on") {
+			p.next()
+			sel.typeCondition = p.expectName()
+		}
+		sel.directives = p.parseDirectives(false)
+		sel.selections = p.parseSelectionSet()
+		return sel
+	}
+
+	sel := &gqlSelection{kind: "field", name: p.expectName(), pos: t.pos}
+	if p.skipPunct(":") {
+		sel.alias, sel.name = sel.name, p.expectName()
+	}
+	sel.args = p.parseArguments(false)
+	sel.directives = p.parseDirectives(false)
+	if p.isPunct("{") {
+		sel.selections = p.parseSelectionSet()
+	}
+	return sel
+}
+
+// Schema definition language.
+
+type gqlType struct {
+	kind        string // SCALAR, OBJECT, INTERFACE, UNION, ENUM or INPUT_OBJECT
+	name        string
+	description string
+	fields      []*gqlField
+	interfaces  []string
+	members     []string
+	enumValues  []*gqlEnumValue
+	inputFields []*gqlInputValue
+	specifiedBy string
+}
+
+type gqlField struct {
+	name              string
+	description       string
+	args              []*gqlInputValue
+	typ               *gqlTypeRef
+	deprecated        bool
+	deprecationReason string
+}
+
+type gqlInputValue struct {
+	name        string
+	description string
+	typ         *gqlTypeRef
+	defValue    *gqlValue
+	// defRaw is the default value as written, reported by introspection.
+	defRaw string
+}
+
+type gqlEnumValue struct {
+	name              string
+	description       string
+	deprecated        bool
+	deprecationReason string
+}
+
+type gqlDirectiveDef struct {
+	name        string
+	description string
+	args        []*gqlInputValue
+	locations   []string
+	repeatable  bool
+}
+
+func (t *gqlType) field(name string) *gqlField {
+	for _, f := range t.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (t *gqlType) isLeaf() bool {
+	return t.kind == "SCALAR" || t.kind == "ENUM"
+}
+
+func (t *gqlType) isComposite() bool {
+	return t.kind == "OBJECT" || t.kind == "INTERFACE" || t.kind == "UNION"
+}
+
+// parseSDL adds the definitions in src to schema.
+func (s *GraphQLSchema) parseSDL(src string) (err error) {
+	p, err := newGQLParser(src)
+	if err != nil {
+		return err
+	}
+	defer p.recover(&err)
+
+	for p.peek().kind != gqlEOF {
+		desc := ""
+		if p.peek().kind == gqlString {
+			desc = p.next().value
+		}
+		t := p.peek()
+		keyword := p.expectName()
+		extend := keyword == "extend"
+		if extend {
+			t = p.peek()
+			keyword = p.expectName()
+		}
+
+		switch keyword {
+		case "schema":
+			p.parseDirectives(true)
+			p.expectPunct("{")
+			for !p.skipPunct("}") {
+				opType := p.expectName()
+				p.expectPunct(":")
+				name := p.expectName()
+				switch opType {
+				case "query":
+					s.query = name
+				case "mutation":
+					s.mutation = name
+				case "subscription":
+					s.subscription = name
+				default:
+					p.fail(t, "Unknown operation type %q.", opType)
+				}
+			}
+		case "directive":
+			s.addDirective(p.parseDirectiveDef(desc))
+		case "scalar", "type", "interface", "union", "enum", "input":
+			def := p.parseTypeDef(keyword, desc)
+			if err := s.addType(def, extend); err != nil {
+				panic(gqlErrorAt(src, t.pos, "%s", err))
+			}
+		default:
+			p.fail(t, "Unexpected Name %q.", keyword)
+		}
+	}
+	return nil
+}
+
+func (p *gqlParser) parseTypeDef(keyword, desc string) *gqlType {
+	t := &gqlType{name: p.expectName(), description: desc}
+	switch keyword {
+	case "scalar":
+		t.kind = "SCALAR"
+		for _, d := range p.parseDirectives(true) {
+			if d.name == "specifiedBy" && len(d.args) > 0 {
+				t.specifiedBy = d.args[0].value.raw
+			}
+		}
+	case "type", "interface":
+		t.kind = "OBJECT"
+		if keyword == "interface" {
+			t.kind = "INTERFACE"
+		}
+		if p.isKeyword("implements") {
+			p.next()
+			p.skipPunct("&")
+			t.interfaces = append(t.interfaces, p.expectName())
+			for p.skipPunct("&") {
+				t.interfaces = append(t.interfaces, p.expectName())
+			}
+		}
+		p.parseDirectives(true)
+		if p.skipPunct("{") {
+			for !p.skipPunct("}") {
+				t.fields = append(t.fields, p.parseFieldDef())
+			}
+		}
+	case "union":
+		t.kind = "UNION"
+		p.parseDirectives(true)
+		if p.skipPunct("=") {
+			p.skipPunct("|")
+			t.members = append(t.members, p.expectName())
+			for p.skipPunct("|") {
+				t.members = append(t.members, p.expectName())
+			}
+		}
+	case "enum":
+		t.kind = "ENUM"
+		p.parseDirectives(true)
+		if p.skipPunct("{") {
+			for !p.skipPunct("}") {
+				v := &gqlEnumValue{}
+				if p.peek().kind == gqlString {
+					v.description = p.next().value
+				}
+				v.name = p.expectName()
+				v.deprecated, v.deprecationReason = deprecation(p.parseDirectives(true))
+				t.enumValues = append(t.enumValues, v)
+			}
+		}
+	case "input":
+		t.kind = "INPUT_OBJECT"
+		p.parseDirectives(true)
+		if p.skipPunct("{") {
+			for !p.skipPunct("}") {
+				t.inputFields = append(t.inputFields, p.parseInputValueDef())
+			}
+		}
+	}
+	return t
+}
+
+func (p *gqlParser) parseFieldDef() *gqlField {
+	f := &gqlField{}
+	if p.peek().kind == gqlString {
+		f.description = p.next().value
+	}
+	f.name = p.expectName()
+	f.args = p.parseArgumentDefs()
+	p.expectPunct(":")
+	f.typ = p.parseTypeRef()
+	f.deprecated, f.deprecationReason = deprecation(p.parseDirectives(true))
+	return f
+}
+
+func (p *gqlParser) parseArgumentDefs() []*gqlInputValue {
+	if !p.skipPunct("(") {
+		return nil
+	}
+	var args []*gqlInputValue
+	for !p.skipPunct(")") {
+		args = append(args, p.parseInputValueDef())
+	}
+	return args
+}
+
+func (p *gqlParser) parseInputValueDef() *gqlInputValue {
+	v := &gqlInputValue{}
+	if p.peek().kind == gqlString {
+		v.description = p.next().value
+	}
+	v.name = p.expectName()
+	p.expectPunct(":")
+	v.typ = p.parseTypeRef()
+	if p.skipPunct("=") {
+		start := p.peek().pos
+		v.defValue = p.parseValue(true)
+		v.defRaw = p.src[start:p.tokens[p.i-1].end]
+	}
+	p.parseDirectives(true)
+	return v
+}
+
+func (p *gqlParser) parseDirectiveDef(desc string) *gqlDirectiveDef {
+	p.expectPunct("@")
+	d := &gqlDirectiveDef{name: p.expectName(), description: desc}
+	d.args = p.parseArgumentDefs()
+	if p.isKeyword("repeatable") {
+		p.next()
+		d.repeatable = true
+	}
+	p.expectKeyword("on")
+	p.skipPunct("|")
+	d.locations = append(d.locations, p.expectName())
+	for p.skipPunct("|") {
+		d.locations = append(d.locations, p.expectName())
+	}
+	return d
+}
+
+func deprecation(dirs []*gqlDirective) (bool, string) {
+	for _, d := range dirs {
+		if d.name != "deprecated" {
+			continue
+		}
+		for _, arg := range d.args {
+			if arg.name == "reason" {
+				return true, arg.value.raw
+			}
+		}
+		return true, "No longer supported"
+	}
+	return false, ""
+}
@@ -0,0 +1,274 @@
+package mock_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+)
+
+const testSchema = `
+interface Node { id: ID! }
+
+type User implements Node {
+  id: ID!
+  name: String!
+  email: String
+  role: Role!
+  posts(first: Int): [Post!]!
+}
+
+type Post implements Node {
+  id: ID!
+  title: String
+  publishedAt: String
+  oldTitle: String @deprecated(reason: "use title")
+}
+
+enum Role { ADMIN MEMBER }
+
+union SearchResult = User | Post
+
+input NewPost { title: String! }
+
+type Query {
+  user(id: ID!): User
+  users(limit: Int): [User!]!
+  node(id: ID!): Node
+  search(text: String!): [SearchResult!]!
+}
+
+type Mutation {
+  createPost(input: NewPost!): Post!
+}
+`
+
+func newGraphQLServer(t *testing.T, cfg mock.GraphQLConfig) *httptest.Server {
+	t.Helper()
+	cfg.SDL = testSchema
+	handler, err := mock.NewGraphQLHandler(&cfg)
+	if err != nil {
+		t.Fatalf("NewGraphQLHandler: %v", err)
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func graphqlPost(t *testing.T, url, query string, vars map[string]interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	defer resp.Body.Close()
+	var out map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return resp.StatusCode, out
+}
+
+func TestGraphQLGeneratesTypedData(t *testing.T) {
+	srv := newGraphQLServer(t, mock.GraphQLConfig{})
+
+	status, out := graphqlPost(t, srv.URL, `query Q($id: ID!) {
+  user(id: $id) { id name email role posts(first: 3) { id title } }
+  users { id }
+}`, map[string]interface{}{"id": "42"})
+	if status != http.StatusOK || out["errors"] != nil {
+		t.Fatalf("status %d: %v", status, out)
+	}
+
+	data := out["data"].(map[string]interface{})
+	user := data["user"].(map[string]interface{})
+	if user["id"] != "42" {
+		t.Errorf("id = %v, want the argument 42", user["id"])
+	}
+	if user["email"] != "user1@example.com" || user["role"] != "ADMIN" {
+		t.Errorf("user = %v", user)
+	}
+	if posts := user["posts"].([]interface{}); len(posts) != 3 {
+		t.Errorf("posts = %d, want first: 3", len(posts))
+	}
+	if users := data["users"].([]interface{}); len(users) != 2 {
+		t.Errorf("users = %d, want default list length 2", len(users))
+	}
+
+	// Arguments are coerced to the type of the field they seed.
+	_, out = graphqlPost(t, srv.URL, `{ user(id: 42) { id } }`, nil)
+	if id := out["data"].(map[string]interface{})["user"].(map[string]interface{})["id"]; id != "42" {
+		t.Errorf("id = %#v, want the string \"42\": %v", id, out)
+	}
+	_, out = graphqlPost(t, srv.URL, `query Q($id: ID!) { user(id: $id) { id } }`, map[string]interface{}{"id": 7})
+	if id := out["data"].(map[string]interface{})["user"].(map[string]interface{})["id"]; id != "7" {
+		t.Errorf("id = %#v, want the string \"7\": %v", id, out)
+	}
+}
+
+func TestGraphQLOverrides(t *testing.T) {
+	srv := newGraphQLServer(t, mock.GraphQLConfig{
+		Overrides: map[string]interface{}{
+			"Query.user": map[string]interface{}{"name": "Ada"},
+			"Query.search": []interface{}{
+				map[string]interface{}{"__typename": "Post", "title": "Hello"},
+			},
+		},
+		Operations: map[string]interface{}{
+			"Fixed": map[string]interface{}{"data": map[string]interface{}{"user": nil}},
+		},
+	})
+
+	_, out := graphqlPost(t, srv.URL, `{
+  user(id: "1") { name role }
+  search(text: "x") { __typename ... on Post { title } ... on User { name } }
+}`, nil)
+	data := out["data"].(map[string]interface{})
+	if name := data["user"].(map[string]interface{})["name"]; name != "Ada" {
+		t.Errorf("name = %v, want Ada", name)
+	}
+	hit := data["search"].([]interface{})[0].(map[string]interface{})
+	if hit["__typename"] != "Post" || hit["title"] != "Hello" {
+		t.Errorf("search = %v", hit)
+	}
+
+	_, out = graphqlPost(t, srv.URL, `query Fixed { user(id: "1") { name } }`, nil)
+	if user := out["data"].(map[string]interface{})["user"]; user != nil {
+		t.Errorf("operation override ignored: %v", out)
+	}
+}
+
+func TestGraphQLValidationErrors(t *testing.T) {
+	srv := newGraphQLServer(t, mock.GraphQLConfig{})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{ user(id: "1") { nope } }`, `Cannot query field "nope" on type "User"`},
+		{`{ user { name } }`, `Argument "id" of type "ID!" is required`},
+		{`{ user(id: "1") }`, `must have a selection of subfields`},
+		{`{ users { name { x } } }`, `must not have a selection`},
+		{`query { user(id: $id) { name } }`, `Variable "$id" is not defined`},
+		{`{ users { ...F } } fragment F on User { posts { ...G } } fragment G on Post { ...F }`, `Cannot spread fragment`},
+		{`{ users { name `, `Syntax Error`},
+	}
+	for _, tt := range tests {
+		status, out := graphqlPost(t, srv.URL, tt.query, nil)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", tt.query, status)
+		}
+		if _, hasData := out["data"]; hasData {
+			t.Errorf("%s: unexpected data", tt.query)
+		}
+		errs, _ := out["errors"].([]interface{})
+		if len(errs) == 0 {
+			t.Errorf("%s: no errors", tt.query)
+			continue
+		}
+		first := errs[0].(map[string]interface{})
+		if !strings.Contains(first["message"].(string), tt.want) {
+			t.Errorf("%s: message %q, want %q", tt.query, first["message"], tt.want)
+		}
+		if first["locations"] == nil {
+			t.Errorf("%s: missing locations", tt.query)
+		}
+	}
+}
+
+func TestGraphQLMutationAndVariables(t *testing.T) {
+	srv := newGraphQLServer(t, mock.GraphQLConfig{})
+
+	query := `mutation M($input: NewPost!) { createPost(input: $input) { title } }`
+	status, out := graphqlPost(t, srv.URL, query, map[string]interface{}{"input": map[string]interface{}{}})
+	if status != http.StatusBadRequest {
+		t.Errorf("invalid input: status %d, want 400: %v", status, out)
+	}
+	status, _ = graphqlPost(t, srv.URL, query, map[string]interface{}{"input": map[string]interface{}{"title": "x"}})
+	if status != http.StatusOK {
+		t.Errorf("valid input: status %d", status)
+	}
+
+	resp, err := http.Get(srv.URL + "?query=" + strings.ReplaceAll("mutation { createPost(input: {title: \"x\"}) { title } }", " ", "+"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("mutation over GET: status %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestGraphQLIntrospection(t *testing.T) {
+	srv := newGraphQLServer(t, mock.GraphQLConfig{})
+
+	_, out := graphqlPost(t, srv.URL, `{
+  __schema { queryType { name } mutationType { name } types { name } }
+  __type(name: "Post") {
+    kind
+    fields { name type { kind ofType { name } } }
+    all: fields(includeDeprecated: true) { name isDeprecated deprecationReason }
+    interfaces { name }
+  }
+  role: __type(name: "Role") { enumValues { name } }
+}`, nil)
+	if out["errors"] != nil {
+		t.Fatalf("errors: %v", out["errors"])
+	}
+	data := out["data"].(map[string]interface{})
+
+	schema := data["__schema"].(map[string]interface{})
+	if schema["queryType"].(map[string]interface{})["name"] != "Query" {
+		t.Errorf("queryType = %v", schema["queryType"])
+	}
+	names := map[string]bool{}
+	for _, typ := range schema["types"].([]interface{}) {
+		names[typ.(map[string]interface{})["name"].(string)] = true
+	}
+	for _, want := range []string{"User", "SearchResult", "NewPost", "String", "__Type"} {
+		if !names[want] {
+			t.Errorf("types missing %s", want)
+		}
+	}
+
+	post := data["__type"].(map[string]interface{})
+	if post["kind"] != "OBJECT" || len(post["fields"].([]interface{})) != 3 || len(post["all"].([]interface{})) != 4 {
+		t.Errorf("Post = %v", post)
+	}
+	id := post["fields"].([]interface{})[0].(map[string]interface{})["type"].(map[string]interface{})
+	if id["kind"] != "NON_NULL" || id["ofType"].(map[string]interface{})["name"] != "ID" {
+		t.Errorf("id type = %v", id)
+	}
+	if roles := data["role"].(map[string]interface{})["enumValues"].([]interface{}); len(roles) != 2 {
+		t.Errorf("enumValues = %v", roles)
+	}
+}
+
+func TestGraphQLEndpointConfig(t *testing.T) {
+	cfg, err := mock.LoadConfig("../../examples/mocks/graphql.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := mock.NewServer()
+	if err := cfg.Apply(s); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	_, out := graphqlPost(t, srv.URL+"/graphql", `{ pokemon(name: "Pikachu") { name types maxHP } }`, nil)
+	pokemon := out["data"].(map[string]interface{})["pokemon"].(map[string]interface{})
+	if pokemon["name"] != "Pikachu" || pokemon["maxHP"] != float64(1002) {
+		t.Errorf("pokemon = %v", pokemon)
+	}
+
+	exported := s.Export()
+	if len(exported.Endpoints) != 2 || exported.Endpoints[0].GraphQL == nil || exported.Endpoints[0].GraphQL.SDL == "" {
+		t.Errorf("graphql endpoints should export with the schema inlined: %+v", exported.Endpoints)
+	}
+}
@@ -0,0 +1,447 @@
+package mock
+
+import (
+	"fmt"
+	"math"
+)
+
+type gqlValidator struct {
+	schema *GraphQLSchema
+	doc    *gqlDocument
+	src    string
+	used   map[string]bool
+	errors []*GraphQLError
+}
+
+func (v *gqlValidator) errorf(pos int, format string, args ...interface{}) {
+	v.errors = append(v.errors, gqlErrorAt(v.src, pos, format, args...))
+}
+
+// validate checks doc against the schema and picks the operation to run.
+func (s *GraphQLSchema) validate(src string, doc *gqlDocument, operationName string) (*gqlOperation, []*GraphQLError) {
+	v := &gqlValidator{schema: s, doc: doc, src: src, used: make(map[string]bool)}
+
+	names := make(map[string]bool)
+	for _, op := range doc.operations {
+		switch {
+		case op.name == "" && len(doc.operations) > 1:
+			v.errorf(op.pos, "This anonymous operation must be the only defined operation.")
+		case names[op.name]:
+			v.errorf(op.pos, "There can be only one operation named %q.", op.name)
+		}
+		names[op.name] = true
+
+		root := s.rootType(op)
+		if root == nil {
+			v.errorf(op.pos, "Schema is not configured to execute %s operation.", op.kind)
+			continue
+		}
+		v.directives(op.directives)
+		v.selections(root, op.selections)
+		v.variables(op)
+	}
+
+	for _, frag := range doc.fragmentOrder {
+		t := s.types[frag.typeCondition]
+		switch {
+		case t == nil:
+			v.errorf(frag.pos, "Unknown type %q.", frag.typeCondition)
+		case !t.isComposite():
+			v.errorf(frag.pos, "Fragment %q cannot condition on non composite type %q.", frag.name, t.name)
+		default:
+			v.directives(frag.directives)
+			v.selections(t, frag.selections)
+		}
+	}
+	for _, frag := range doc.fragmentOrder {
+		if !v.used[frag.name] {
+			v.errorf(frag.pos, "Fragment %q is never used.", frag.name)
+		}
+	}
+	v.fragmentCycles()
+
+	if len(v.errors) > 0 {
+		return nil, v.errors
+	}
+
+	if operationName != "" {
+		for _, op := range doc.operations {
+			if op.name == operationName {
+				return op, nil
+			}
+		}
+		return nil, []*GraphQLError{{Message: fmt.Sprintf("Unknown operation named %q.", operationName)}}
+	}
+	if len(doc.operations) != 1 {
+		if len(doc.operations) == 0 {
+			return nil, []*GraphQLError{{Message: "Must provide an operation."}}
+		}
+		return nil, []*GraphQLError{{Message: "Must provide operation name if query contains multiple operations."}}
+	}
+	return doc.operations[0], nil
+}
+
+func (v *gqlValidator) selections(t *gqlType, sels []*gqlSelection) {
+	for _, sel := range sels {
+		v.directives(sel.directives)
+		switch sel.kind {
+		case "field":
+			def := v.schema.fieldDef(t, sel.name)
+			if def == nil {
+				v.errorf(sel.pos, "Cannot query field %q on type %q.", sel.name, t.name)
+				continue
+			}
+			v.arguments(t.name+"."+def.name, def.args, sel.args, sel.pos)
+
+			named := v.schema.types[def.typ.named()]
+			switch {
+			case named.isLeaf() && sel.selections != nil:
+				v.errorf(sel.pos, "Field %q must not have a selection since type %q has no subfields.", sel.name, def.typ.String())
+			case !named.isLeaf() && sel.selections == nil:
+				v.errorf(sel.pos, "Field %q of type %q must have a selection of subfields. Did you mean \"%s { ... }\"?", sel.name, def.typ.String(), sel.name)
+			case sel.selections != nil:
+				v.selections(named, sel.selections)
+			}
+		case "spread":
+			if v.doc.fragments[sel.name] == nil {
+				v.errorf(sel.pos, "Unknown fragment %q.", sel.name)
+				continue
+			}
+			v.used[sel.name] = true
+		case "inline":
+			target := t
+			if sel.typeCondition != "" {
+				target = v.schema.types[sel.typeCondition]
+				if target == nil {
+					v.errorf(sel.pos, "Unknown type %q.", sel.typeCondition)
+					continue
+				}
+				if !target.isComposite() {
+					v.errorf(sel.pos, "Fragment cannot condition on non composite type %q.", target.name)
+					continue
+				}
+			}
+			v.selections(target, sel.selections)
+		}
+	}
+}
+
+func (v *gqlValidator) arguments(where string, defs []*gqlInputValue, given []*gqlArgument, pos int) {
+	provided := make(map[string]bool)
+	for _, arg := range given {
+		def := findInputValue(defs, arg.name)
+		if def == nil {
+			v.errorf(arg.pos, "Unknown argument %q on %s.", arg.name, where)
+			continue
+		}
+		provided[arg.name] = arg.value.kind != "Null"
+		if !v.schema.literalMatches(def.typ, arg.value) {
+			v.errorf(arg.value.pos, "Argument %q on %s expects type %q.", arg.name, where, def.typ.String())
+		}
+	}
+	for _, def := range defs {
+		if def.typ.Kind == "NON_NULL" && def.defValue == nil && !provided[def.name] {
+			v.errorf(pos, "Argument %q of type %q is required on %s, but it was not provided.", def.name, def.typ.String(), where)
+		}
+	}
+}
+
+func (v *gqlValidator) directives(dirs []*gqlDirective) {
+	for _, d := range dirs {
+		var def *gqlDirectiveDef
+		for _, candidate := range v.schema.directives {
+			if candidate.name == d.name {
+				def = candidate
+			}
+		}
+		if def == nil {
+			v.errorf(d.pos, "Unknown directive \"@%s\".", d.name)
+			continue
+		}
+		v.arguments("@"+d.name, def.args, d.args, d.pos)
+	}
+}
+
+// variables checks that op defines every variable it uses, including in
+// the fragments it spreads, and uses every variable it defines.
+func (v *gqlValidator) variables(op *gqlOperation) {
+	used := make(map[string]bool)
+	visited := make(map[string]bool)
+	var walkValue func(val *gqlValue)
+	walkValue = func(val *gqlValue) {
+		switch val.kind {
+		case "Variable":
+			used[val.raw] = true
+			if !op.definesVar(val.raw) {
+				if op.name == "" {
+					v.errorf(val.pos, "Variable \"$%s\" is not defined.", val.raw)
+				} else {
+					v.errorf(val.pos, "Variable \"$%s\" is not defined by operation %q.", val.raw, op.name)
+				}
+			}
+		case "List":
+			for _, item := range val.list {
+				walkValue(item)
+			}
+		case "Object":
+			for _, f := range val.fields {
+				walkValue(f.value)
+			}
+		}
+	}
+	walkDirectives := func(dirs []*gqlDirective) {
+		for _, d := range dirs {
+			for _, arg := range d.args {
+				walkValue(arg.value)
+			}
+		}
+	}
+	var walk func(sels []*gqlSelection)
+	walk = func(sels []*gqlSelection) {
+		for _, sel := range sels {
+			walkDirectives(sel.directives)
+			for _, arg := range sel.args {
+				walkValue(arg.value)
+			}
+			if sel.kind == "spread" {
+				if frag := v.doc.fragments[sel.name]; frag != nil && !visited[sel.name] {
+					visited[sel.name] = true
+					walk(frag.selections)
+				}
+				continue
+			}
+			walk(sel.selections)
+		}
+	}
+	walkDirectives(op.directives)
+	walk(op.selections)
+
+	for _, def := range op.vars {
+		if t := v.schema.types[def.typ.named()]; t == nil || t.isComposite() {
+			v.errorf(def.pos, "Variable \"$%s\" cannot be non-input type %q.", def.name, def.typ.String())
+		}
+		if !used[def.name] {
+			if op.name == "" {
+				v.errorf(def.pos, "Variable \"$%s\" is never used.", def.name)
+			} else {
+				v.errorf(def.pos, "Variable \"$%s\" is never used in operation %q.", def.name, op.name)
+			}
+		}
+	}
+}
+
+func (op *gqlOperation) definesVar(name string) bool {
+	for _, def := range op.vars {
+		if def.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fragmentCycles reports fragments that spread themselves, directly or
+// through other fragments, which would never finish executing.
+func (v *gqlValidator) fragmentCycles() {
+	const visiting, done = 1, 2
+	state := make(map[string]int)
+	var visit func(frag *gqlFragment)
+	var spreads func(sels []*gqlSelection)
+	spreads = func(sels []*gqlSelection) {
+		for _, sel := range sels {
+			if sel.kind != "spread" {
+				spreads(sel.selections)
+				continue
+			}
+			target := v.doc.fragments[sel.name]
+			if target == nil {
+				continue
+			}
+			switch state[sel.name] {
+			case visiting:
+				v.errorf(sel.pos, "Cannot spread fragment %q within itself.", sel.name)
+			case 0:
+				visit(target)
+			}
+		}
+	}
+	visit = func(frag *gqlFragment) {
+		state[frag.name] = visiting
+		spreads(frag.selections)
+		state[frag.name] = done
+	}
+	for _, frag := range v.doc.fragmentOrder {
+		if state[frag.name] == 0 {
+			visit(frag)
+		}
+	}
+}
+
+func findInputValue(defs []*gqlInputValue, name string) *gqlInputValue {
+	for _, def := range defs {
+		if def.name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// literalMatches reports whether a query literal can be coerced to ref.
+// Variables are checked separately by coerceVariables.
+func (s *GraphQLSchema) literalMatches(ref *gqlTypeRef, val *gqlValue) bool {
+	if val.kind == "Variable" {
+		return true
+	}
+	switch ref.Kind {
+	case "NON_NULL":
+		return val.kind != "Null" && s.literalMatches(ref.OfType, val)
+	case "LIST":
+		if val.kind != "List" {
+			return s.literalMatches(ref.OfType, val)
+		}
+		for _, item := range val.list {
+			if !s.literalMatches(ref.OfType, item) {
+				return false
+			}
+		}
+		return true
+	}
+	if val.kind == "Null" {
+		return true
+	}
+
+	t := s.types[ref.Name]
+	switch t.kind {
+	case "ENUM":
+		return val.kind == "Enum" && t.hasEnumValue(val.raw)
+	case "INPUT_OBJECT":
+		if val.kind != "Object" {
+			return false
+		}
+		given := make(map[string]bool)
+		for _, f := range val.fields {
+			def := findInputValue(t.inputFields, f.name)
+			if def == nil || !s.literalMatches(def.typ, f.value) {
+				return false
+			}
+			given[f.name] = true
+		}
+		for _, def := range t.inputFields {
+			if def.typ.Kind == "NON_NULL" && def.defValue == nil && !given[def.name] {
+				return false
+			}
+		}
+		return true
+	}
+	switch t.name {
+	case "Int":
+		return val.kind == "Int"
+	case "Float":
+		return val.kind == "Int" || val.kind == "Float"
+	case "String":
+		return val.kind == "String"
+	case "Boolean":
+		return val.kind == "Boolean"
+	case "ID":
+		return val.kind == "String" || val.kind == "Int"
+	}
+	return true
+}
+
+// valueMatches is literalMatches for JSON variable values.
+func (s *GraphQLSchema) valueMatches(ref *gqlTypeRef, val interface{}) bool {
+	switch ref.Kind {
+	case "NON_NULL":
+		return val != nil && s.valueMatches(ref.OfType, val)
+	case "LIST":
+		list, ok := val.([]interface{})
+		if !ok {
+			return s.valueMatches(ref.OfType, val)
+		}
+		for _, item := range list {
+			if !s.valueMatches(ref.OfType, item) {
+				return false
+			}
+		}
+		return true
+	}
+	if val == nil {
+		return true
+	}
+
+	t := s.types[ref.Name]
+	switch t.kind {
+	case "ENUM":
+		name, ok := val.(string)
+		return ok && t.hasEnumValue(name)
+	case "INPUT_OBJECT":
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for name, item := range obj {
+			def := findInputValue(t.inputFields, name)
+			if def == nil || !s.valueMatches(def.typ, item) {
+				return false
+			}
+		}
+		for _, def := range t.inputFields {
+			if _, given := obj[def.name]; def.typ.Kind == "NON_NULL" && def.defValue == nil && !given {
+				return false
+			}
+		}
+		return true
+	}
+	switch t.name {
+	case "Int":
+		n, ok := val.(float64)
+		return ok && n == math.Trunc(n)
+	case "Float":
+		_, ok := val.(float64)
+		return ok
+	case "String":
+		_, ok := val.(string)
+		return ok
+	case "Boolean":
+		_, ok := val.(bool)
+		return ok
+	case "ID":
+		switch n := val.(type) {
+		case string:
+			return true
+		case float64:
+			return n == math.Trunc(n)
+		}
+		return false
+	}
+	return true
+}
+
+func (t *gqlType) hasEnumValue(name string) bool {
+	for _, v := range t.enumValues {
+		if v.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// coerceVariables applies defaults to the request's variables and checks
+// them against their declared types.
+func (s *GraphQLSchema) coerceVariables(src string, op *gqlOperation, given map[string]interface{}) (map[string]interface{}, []*GraphQLError) {
+	out := make(map[string]interface{})
+	var errs []*GraphQLError
+	for _, def := range op.vars {
+		val, ok := given[def.name]
+		switch {
+		case !ok && def.defValue != nil:
+			out[def.name] = gqlLiteral(def.defValue, nil)
+		case (!ok || val == nil) && def.typ.Kind == "NON_NULL":
+			errs = append(errs, gqlErrorAt(src, def.pos, "Variable \"$%s\" of required type %q was not provided.", def.name, def.typ.String()))
+		case ok && !s.valueMatches(def.typ, val):
+			errs = append(errs, gqlErrorAt(src, def.pos, "Variable \"$%s\" got invalid value for type %q.", def.name, def.typ.String()))
+		case ok:
+			out[def.name] = val
+		}
+	}
+	return out, errs
+}
@@ -0,0 +1,384 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultJournalSize = 1000
+	maxJournalBody     = 64 << 10
+	maxNearMisses      = 3
+)
+
+// JournalEntry is one request received by the mock server.
+type JournalEntry struct {
+	ID      int64             `json:"id"`
+	Time    time.Time         `json:"time"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	// Matched names the endpoint or resource that answered, empty if none did.
+	Matched    string        `json:"matched,omitempty"`
+	Status     int           `json:"status"`
+	Duration   time.Duration `json:"duration"`
+	NearMisses []NearMiss    `json:"nearMisses,omitempty"`
+	// Callbacks holds the outcome of callbacks fired by this request as
+	// they complete.
+	Callbacks []CallbackResult `json:"callbacks,omitempty"`
+}
+
+// NearMiss is an endpoint that almost matched an unmatched request.
+type NearMiss struct {
+	Endpoint    string   `json:"endpoint"`
+	Differences []string `json:"differences"`
+	distance    int
+}
+
+// RequestQuery selects journal entries. Empty fields match everything; Path
+// accepts the same patterns as endpoints and Body/Headers are regexps.
+type RequestQuery struct {
+	Method    string            `json:"method,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Body      string            `json:"body,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Unmatched bool              `json:"unmatched,omitempty"`
+}
+
+// Journal is a bounded log of received requests; once full the oldest
+// entries are dropped.
+type Journal struct {
+	mu      sync.RWMutex
+	limit   int
+	entries []JournalEntry
+	start   int
+	nextID  int64
+	dropped int64
+}
+
+func NewJournal(limit int) *Journal {
+	if limit <= 0 {
+		limit = DefaultJournalSize
+	}
+	return &Journal{limit: limit}
+}
+
+func (j *Journal) Record(e JournalEntry) JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.nextID++
+	e.ID = j.nextID
+	if len(j.entries) < j.limit {
+		j.entries = append(j.entries, e)
+		return e
+	}
+	j.entries[j.start] = e
+	j.start = (j.start + 1) % j.limit
+	j.dropped++
+	return e
+}
+
+// attach adds a callback result to entry id, unless it has been evicted.
+func (j *Journal) attach(id int64, result CallbackResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := range j.entries {
+		if e := &j.entries[i]; e.ID == id {
+			// copy so slices already handed out by Entries are unaffected
+			e.Callbacks = append(e.Callbacks[:len(e.Callbacks):len(e.Callbacks)], result)
+			return
+		}
+	}
+}
+
+// finish sets the final status and duration of entry id, unless it has
+// been evicted.
+func (j *Journal) finish(id int64, status int, d time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := range j.entries {
+		if e := &j.entries[i]; e.ID == id {
+			e.Status, e.Duration = status, d
+			return
+		}
+	}
+}
+
+// Entries returns all retained entries, oldest first.
+func (j *Journal) Entries() []JournalEntry {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.ordered()
+}
+
+func (j *Journal) ordered() []JournalEntry {
+	out := make([]JournalEntry, 0, len(j.entries))
+	out = append(out, j.entries[j.start:]...)
+	return append(out, j.entries[:j.start]...)
+}
+
+// Dropped reports how many entries were evicted because the journal was full.
+func (j *Journal) Dropped() int64 {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.dropped
+}
+
+func (j *Journal) Find(q RequestQuery) ([]JournalEntry, error) {
+	m, err := q.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	out := []JournalEntry{}
+	for _, e := range j.ordered() {
+		if m.matches(e) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (j *Journal) Count(q RequestQuery) (int, error) {
+	entries, err := j.Find(q)
+	return len(entries), err
+}
+
+// Unmatched returns requests no endpoint answered, with near-miss diagnostics.
+func (j *Journal) Unmatched() []JournalEntry {
+	entries, _ := j.Find(RequestQuery{Unmatched: true})
+	return entries
+}
+
+func (j *Journal) Reset() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = nil
+	j.start = 0
+	j.dropped = 0
+}
+
+type requestMatcher struct {
+	query   RequestQuery
+	body    *regexp.Regexp
+	headers map[string]*regexp.Regexp
+}
+
+func (q RequestQuery) compile() (*requestMatcher, error) {
+	m := &requestMatcher{query: q, headers: map[string]*regexp.Regexp{}}
+	if q.Body != "" {
+		re, err := regexp.Compile(q.Body)
+		if err != nil {
+			return nil, fmt.Errorf("body matcher: %w", err)
+		}
+		m.body = re
+	}
+	for name, pattern := range q.Headers {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("header matcher %s: %w", name, err)
+		}
+		m.headers[name] = re
+	}
+	return m, nil
+}
+
+func (m *requestMatcher) matches(e JournalEntry) bool {
+	q := m.query
+	if q.Unmatched && e.Matched != "" {
+		return false
+	}
+	if q.Method != "" && !strings.EqualFold(q.Method, e.Method) {
+		return false
+	}
+	if q.Path != "" && q.Path != e.Path && !pathPatternMatches(q.Path, e.Path) {
+		return false
+	}
+	if m.body != nil && !m.body.MatchString(e.Body) {
+		return false
+	}
+	for name, re := range m.headers {
+		if !re.MatchString(headerValue(e.Headers, name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func pathPatternMatches(pattern, path string) bool {
+	if strings.Contains(pattern, "{") {
+		_, ok := matchTemplate(pattern, path)
+		return ok
+	}
+	if strings.Contains(pattern, "*") {
+		re, err := regexp.Compile("^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$")
+		return err == nil && re.MatchString(path)
+	}
+	return pattern == path
+}
+
+// nearMisses ranks endpoints by how close they came to matching r.
+// Caller holds s.mu.
+func (s *Server) nearMisses(r *http.Request, body []byte) []NearMiss {
+	var misses []NearMiss
+	for _, ep := range s.endpoints {
+		var diffs []string
+		distance := 0
+
+		if ep.Method != r.Method {
+			diffs = append(diffs, fmt.Sprintf("method: expected %s, got %s", ep.Method, r.Method))
+			distance += 2
+		}
+		if !s.pathMatches(ep.Path, r.URL.Path) {
+			d := editDistance(ep.Path, r.URL.Path)
+			diffs = append(diffs, fmt.Sprintf("path: expected %s, got %s", ep.Path, r.URL.Path))
+			distance += d
+		}
+		if ep.Matcher != nil {
+			names := make([]string, 0, len(ep.Matcher.HeaderMatchers))
+			for name := range ep.Matcher.HeaderMatchers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if v := r.Header.Get(name); !ep.Matcher.HeaderMatchers[name].MatchString(v) {
+					diffs = append(diffs, fmt.Sprintf("header %s: %q does not match %s", name, v, ep.Matcher.HeaderMatchers[name]))
+					distance++
+				}
+			}
+			if ep.Matcher.BodyMatcher != nil && !ep.Matcher.BodyMatcher.Match(body) {
+				diffs = append(diffs, fmt.Sprintf("body does not match %s", ep.Matcher.BodyMatcher))
+				distance++
+			}
+		}
+		if sc := s.scenarioOf(ep); sc != nil && !s.inState(ep, r) {
+			diffs = append(diffs, fmt.Sprintf("scenario %s: in state %s, endpoint requires %s", sc.Name, sc.State(sc.clientKey(r)), ep.Scenario.State))
+			distance++
+		}
+
+		// an endpoint differing in everything is not a near miss
+		if len(diffs) == 0 || distance > len(r.URL.Path)/2+3 {
+			continue
+		}
+		misses = append(misses, NearMiss{
+			Endpoint:    ep.Method + " " + ep.Path,
+			Differences: diffs,
+			distance:    distance,
+		})
+	}
+
+	sort.Slice(misses, func(i, j int) bool {
+		if misses[i].distance != misses[j].distance {
+			return misses[i].distance < misses[j].distance
+		}
+		return misses[i].Endpoint < misses[j].Endpoint
+	})
+	if len(misses) > maxNearMisses {
+		misses = misses[:maxNearMisses]
+	}
+	return misses
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func newJournalEntry(r *http.Request, body []byte) JournalEntry {
+	headers := make(map[string]string, len(r.Header))
+	for k, vs := range r.Header {
+		headers[k] = strings.Join(vs, ", ")
+	}
+	if len(body) > maxJournalBody {
+		body = body[:maxJournalBody]
+	}
+	return JournalEntry{
+		Time:    time.Now(),
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.RawQuery,
+		Headers: headers,
+		Body:    string(body),
+	}
+}
+
+// handleJournal serves the journal admin routes:
+//
+//	GET    /requests            list, filtered by method, path, body and unmatched query params
+//	GET    /requests/count      {"count": n} for the same filters
+//	POST   /requests/find       RequestQuery as JSON body, including header matchers
+//	GET    /requests/unmatched  unmatched requests with near misses
+//	DELETE /requests            clear the journal
+func (s *Server) handleJournal(w http.ResponseWriter, r *http.Request, route string) {
+	switch {
+	case route == "/requests" && r.Method == http.MethodDelete:
+		s.journal.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	case (route == "/requests" || route == "/requests/count") && r.Method == http.MethodGet:
+		params := r.URL.Query()
+		unmatched, _ := strconv.ParseBool(params.Get("unmatched"))
+		q := RequestQuery{
+			Method:    params.Get("method"),
+			Path:      params.Get("path"),
+			Body:      params.Get("body"),
+			Unmatched: unmatched,
+		}
+		s.writeJournalResult(w, q, route == "/requests/count")
+	case route == "/requests/find" && r.Method == http.MethodPost:
+		var q RequestQuery
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("decode query: %v", err))
+			return
+		}
+		s.writeJournalResult(w, q, false)
+	case route == "/requests/unmatched" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.journal.Unmatched())
+	default:
+		writeError(w, http.StatusNotFound, "unknown admin route")
+	}
+}
+
+func (s *Server) writeJournalResult(w http.ResponseWriter, q RequestQuery, count bool) {
+	entries, err := s.journal.Find(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if count {
+		writeJSON(w, http.StatusOK, map[string]int{"count": len(entries)})
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
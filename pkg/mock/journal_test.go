@@ -0,0 +1,154 @@
+package mock_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+)
+
+func TestJournal_FindAndCount(t *testing.T) {
+	srv := mock.NewServer()
+	srv.AddEndpoint(&mock.Endpoint{Path: "/api/users/{id}", Method: "GET", Response: mock.Response{StatusCode: 200}})
+	srv.AddEndpoint(&mock.Endpoint{Path: "/api/orders", Method: "POST", Response: mock.Response{StatusCode: 201}})
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	http.Get(ts.URL + "/api/users/1")
+	http.Get(ts.URL + "/api/users/2")
+	req, _ := http.NewRequest("POST", ts.URL+"/api/orders", strings.NewReader(`{"sku":"abc"}`))
+	req.Header.Set("X-Tenant", "acme")
+	http.DefaultClient.Do(req)
+
+	j := srv.Journal()
+	if n, _ := j.Count(mock.RequestQuery{Path: "/api/users/{id}"}); n != 2 {
+		t.Fatalf("expected 2 user requests, got %d", n)
+	}
+	found, err := j.Find(mock.RequestQuery{Method: "post", Body: `"sku":"abc"`, Headers: map[string]string{"x-tenant": "^acme$"}})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("find order: %v %v", found, err)
+	}
+	if found[0].Status != 201 || found[0].Matched != "POST /api/orders" {
+		t.Fatalf("unexpected entry: %+v", found[0])
+	}
+	if _, err := j.Find(mock.RequestQuery{Body: "("}); err == nil {
+		t.Fatal("expected invalid regexp error")
+	}
+}
+
+func TestJournal_NearMisses(t *testing.T) {
+	srv := mock.NewServer()
+	srv.AddEndpoint(&mock.Endpoint{Path: "/api/users", Method: "GET", Response: mock.Response{StatusCode: 200}})
+	srv.AddEndpoint(&mock.Endpoint{Path: "/health", Method: "GET", Response: mock.Response{StatusCode: 200}})
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	http.Get(ts.URL + "/api/user")
+
+	unmatched := srv.Journal().Unmatched()
+	if len(unmatched) != 1 || unmatched[0].Status != 404 {
+		t.Fatalf("expected one unmatched request, got %+v", unmatched)
+	}
+	misses := unmatched[0].NearMisses
+	if len(misses) != 1 || misses[0].Endpoint != "GET /api/users" {
+		t.Fatalf("unexpected near misses: %+v", misses)
+	}
+}
+
+func TestJournal_AdminAPI(t *testing.T) {
+	srv := mock.NewServer()
+	srv.AddEndpoint(&mock.Endpoint{Path: "/ping", Method: "GET", Response: mock.Response{StatusCode: 200}})
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	for i := 0; i < 3; i++ {
+		http.Get(ts.URL + "/ping")
+	}
+	http.Get(ts.URL + "/missing")
+
+	count := func(query string) int {
+		res, err := http.Get(ts.URL + mock.AdminPrefix + "/requests/count" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var body struct{ Count int }
+		json.NewDecoder(res.Body).Decode(&body)
+		return body.Count
+	}
+	if n := count("?path=/ping"); n != 3 {
+		t.Fatalf("expected 3 pings, got %d", n)
+	}
+	if n := count("?unmatched=true"); n != 1 {
+		t.Fatalf("expected 1 unmatched, got %d", n)
+	}
+
+	res, err := http.Post(ts.URL+mock.AdminPrefix+"/requests/find", "application/json", strings.NewReader(`{"method":"GET","path":"/missing"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []mock.JournalEntry
+	json.NewDecoder(res.Body).Decode(&entries)
+	res.Body.Close()
+	if len(entries) != 1 || entries[0].Path != "/missing" {
+		t.Fatalf("unexpected find result: %+v", entries)
+	}
+
+	req, _ := http.NewRequest("DELETE", ts.URL+mock.AdminPrefix+"/requests", nil)
+	if res, err := http.DefaultClient.Do(req); err != nil || res.StatusCode != 204 {
+		t.Fatalf("clear journal: %v %v", res, err)
+	}
+	if n := count(""); n != 0 {
+		t.Fatalf("expected empty journal, got %d", n)
+	}
+}
+
+func TestJournal_Bounded(t *testing.T) {
+	j := mock.NewJournal(2)
+	for _, p := range []string{"/a", "/b", "/c"} {
+		j.Record(mock.JournalEntry{Path: p})
+	}
+	entries := j.Entries()
+	if len(entries) != 2 || entries[0].Path != "/b" || entries[1].Path != "/c" || j.Dropped() != 1 {
+		t.Fatalf("unexpected entries %+v dropped %d", entries, j.Dropped())
+	}
+}
+
+func TestJournal_RecordedBeforeResponse(t *testing.T) {
+	srv := mock.NewServer()
+	srv.AddEndpoint(&mock.Endpoint{Path: "/slow", Method: "GET", Response: mock.Response{StatusCode: 200, Body: "abcdef"},
+		Faults: &mock.FaultProfile{Trickle: &mock.Trickle{ChunkSize: 1, Interval: 20 * time.Millisecond}}})
+	srv.AddEndpoint(&mock.Endpoint{Path: "/broken", Method: "GET", Response: mock.Response{StatusCode: 200},
+		Faults: &mock.FaultProfile{Errors: []mock.ErrorFault{{Status: 503, Probability: 1}}}})
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The body is still trickling in, but the request is already journaled.
+	if entries := srv.Journal().Entries(); len(entries) != 1 || entries[0].Status != 200 {
+		t.Fatalf("expected the request journaled before its body was sent, got %+v", entries)
+	}
+	io.ReadAll(res.Body)
+	res.Body.Close()
+
+	res, err = http.Get(ts.URL + "/broken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if entries := srv.Journal().Entries(); len(entries) != 2 || entries[1].Status != 503 {
+		t.Fatalf("expected the fault status to be patched in, got %+v", entries)
+	}
+}
@@ -0,0 +1,489 @@
+package mock
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Instance is one named mock server managed by a Manager. Instances with
+// their own Addr get a dedicated listener; the rest share the manager's
+// listener and are routed by Host and/or PathPrefix.
+type Instance struct {
+	Name   string
+	Server *Server
+	Addr   string
+	// Host routes requests whose Host header matches, ignoring the port.
+	Host string
+	// PathPrefix routes requests under the prefix, which is stripped before
+	// the request reaches Server.
+	PathPrefix string
+	// TLS serves the listener over HTTPS with HTTP/2 enabled. Without
+	// CertFile and KeyFile a self-signed certificate is generated.
+	TLS      bool
+	CertFile string
+	KeyFile  string
+
+	handler http.Handler
+	url     string
+}
+
+// URL is the base URL of the instance once its listener is bound.
+func (inst *Instance) URL() string {
+	return inst.url
+}
+
+// Manager runs many isolated mock instances in one process.
+type Manager struct {
+	// Addr is the shared listener for instances without their own Addr.
+	Addr string
+
+	mu        sync.RWMutex
+	instances []*Instance
+	listeners []*listener
+}
+
+type listener struct {
+	addr      string
+	ln        net.Listener
+	tlsConfig *tls.Config
+	instances []*Instance
+}
+
+func NewManager(addr string) *Manager {
+	return &Manager{Addr: addr}
+}
+
+func (m *Manager) Add(inst *Instance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if inst.Name == "" {
+		return fmt.Errorf("instance missing name")
+	}
+	if inst.Server == nil {
+		return fmt.Errorf("instance %s: missing server", inst.Name)
+	}
+	if inst.PathPrefix != "" {
+		inst.PathPrefix = "/" + strings.Trim(inst.PathPrefix, "/")
+	}
+	for _, existing := range m.instances {
+		if existing.Name == inst.Name {
+			return fmt.Errorf("instance %s already exists", inst.Name)
+		}
+		if existing.Addr == inst.Addr && strings.EqualFold(existing.Host, inst.Host) && existing.PathPrefix == inst.PathPrefix {
+			return fmt.Errorf("instance %s: same address and route as %s", inst.Name, existing.Name)
+		}
+	}
+
+	inst.handler = inst.Server
+	if inst.PathPrefix != "" {
+		inst.handler = http.StripPrefix(inst.PathPrefix, inst.Server)
+	}
+	m.instances = append(m.instances, inst)
+	return nil
+}
+
+func (m *Manager) Get(name string) *Instance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, inst := range m.instances {
+		if inst.Name == name {
+			return inst
+		}
+	}
+	return nil
+}
+
+func (m *Manager) Instances() []*Instance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]*Instance, len(m.instances))
+	copy(out, m.instances)
+	return out
+}
+
+// Listen binds every listener so instance URLs are known before Serve.
+func (m *Manager) Listen() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.instances) == 0 {
+		return fmt.Errorf("no mock instances")
+	}
+
+	byAddr := map[string]*listener{}
+	var groups []*listener
+	for _, inst := range m.instances {
+		addr := inst.Addr
+		if addr == "" {
+			addr = m.Addr
+		}
+		l, ok := byAddr[addr]
+		if !ok {
+			l = &listener{addr: addr}
+			byAddr[addr] = l
+			groups = append(groups, l)
+		}
+		if len(l.instances) > 0 && l.instances[0].TLS != inst.TLS {
+			return fmt.Errorf("instances %s and %s share %s but disagree on TLS", l.instances[0].Name, inst.Name, addr)
+		}
+		l.instances = append(l.instances, inst)
+	}
+
+	for _, l := range groups {
+		if err := l.listen(); err != nil {
+			m.closeListeners(groups)
+			return err
+		}
+	}
+	m.listeners = groups
+	return nil
+}
+
+func (m *Manager) closeListeners(groups []*listener) {
+	for _, l := range groups {
+		if l.ln != nil {
+			l.ln.Close()
+		}
+	}
+}
+
+func (l *listener) listen() error {
+	if l.instances[0].TLS {
+		cfg, err := l.loadTLS()
+		if err != nil {
+			return err
+		}
+		l.tlsConfig = cfg
+	}
+
+	ln, err := net.Listen("tcp", l.addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", l.addr, err)
+	}
+	l.ln = ln
+
+	scheme := "http"
+	if l.tlsConfig != nil {
+		scheme = "https"
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	for _, inst := range l.instances {
+		host := "localhost"
+		if inst.Host != "" {
+			host = inst.Host
+		}
+		inst.url = fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, port), inst.PathPrefix)
+	}
+
+	// most specific routes first
+	sort.SliceStable(l.instances, func(i, j int) bool {
+		a, b := l.instances[i], l.instances[j]
+		if (a.Host != "") != (b.Host != "") {
+			return a.Host != ""
+		}
+		return len(a.PathPrefix) > len(b.PathPrefix)
+	})
+	return nil
+}
+
+func (l *listener) loadTLS() (*tls.Config, error) {
+	var certs []tls.Certificate
+	var hosts []string
+	for _, inst := range l.instances {
+		if inst.CertFile != "" || inst.KeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(inst.CertFile, inst.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("instance %s: load certificate: %w", inst.Name, err)
+			}
+			certs = append(certs, cert)
+		}
+		if inst.Host != "" {
+			hosts = append(hosts, inst.Host)
+		}
+	}
+	if len(certs) == 0 {
+		cert, err := SelfSignedCert(hosts...)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return &tls.Config{Certificates: certs}, nil
+}
+
+// ServeHTTP routes a request on a shared listener to its instance.
+func (l *listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == AdminPrefix+"/instances" {
+		l.serveInstances(w)
+		return
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, inst := range l.instances {
+		if inst.Host != "" && !strings.EqualFold(inst.Host, host) {
+			continue
+		}
+		if inst.PathPrefix != "" {
+			rest, ok := strings.CutPrefix(r.URL.Path, inst.PathPrefix)
+			if !ok || (rest != "" && rest[0] != '/') {
+				continue
+			}
+		}
+		inst.handler.ServeHTTP(w, r)
+		return
+	}
+	writeError(w, http.StatusNotFound, "no mock instance for this host and path")
+}
+
+func (l *listener) serveInstances(w http.ResponseWriter) {
+	type info struct {
+		Name       string `json:"name"`
+		URL        string `json:"url"`
+		Host       string `json:"host,omitempty"`
+		PathPrefix string `json:"pathPrefix,omitempty"`
+		Endpoints  int    `json:"endpoints"`
+	}
+	out := make([]info, 0, len(l.instances))
+	for _, inst := range l.instances {
+		out = append(out, info{
+			Name:       inst.Name,
+			URL:        inst.url,
+			Host:       inst.Host,
+			PathPrefix: inst.PathPrefix,
+			Endpoints:  len(inst.Server.Endpoints()),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Serve runs every listener until ctx is cancelled or one fails, then shuts
+// all of them down gracefully. Listen is called first if needed.
+func (m *Manager) Serve(ctx context.Context) error {
+	m.mu.RLock()
+	bound := m.listeners != nil
+	m.mu.RUnlock()
+	if !bound {
+		if err := m.Listen(); err != nil {
+			return err
+		}
+	}
+
+	m.mu.RLock()
+	groups := m.listeners
+	m.mu.RUnlock()
+
+	servers := make([]*http.Server, len(groups))
+	errc := make(chan error, len(groups))
+	for i, l := range groups {
+		srv := &http.Server{Handler: l, TLSConfig: l.tlsConfig}
+		servers[i] = srv
+
+		for _, inst := range l.instances {
+			slog.Info("mock instance starting", "name", inst.Name, "url", inst.url)
+		}
+		go func() {
+			if l.tlsConfig != nil {
+				// certificates come from TLSConfig; ServeTLS also enables HTTP/2
+				errc <- srv.ServeTLS(l.ln, "", "")
+				return
+			}
+			errc <- srv.Serve(l.ln)
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errc:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		srv.Shutdown(shutdownCtx)
+	}
+	for _, l := range groups {
+		for _, inst := range l.instances {
+			inst.Server.Close()
+		}
+	}
+
+	m.mu.Lock()
+	m.listeners = nil
+	m.mu.Unlock()
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// SelfSignedCert generates a certificate for localhost, the loopback
+// addresses and any extra hosts, valid for one year.
+func SelfSignedCert(hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate serial: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Nexus Mock"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("create certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// ManagerConfig is the file format for running several instances:
+//
+//	listen: :9999
+//	instances:
+//	  - name: payments
+//	    addr: :9443
+//	    tls: true
+//	    config: payments.yaml
+//	  - name: users
+//	    pathPrefix: /users
+//	    openapi: users.yaml
+type ManagerConfig struct {
+	Listen    string           `yaml:"listen,omitempty"`
+	Instances []InstanceConfig `yaml:"instances"`
+
+	baseDir string
+}
+
+type InstanceConfig struct {
+	Name       string `yaml:"name"`
+	Addr       string `yaml:"addr,omitempty"`
+	Host       string `yaml:"host,omitempty"`
+	PathPrefix string `yaml:"pathPrefix,omitempty"`
+	TLS        bool   `yaml:"tls,omitempty"`
+	CertFile   string `yaml:"certFile,omitempty"`
+	KeyFile    string `yaml:"keyFile,omitempty"`
+	Config     string `yaml:"config,omitempty"`
+	OpenAPI    string `yaml:"openapi,omitempty"`
+}
+
+func LoadManagerConfig(path string) (*ManagerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	var cfg ManagerConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	cfg.baseDir = filepath.Dir(path)
+	return &cfg, nil
+}
+
+// Build creates a manager with one server per instance, loading each
+// instance's endpoint config and OpenAPI spec. addr is used when the file
+// does not set listen.
+func (c *ManagerConfig) Build(addr string) (*Manager, error) {
+	if c.Listen != "" {
+		addr = c.Listen
+	}
+	m := NewManager(addr)
+	resolve := (&Config{baseDir: c.baseDir}).resolvePath
+
+	for _, ic := range c.Instances {
+		srv, err := LoadServer(resolve(ic.Config), resolve(ic.OpenAPI))
+		if err != nil {
+			return nil, fmt.Errorf("instance %s: %w", ic.Name, err)
+		}
+		err = m.Add(&Instance{
+			Name:       ic.Name,
+			Server:     srv,
+			Addr:       ic.Addr,
+			Host:       ic.Host,
+			PathPrefix: ic.PathPrefix,
+			TLS:        ic.TLS,
+			CertFile:   resolve(ic.CertFile),
+			KeyFile:    resolve(ic.KeyFile),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// LoadServer creates a server from an endpoint config file and/or an
+// OpenAPI spec; either path may be empty.
+func LoadServer(configPath, openapiPath string) (*Server, error) {
+	srv := NewServer()
+	if configPath != "" {
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.Apply(srv); err != nil {
+			return nil, err
+		}
+	}
+	if openapiPath != "" {
+		spec, err := LoadOpenAPI(openapiPath)
+		if err != nil {
+			return nil, err
+		}
+		endpoints, err := spec.Endpoints()
+		if err != nil {
+			return nil, err
+		}
+		for _, ep := range endpoints {
+			srv.AddEndpoint(ep)
+		}
+	}
+	return srv, nil
+}
@@ -0,0 +1,151 @@
+package mock_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+	"golang.org/x/net/http2"
+)
+
+func staticServer(body string) *mock.Server {
+	srv := mock.NewServer()
+	srv.AddEndpoint(&mock.Endpoint{Path: "/who", Method: "GET", Response: mock.Response{StatusCode: 200, Body: body}})
+	return srv
+}
+
+func runManager(t *testing.T, m *mock.Manager) (stop func() error) {
+	t.Helper()
+	if err := m.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Serve(ctx) }()
+	return func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("manager did not shut down")
+			return nil
+		}
+	}
+}
+
+func TestManager_SharedListenerRouting(t *testing.T) {
+	m := mock.NewManager("127.0.0.1:0")
+	for _, inst := range []*mock.Instance{
+		{Name: "root", Server: staticServer("root")},
+		{Name: "team-a", Server: staticServer("a"), PathPrefix: "/team-a"},
+		{Name: "billing", Server: staticServer("billing"), Host: "billing.local"},
+	} {
+		if err := m.Add(inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Add(&mock.Instance{Name: "root", Server: mock.NewServer()}); err == nil {
+		t.Fatal("expected duplicate name error")
+	}
+	stop := runManager(t, m)
+
+	get := func(url, host string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		if host != "" {
+			req.Host = host
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return string(body)
+	}
+
+	root := m.Get("root").URL()
+	if got := get(root+"/who", ""); got != "root" {
+		t.Fatalf("root instance: %q", got)
+	}
+	if got := get(m.Get("team-a").URL()+"/who", ""); got != "a" {
+		t.Fatalf("prefixed instance: %q", got)
+	}
+	if got := get(root+"/who", "billing.local"); got != "billing" {
+		t.Fatalf("host-routed instance: %q", got)
+	}
+
+	// instances keep separate journals
+	if n, _ := m.Get("team-a").Server.Journal().Count(mock.RequestQuery{}); n != 1 {
+		t.Fatalf("team-a journal has %d entries", n)
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if _, err := http.Get(root + "/who"); err == nil {
+		t.Fatal("expected listener to be closed after shutdown")
+	}
+}
+
+func TestManager_TLSAndHTTP2(t *testing.T) {
+	m := mock.NewManager("127.0.0.1:0")
+	m.Add(&mock.Instance{Name: "secure", Server: staticServer("secure"), Addr: "127.0.0.1:0", TLS: true})
+	stop := runManager(t, m)
+	defer stop()
+
+	client := &http.Client{Transport: &http2.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	res, err := client.Get(m.Get("secure").URL() + "/who")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.ProtoMajor != 2 || string(body) != "secure" {
+		t.Fatalf("expected HTTP/2 response, got %s %q", res.Proto, body)
+	}
+}
+
+func TestManagerConfig_Build(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("endpoints:\n  - path: /who\n    response: {body: from-file}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "instances.yaml"), []byte(`
+listen: 127.0.0.1:0
+instances:
+  - name: a
+    pathPrefix: a
+    config: a.yaml
+  - name: b
+    pathPrefix: /b
+`), 0644)
+
+	cfg, err := mock.LoadManagerConfig(filepath.Join(dir, "instances.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := cfg.Build(":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runManager(t, m)
+	defer stop()
+
+	res, err := http.Get(m.Get("a").URL() + "/who")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "from-file" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if res, _ := http.Get(m.Get("b").URL() + "/who"); res.StatusCode != 404 {
+		t.Fatalf("instance b should have no endpoints, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,478 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPISpec is the subset of an OpenAPI 3 document needed to mock it.
+type OpenAPISpec struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components Components           `yaml:"components"`
+}
+
+type Components struct {
+	Schemas       map[string]*Schema          `yaml:"schemas"`
+	Parameters    map[string]*Parameter       `yaml:"parameters"`
+	RequestBodies map[string]*RequestBody     `yaml:"requestBodies"`
+	Responses     map[string]*OpenAPIResponse `yaml:"responses"`
+	Examples      map[string]*Example         `yaml:"examples"`
+}
+
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Delete     *Operation   `yaml:"delete"`
+	Options    *Operation   `yaml:"options"`
+	Head       *Operation   `yaml:"head"`
+	Patch      *Operation   `yaml:"patch"`
+}
+
+type Operation struct {
+	OperationID string                      `yaml:"operationId"`
+	Parameters  []*Parameter                `yaml:"parameters"`
+	RequestBody *RequestBody                `yaml:"requestBody"`
+	Responses   map[string]*OpenAPIResponse `yaml:"responses"`
+}
+
+type Parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+}
+
+type RequestBody struct {
+	Ref      string                `yaml:"$ref"`
+	Required bool                  `yaml:"required"`
+	Content  map[string]*MediaType `yaml:"content"`
+}
+
+type OpenAPIResponse struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Headers     map[string]*Parameter `yaml:"headers"`
+	Content     map[string]*MediaType `yaml:"content"`
+}
+
+type MediaType struct {
+	Schema   *Schema             `yaml:"schema"`
+	Example  interface{}         `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+type Example struct {
+	Ref     string      `yaml:"$ref"`
+	Summary string      `yaml:"summary"`
+	Value   interface{} `yaml:"value"`
+}
+
+func LoadOpenAPI(path string) (*OpenAPISpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return ParseOpenAPI(data)
+}
+
+// ParseOpenAPI parses a YAML or JSON OpenAPI 3 document.
+func ParseOpenAPI(data []byte) (*OpenAPISpec, error) {
+	var spec OpenAPISpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("unmarshal openapi: %w", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version: %q", spec.OpenAPI)
+	}
+	return &spec, nil
+}
+
+// Endpoints builds one mock endpoint per operation in the spec. Each
+// endpoint validates incoming requests and answers with declared examples or
+// bodies synthesized from the response schema.
+func (spec *OpenAPISpec) Endpoints() ([]*Endpoint, error) {
+	paths := make([]string, 0, len(spec.Paths))
+	for p := range spec.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var endpoints []*Endpoint
+	for _, path := range paths {
+		item := spec.Paths[path]
+		ops := []struct {
+			method string
+			op     *Operation
+		}{
+			{http.MethodGet, item.Get}, {http.MethodPut, item.Put}, {http.MethodPost, item.Post},
+			{http.MethodDelete, item.Delete}, {http.MethodOptions, item.Options},
+			{http.MethodHead, item.Head}, {http.MethodPatch, item.Patch},
+		}
+
+		for _, o := range ops {
+			if o.op == nil {
+				continue
+			}
+			h, err := spec.newOperationHandler(path, item, o.op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", o.method, path, err)
+			}
+			endpoints = append(endpoints, &Endpoint{
+				Path:    path,
+				Method:  o.method,
+				Handler: h,
+			})
+		}
+	}
+	return endpoints, nil
+}
+
+type operationHandler struct {
+	spec   *OpenAPISpec
+	path   string
+	params []*Parameter
+	body   *RequestBody
+	op     *Operation
+}
+
+func (spec *OpenAPISpec) newOperationHandler(path string, item *PathItem, op *Operation) (*operationHandler, error) {
+	h := &operationHandler{spec: spec, path: path, op: op}
+
+	// operation parameters override path-level ones with the same name and location
+	byKey := map[string]*Parameter{}
+	var order []string
+	for _, list := range [][]*Parameter{item.Parameters, op.Parameters} {
+		for _, p := range list {
+			resolved, err := spec.resolveParameter(p)
+			if err != nil {
+				return nil, err
+			}
+			key := resolved.In + ":" + resolved.Name
+			if _, ok := byKey[key]; !ok {
+				order = append(order, key)
+			}
+			byKey[key] = resolved
+		}
+	}
+	for _, key := range order {
+		h.params = append(h.params, byKey[key])
+	}
+
+	if op.RequestBody != nil {
+		body, err := spec.resolveRequestBody(op.RequestBody)
+		if err != nil {
+			return nil, err
+		}
+		h.body = body
+	}
+
+	if len(op.Responses) == 0 {
+		return nil, fmt.Errorf("no responses declared")
+	}
+	return h, nil
+}
+
+func (h *operationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if errs := h.validate(r); len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":   "request validation failed",
+			"details": errs,
+		})
+		return
+	}
+
+	prefer := parsePrefer(r.Header.Get("Prefer"))
+
+	code, resp, err := h.selectResponse(prefer["code"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if n, err := strconv.Atoi(code); err == nil {
+		status = n
+	} else if code == "default" {
+		status = http.StatusInternalServerError
+	}
+
+	for name, hp := range resp.Headers {
+		if p, err := h.spec.resolveParameter(hp); err == nil && p.Schema != nil {
+			w.Header().Set(name, fmt.Sprint(h.spec.Synthesize(p.Schema)))
+		}
+	}
+
+	contentType, media := selectMedia(resp.Content)
+	if media == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	body, err := h.spec.exampleBody(media, prefer["example"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	if s, ok := body.(string); ok && !strings.Contains(contentType, "json") {
+		w.Write([]byte(s))
+		return
+	}
+	data, _ := json.Marshal(normalizeYAML(body))
+	w.Write(data)
+}
+
+func (h *operationHandler) validate(r *http.Request) []ValidationError {
+	var errs []ValidationError
+	pathParams, _ := matchTemplate(h.path, r.URL.Path)
+	query := r.URL.Query()
+
+	for _, p := range h.params {
+		loc := p.In + "." + p.Name
+		var raw []string
+		switch p.In {
+		case "path":
+			if v, ok := pathParams[p.Name]; ok {
+				raw = []string{v}
+			}
+		case "query":
+			raw = query[p.Name]
+		case "header":
+			raw = r.Header.Values(p.Name)
+		case "cookie":
+			if c, err := r.Cookie(p.Name); err == nil {
+				raw = []string{c.Value}
+			}
+		}
+
+		if len(raw) == 0 {
+			if p.Required || p.In == "path" {
+				errs = append(errs, ValidationError{Location: loc, Message: "required parameter is missing"})
+			}
+			continue
+		}
+		if p.Schema == nil {
+			continue
+		}
+
+		v, err := h.spec.coerce(p.Schema, raw)
+		if err != nil {
+			errs = append(errs, ValidationError{Location: loc, Message: err.Error()})
+			continue
+		}
+		errs = append(errs, h.spec.Validate(p.Schema, v, loc)...)
+	}
+
+	if h.body == nil {
+		return errs
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return append(errs, ValidationError{Location: "body", Message: err.Error()})
+	}
+	if len(data) == 0 {
+		if h.body.Required {
+			errs = append(errs, ValidationError{Location: "body", Message: "request body is required"})
+		}
+		return errs
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	media, ok := h.body.Content[strings.TrimSpace(contentType)]
+	if !ok {
+		if len(h.body.Content) > 0 {
+			var allowed []string
+			for ct := range h.body.Content {
+				allowed = append(allowed, ct)
+			}
+			sort.Strings(allowed)
+			errs = append(errs, ValidationError{
+				Location: "header.Content-Type",
+				Message:  fmt.Sprintf("unsupported content type %q, expected one of %s", contentType, strings.Join(allowed, ", ")),
+			})
+		}
+		return errs
+	}
+	if media.Schema == nil || !strings.Contains(contentType, "json") {
+		return errs
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return append(errs, ValidationError{Location: "body", Message: fmt.Sprintf("invalid JSON: %v", err)})
+	}
+	return append(errs, h.spec.Validate(media.Schema, body, "body")...)
+}
+
+// selectResponse picks the response for the preferred status code, or the
+// lowest declared 2xx, falling back to "default".
+func (h *operationHandler) selectResponse(preferred string) (string, *OpenAPIResponse, error) {
+	pick := func(code string) (string, *OpenAPIResponse, error) {
+		resp, err := h.spec.resolveResponse(h.op.Responses[code])
+		return code, resp, err
+	}
+
+	if preferred != "" {
+		if _, ok := h.op.Responses[preferred]; ok {
+			return pick(preferred)
+		}
+		return "", nil, fmt.Errorf("no response declared for status %s", preferred)
+	}
+
+	codes := make([]string, 0, len(h.op.Responses))
+	for code := range h.op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			return pick(code)
+		}
+	}
+	if _, ok := h.op.Responses["default"]; ok {
+		return pick("default")
+	}
+	return pick(codes[0])
+}
+
+func selectMedia(content map[string]*MediaType) (string, *MediaType) {
+	if len(content) == 0 {
+		return "", nil
+	}
+	if m, ok := content["application/json"]; ok {
+		return "application/json", m
+	}
+	types := make([]string, 0, len(content))
+	for ct := range content {
+		types = append(types, ct)
+	}
+	sort.Strings(types)
+	return types[0], content[types[0]]
+}
+
+func (spec *OpenAPISpec) exampleBody(media *MediaType, name string) (interface{}, error) {
+	if name != "" {
+		ex, ok := media.Examples[name]
+		if !ok {
+			return nil, fmt.Errorf("no example named %q", name)
+		}
+		ex, err := spec.resolveExample(ex)
+		if err != nil {
+			return nil, err
+		}
+		return ex.Value, nil
+	}
+
+	if media.Example != nil {
+		return media.Example, nil
+	}
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for n := range media.Examples {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		ex, err := spec.resolveExample(media.Examples[names[0]])
+		if err != nil {
+			return nil, err
+		}
+		return ex.Value, nil
+	}
+	if media.Schema != nil {
+		return spec.Synthesize(media.Schema), nil
+	}
+	return nil, nil
+}
+
+// parsePrefer parses an RFC 7240 Prefer header such as "code=404, example=missing".
+func parsePrefer(header string) map[string]string {
+	out := map[string]string{}
+	for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ',' || r == ';' }) {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		out[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return out
+}
+
+func refName(ref, prefix string) (string, error) {
+	name, ok := strings.CutPrefix(ref, prefix)
+	if !ok {
+		return "", fmt.Errorf("unsupported $ref: %s", ref)
+	}
+	return name, nil
+}
+
+func (spec *OpenAPISpec) resolveParameter(p *Parameter) (*Parameter, error) {
+	for i := 0; p != nil && p.Ref != "" && i < maxRefDepth; i++ {
+		name, err := refName(p.Ref, "#/components/parameters/")
+		if err != nil {
+			return nil, err
+		}
+		p = spec.Components.Parameters[name]
+	}
+	if p == nil {
+		return nil, fmt.Errorf("unresolved parameter")
+	}
+	return p, nil
+}
+
+func (spec *OpenAPISpec) resolveRequestBody(b *RequestBody) (*RequestBody, error) {
+	for i := 0; b != nil && b.Ref != "" && i < maxRefDepth; i++ {
+		name, err := refName(b.Ref, "#/components/requestBodies/")
+		if err != nil {
+			return nil, err
+		}
+		b = spec.Components.RequestBodies[name]
+	}
+	if b == nil {
+		return nil, fmt.Errorf("unresolved request body")
+	}
+	return b, nil
+}
+
+func (spec *OpenAPISpec) resolveResponse(r *OpenAPIResponse) (*OpenAPIResponse, error) {
+	for i := 0; r != nil && r.Ref != "" && i < maxRefDepth; i++ {
+		name, err := refName(r.Ref, "#/components/responses/")
+		if err != nil {
+			return nil, err
+		}
+		r = spec.Components.Responses[name]
+	}
+	if r == nil {
+		return nil, fmt.Errorf("unresolved response")
+	}
+	return r, nil
+}
+
+func (spec *OpenAPISpec) resolveExample(e *Example) (*Example, error) {
+	for i := 0; e != nil && e.Ref != "" && i < maxRefDepth; i++ {
+		name, err := refName(e.Ref, "#/components/examples/")
+		if err != nil {
+			return nil, err
+		}
+		e = spec.Components.Examples[name]
+	}
+	if e == nil {
+		return nil, fmt.Errorf("unresolved example")
+	}
+	return e, nil
+}
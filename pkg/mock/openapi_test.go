@@ -0,0 +1,118 @@
+package mock_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+)
+
+func newOpenAPIServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	spec, err := mock.LoadOpenAPI("../../examples/openapi/petstore.yaml")
+	if err != nil {
+		t.Fatalf("LoadOpenAPI: %v", err)
+	}
+	endpoints, err := spec.Endpoints()
+	if err != nil {
+		t.Fatalf("Endpoints: %v", err)
+	}
+
+	srv := mock.NewServer()
+	for _, ep := range endpoints {
+		srv.AddEndpoint(ep)
+	}
+	return httptest.NewServer(srv)
+}
+
+func TestOpenAPI_Responses(t *testing.T) {
+	ts := newOpenAPIServer(t)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/pets?limit=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pets []map[string]interface{}
+	json.NewDecoder(res.Body).Decode(&pets)
+	res.Body.Close()
+	if res.StatusCode != 200 || len(pets) != 1 || pets[0]["name"] != "string" || pets[0]["id"] != float64(1) {
+		t.Fatalf("synthesized list: status %d body %v", res.StatusCode, pets)
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL+"/pets/7", nil)
+	req.Header.Set("Prefer", "example=dog")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 || !strings.Contains(string(b), "Rex") {
+		t.Fatalf("named example: status %d body %s", res.StatusCode, b)
+	}
+
+	req.Header.Set("Prefer", "code=404")
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ = io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 404 || !strings.Contains(string(b), "pet not found") {
+		t.Fatalf("preferred code: status %d body %s", res.StatusCode, b)
+	}
+}
+
+func TestOpenAPI_Validation(t *testing.T) {
+	ts := newOpenAPIServer(t)
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		location string
+	}{
+		{"query out of range", "GET", "/pets?limit=500", "", "query.limit"},
+		{"path not an integer", "GET", "/pets/abc", "", "path.petId"},
+		{"missing required property", "POST", "/pets", `{"tag":"cat"}`, "body.name"},
+		{"enum violation", "POST", "/pets", `{"name":"Tom","tag":"fish"}`, "body.tag"},
+		{"missing body", "POST", "/pets", "", "body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			var out struct {
+				Details []mock.ValidationError `json:"details"`
+			}
+			json.NewDecoder(res.Body).Decode(&out)
+			if res.StatusCode != 400 || len(out.Details) == 0 || out.Details[0].Location != tt.location {
+				t.Fatalf("expected 400 at %s, got %d %+v", tt.location, res.StatusCode, out.Details)
+			}
+		})
+	}
+
+	res, err := http.Post(ts.URL+"/pets", "application/json", strings.NewReader(`{"name":"Tom","tag":"cat"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != 201 {
+		t.Fatalf("valid create: expected 201 got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,771 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces sensitive values in recordings. During replay a recorded
+// value of Redacted matches anything.
+const Redacted = "REDACTED"
+
+// DefaultMatchKeys are the request parts compared when replaying.
+var DefaultMatchKeys = []string{"method", "path", "query", "body"}
+
+// Recording is a captured request/response pair as stored on disk.
+type Recording struct {
+	Match    []string         `json:"match,omitempty" yaml:"match,omitempty"`
+	Request  RecordedRequest  `json:"request" yaml:"request"`
+	Response RecordedResponse `json:"response" yaml:"response"`
+	file     string
+}
+
+type RecordedRequest struct {
+	Method     string              `json:"method" yaml:"method"`
+	Path       string              `json:"path" yaml:"path"`
+	Query      map[string][]string `json:"query,omitempty" yaml:"query,omitempty"`
+	Headers    map[string]string   `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body       interface{}         `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyBase64 string              `json:"bodyBase64,omitempty" yaml:"bodyBase64,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status     int               `json:"status" yaml:"status"`
+	Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body       interface{}       `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyBase64 string            `json:"bodyBase64,omitempty" yaml:"bodyBase64,omitempty"`
+}
+
+type RecordConfig struct {
+	Upstream string
+	Dir      string
+	// RedactHeaders lists header names whose values are replaced in both
+	// the recorded request and response.
+	RedactHeaders []string
+	// RedactFields lists JSON object keys redacted at any depth in bodies.
+	RedactFields []string
+	MatchKeys    []string
+}
+
+// Recorder is a reverse proxy that saves every exchange as a Recording file.
+type Recorder struct {
+	cfg      RecordConfig
+	upstream *url.URL
+	client   *http.Client
+	mu       sync.Mutex
+	seq      int
+}
+
+var hopHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Content-Length":      true,
+}
+
+func NewRecorder(cfg RecordConfig) (*Recorder, error) {
+	upstream, err := url.Parse(cfg.Upstream)
+	if err != nil || upstream.Scheme == "" || upstream.Host == "" {
+		return nil, fmt.Errorf("invalid upstream: %q", cfg.Upstream)
+	}
+	if len(cfg.MatchKeys) == 0 {
+		cfg.MatchKeys = DefaultMatchKeys
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+
+	existing, err := recordingFiles(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	// Numbering continues after the highest existing prefix, so a deleted
+	// recording never causes a later one to reuse a number.
+	seq := 0
+	for _, file := range existing {
+		if n := recordingSeq(file); n > seq {
+			seq = n
+		}
+	}
+
+	return &Recorder{
+		cfg:      cfg,
+		upstream: upstream,
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		seq: seq,
+	}, nil
+}
+
+func (rc *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("read body: %v", err))
+		return
+	}
+
+	target := *rc.upstream
+	target.Path = strings.TrimSuffix(target.Path, "/") + r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+
+	out, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), bytes.NewReader(reqBody))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("create request: %v", err))
+		return
+	}
+	for k, vs := range r.Header {
+		if hopHeaders[http.CanonicalHeaderKey(k)] || strings.EqualFold(k, "Accept-Encoding") {
+			continue
+		}
+		out.Header[k] = vs
+	}
+
+	resp, err := rc.client.Do(out)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("upstream: %v", err))
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("read upstream body: %v", err))
+		return
+	}
+
+	for k, vs := range resp.Header {
+		if hopHeaders[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		w.Header()[k] = vs
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(respBody)
+
+	rec := Recording{
+		Match: rc.cfg.MatchKeys,
+		Request: RecordedRequest{
+			Method:  r.Method,
+			Path:    r.URL.Path,
+			Query:   r.URL.Query(),
+			Headers: flattenHeaders(r.Header, rc.cfg.RedactHeaders),
+		},
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: flattenHeaders(resp.Header, rc.cfg.RedactHeaders),
+		},
+	}
+	if len(rec.Request.Query) == 0 {
+		rec.Request.Query = nil
+	}
+	rec.Request.Body, rec.Request.BodyBase64 = encodeBody(reqBody, rc.cfg.RedactFields)
+	rec.Response.Body, rec.Response.BodyBase64 = encodeBody(respBody, rc.cfg.RedactFields)
+
+	if err := rc.save(&rec); err != nil {
+		slog.Error("save recording", "error", err)
+	}
+}
+
+func (rc *Recorder) save(rec *Recording) error {
+	data, err := yaml.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal recording: %w", err)
+	}
+
+	for {
+		rc.mu.Lock()
+		rc.seq++
+		name := fmt.Sprintf("%04d-%s%s.yaml", rc.seq, strings.ToLower(rec.Request.Method), slugify(rec.Request.Path))
+		rc.mu.Unlock()
+
+		// O_EXCL keeps a file written by something else from being
+		// overwritten; its number is skipped instead.
+		file := filepath.Join(rc.cfg.Dir, name)
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("create recording: %w", err)
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("write recording: %w", err)
+		}
+
+		rec.file = file
+		slog.Info("recorded", "method", rec.Request.Method, "path", rec.Request.Path, "file", rec.file)
+		return nil
+	}
+}
+
+// LoadRecordings reads every recording in dir, ordered by file name.
+func LoadRecordings(dir string) ([]Recording, error) {
+	files, err := recordingFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	recs := make([]Recording, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read recording: %w", err)
+		}
+		var rec Recording
+		if err := yaml.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("unmarshal %s: %w", file, err)
+		}
+		if len(rec.Match) == 0 {
+			rec.Match = DefaultMatchKeys
+		}
+		rec.file = file
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// parseRecording reports whether data holds a single recording rather than
+// a config with endpoints.
+func parseRecording(data []byte) (*Recording, bool) {
+	var probe struct {
+		Endpoints []yaml.Node `yaml:"endpoints"`
+	}
+	var rec Recording
+	if yaml.Unmarshal(data, &probe) != nil || len(probe.Endpoints) > 0 {
+		return nil, false
+	}
+	if yaml.Unmarshal(data, &rec) != nil || rec.Request.Path == "" {
+		return nil, false
+	}
+	if len(rec.Match) == 0 {
+		rec.Match = DefaultMatchKeys
+	}
+	return &rec, true
+}
+
+// Endpoint converts the recording into a mock endpoint config. Headers
+// named in Match and plain-text bodies become exact matchers; the query
+// string and JSON bodies are not matched, so use a Replayer when those
+// must tell recordings apart.
+func (rec *Recording) Endpoint() EndpointConfig {
+	ec := EndpointConfig{Method: rec.Request.Method, Path: rec.Request.Path}
+
+	match := &MatchConfig{}
+	for _, key := range rec.Match {
+		switch {
+		case key == "body":
+			if body, ok := rec.Request.Body.(string); ok && body != "" && body != Redacted {
+				match.Body = "^" + regexp.QuoteMeta(body) + "$"
+			}
+		case strings.HasPrefix(key, "header:"):
+			name := strings.TrimPrefix(key, "header:")
+			if v := headerValue(rec.Request.Headers, name); v != Redacted {
+				if match.Headers == nil {
+					match.Headers = map[string]string{}
+				}
+				match.Headers[name] = "^" + regexp.QuoteMeta(v) + "$"
+			}
+		}
+	}
+	if match.Body != "" || len(match.Headers) > 0 {
+		ec.Match = match
+	}
+
+	ec.Response = ResponseConfig{Status: rec.Response.Status, Body: rec.Response.Body}
+	if rec.Response.BodyBase64 != "" {
+		ec.Response.Body = string(decodeBody(nil, rec.Response.BodyBase64))
+	}
+	for k, v := range rec.Response.Headers {
+		if v == Redacted || hopHeaders[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		if ec.Response.Headers == nil {
+			ec.Response.Headers = map[string]string{}
+		}
+		ec.Response.Headers[k] = v
+	}
+	return ec
+}
+
+// recordingEndpoints converts recordings into endpoints. Recordings that
+// map to the same method, path and matchers become one endpoint replaying
+// their responses in order and repeating the last, as a Replayer would.
+func recordingEndpoints(recs []Recording) []EndpointConfig {
+	var out []EndpointConfig
+	index := map[string]int{}
+	for i := range recs {
+		ec := recs[i].Endpoint()
+		m, _ := json.Marshal(ec.Match)
+		key := ec.Method + " " + ec.Path + " " + string(m)
+		j, seen := index[key]
+		if !seen {
+			index[key] = len(out)
+			out = append(out, ec)
+			continue
+		}
+		prev := &out[j]
+		if len(prev.Sequence) == 0 {
+			prev.Sequence = []ResponseConfig{prev.Response}
+			prev.Response = ResponseConfig{}
+		}
+		prev.Sequence = append(prev.Sequence, ec.Response)
+	}
+	return out
+}
+
+type ReplayConfig struct {
+	// Lenient only requires method and path to match and picks the
+	// recording agreeing on the most remaining match keys.
+	Lenient bool
+}
+
+// UnmatchedRequest describes a replayed request no recording answered.
+type UnmatchedRequest struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	Path    string    `json:"path"`
+	Query   string    `json:"query,omitempty"`
+	Closest string    `json:"closest,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// Replayer serves recorded responses. Recordings sharing the same request
+// are played back in order, repeating the last one.
+type Replayer struct {
+	cfg     ReplayConfig
+	mu      sync.Mutex
+	entries []*replayEntry
+	// unmatched is a ring buffer of the latest maxUnmatched requests.
+	unmatched []UnmatchedRequest
+	start     int
+	dropped   int
+}
+
+// maxUnmatched bounds the unmatched requests a Replayer keeps.
+const maxUnmatched = DefaultJournalSize
+
+type replayEntry struct {
+	rec  Recording
+	hits int
+}
+
+func NewReplayer(recs []Recording, cfg ReplayConfig) *Replayer {
+	rp := &Replayer{cfg: cfg}
+	for _, rec := range recs {
+		rp.entries = append(rp.entries, &replayEntry{rec: rec})
+	}
+	return rp
+}
+
+// Unmatched returns the latest requests that could not be answered, oldest
+// first.
+func (rp *Replayer) Unmatched() []UnmatchedRequest {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	out := make([]UnmatchedRequest, 0, len(rp.unmatched))
+	out = append(out, rp.unmatched[rp.start:]...)
+	return append(out, rp.unmatched[:rp.start]...)
+}
+
+// Dropped reports how many unmatched requests were evicted because the
+// buffer was full.
+func (rp *Replayer) Dropped() int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.dropped
+}
+
+// addUnmatched records um, evicting the oldest entry when full. Caller
+// holds rp.mu.
+func (rp *Replayer) addUnmatched(um UnmatchedRequest) {
+	if len(rp.unmatched) < maxUnmatched {
+		rp.unmatched = append(rp.unmatched, um)
+		return
+	}
+	rp.unmatched[rp.start] = um
+	rp.start = (rp.start + 1) % maxUnmatched
+	rp.dropped++
+}
+
+func (rp *Replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == AdminPrefix+"/unmatched" {
+		writeJSON(w, http.StatusOK, rp.Unmatched())
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("read body: %v", err))
+		return
+	}
+
+	rp.mu.Lock()
+	entry, closest, reason := rp.match(r, body)
+	if entry == nil {
+		um := UnmatchedRequest{
+			Time:   time.Now(),
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Reason: reason,
+		}
+		if closest != nil {
+			um.Closest = closest.rec.file
+		}
+		rp.addUnmatched(um)
+		rp.mu.Unlock()
+
+		slog.Warn("unmatched request", "method", r.Method, "path", r.URL.Path, "reason", reason)
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "no recording matches request", "unmatched": um})
+		return
+	}
+	entry.hits++
+	resp := entry.rec.Response
+	rp.mu.Unlock()
+
+	for k, v := range resp.Headers {
+		if v == Redacted || hopHeaders[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		w.Header().Set(k, v)
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(decodeBody(resp.Body, resp.BodyBase64))
+}
+
+// match returns the recording to serve or, when none qualifies, the closest
+// candidate and a reason. Caller holds rp.mu.
+func (rp *Replayer) match(r *http.Request, body []byte) (*replayEntry, *replayEntry, string) {
+	var candidates []*replayEntry
+	var closest *replayEntry
+	bestScore := -1
+	reason := "no recording for method and path"
+
+	for _, e := range rp.entries {
+		if !recordedEqual(e.rec.Request.Method, r.Method) || !recordedEqual(e.rec.Request.Path, r.URL.Path) {
+			continue
+		}
+
+		score, mismatch := e.rec.score(r, body)
+		if mismatch == "" || rp.cfg.Lenient {
+			if score > bestScore {
+				candidates, bestScore = nil, score
+			}
+			if score == bestScore {
+				candidates = append(candidates, e)
+			}
+			continue
+		}
+		if closest == nil {
+			closest, reason = e, mismatch+" differs"
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, closest, reason
+	}
+
+	for _, e := range candidates {
+		if e.hits == 0 {
+			return e, nil, ""
+		}
+	}
+	return candidates[len(candidates)-1], nil, ""
+}
+
+// score counts how many of the recording's match keys agree with r, beyond
+// method and path, and names the first key that does not.
+func (rec *Recording) score(r *http.Request, body []byte) (int, string) {
+	score := 0
+	mismatch := ""
+	for _, key := range rec.Match {
+		var ok bool
+		switch {
+		case key == "method" || key == "path":
+			continue
+		case key == "query":
+			ok = queryEqual(rec.Request.Query, r.URL.Query())
+		case key == "body":
+			ok = bodyEqual(decodeBody(rec.Request.Body, rec.Request.BodyBase64), body)
+		case strings.HasPrefix(key, "header:"):
+			name := strings.TrimPrefix(key, "header:")
+			ok = recordedEqual(headerValue(rec.Request.Headers, name), r.Header.Get(name))
+		default:
+			continue
+		}
+		if ok {
+			score++
+		} else if mismatch == "" {
+			mismatch = key
+		}
+	}
+	return score, mismatch
+}
+
+func recordedEqual(recorded, actual string) bool {
+	return recorded == Redacted || recorded == actual
+}
+
+func headerValue(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+func queryEqual(recorded map[string][]string, actual url.Values) bool {
+	if len(recorded) != len(actual) {
+		return false
+	}
+	for k, want := range recorded {
+		got := actual[k]
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range want {
+			if !recordedEqual(want[i], got[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func bodyEqual(recorded, actual []byte) bool {
+	if bytes.Equal(recorded, actual) {
+		return true
+	}
+
+	var want, got interface{}
+	if json.Unmarshal(recorded, &want) != nil || json.Unmarshal(actual, &got) != nil {
+		return false
+	}
+	return jsonEqual(want, got)
+}
+
+func jsonEqual(want, got interface{}) bool {
+	if s, ok := want.(string); ok && s == Redacted {
+		return true
+	}
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for k, v := range w {
+			gv, ok := g[k]
+			if !ok || !jsonEqual(v, gv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !jsonEqual(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(want, got)
+	}
+}
+
+func flattenHeaders(h http.Header, redact []string) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, vs := range h {
+		if hopHeaders[http.CanonicalHeaderKey(k)] {
+			continue
+		}
+		out[k] = strings.Join(vs, ", ")
+		for _, name := range redact {
+			if strings.EqualFold(k, name) {
+				out[k] = Redacted
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// encodeBody stores JSON bodies structurally, text verbatim and anything
+// else as base64.
+func encodeBody(body []byte, redactFields []string) (interface{}, string) {
+	if len(body) == 0 {
+		return nil, ""
+	}
+
+	var v interface{}
+	if json.Unmarshal(body, &v) == nil {
+		if _, isString := v.(string); !isString {
+			return redactJSON(v, redactFields), ""
+		}
+	}
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return nil, base64.StdEncoding.EncodeToString(body)
+}
+
+func decodeBody(body interface{}, b64 string) []byte {
+	if b64 != "" {
+		data, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil
+		}
+		return data
+	}
+
+	switch v := body.(type) {
+	case nil:
+		return nil
+	case string:
+		return []byte(v)
+	default:
+		data, err := json.Marshal(normalizeYAML(v))
+		if err != nil {
+			return nil
+		}
+		return data
+	}
+}
+
+func redactJSON(v interface{}, fields []string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			redacted := false
+			for _, f := range fields {
+				if strings.EqualFold(k, f) {
+					val[k] = Redacted
+					redacted = true
+					break
+				}
+			}
+			if !redacted {
+				val[k] = redactJSON(item, fields)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactJSON(item, fields)
+		}
+	}
+	return v
+}
+
+// normalizeYAML returns a copy of v with any map[interface{}]interface{}
+// values yaml can produce converted into JSON-marshalable maps.
+func normalizeYAML(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = normalizeYAML(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = normalizeYAML(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+var slugRe = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+func slugify(path string) string {
+	slug := strings.Trim(slugRe.ReplaceAllString(path, "-"), "-")
+	if slug == "" {
+		return ""
+	}
+	if len(slug) > 60 {
+		slug = slug[:60]
+	}
+	return "-" + strings.ToLower(slug)
+}
+
+func recordingFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read directory: %w", err)
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// recordingSeq returns the number a recording file name starts with, or 0.
+func recordingSeq(file string) int {
+	name := filepath.Base(file)
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(name[:end])
+	return n
+}
@@ -0,0 +1,173 @@
+package mock_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `","page":"` + r.URL.Query().Get("page") + `","token":"abc"}`))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	recorder, err := mock.NewRecorder(mock.RecordConfig{
+		Upstream:      upstream.URL,
+		Dir:           dir,
+		RedactHeaders: []string{"Authorization", "Set-Cookie"},
+		RedactFields:  []string{"token"},
+	})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	req, _ := http.NewRequest("GET", proxy.URL+"/api/users?page=1", nil)
+	req.Header.Set("Authorization", "Bearer real-token")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("proxy request: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(body), `"token":"abc"`) {
+		t.Fatalf("client should receive the unredacted upstream body, got %s", body)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 recording, got %d", len(files))
+	}
+	saved, _ := os.ReadFile(files[0])
+	if strings.Contains(string(saved), "real-token") || strings.Contains(string(saved), "abc") || strings.Contains(string(saved), "session=secret") {
+		t.Fatalf("recording was not redacted:\n%s", saved)
+	}
+
+	recs, err := mock.LoadRecordings(dir)
+	if err != nil {
+		t.Fatalf("LoadRecordings: %v", err)
+	}
+
+	strict := httptest.NewServer(mock.NewReplayer(recs, mock.ReplayConfig{}))
+	defer strict.Close()
+
+	res, _ = http.Get(strict.URL + "/api/users?page=1")
+	body, _ = io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != 200 || !strings.Contains(string(body), `"page":"1"`) {
+		t.Fatalf("strict replay: status %d body %s", res.StatusCode, body)
+	}
+
+	res, _ = http.Get(strict.URL + "/api/users?page=2")
+	res.Body.Close()
+	if res.StatusCode != 404 {
+		t.Fatalf("strict replay with different query: expected 404 got %d", res.StatusCode)
+	}
+
+	replayer := mock.NewReplayer(recs, mock.ReplayConfig{Lenient: true})
+	lenient := httptest.NewServer(replayer)
+	defer lenient.Close()
+
+	res, _ = http.Get(lenient.URL + "/api/users?page=2")
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		t.Fatalf("lenient replay: expected 200 got %d", res.StatusCode)
+	}
+
+	res, _ = http.Get(lenient.URL + "/api/orders")
+	res.Body.Close()
+	unmatched := replayer.Unmatched()
+	if res.StatusCode != 404 || len(unmatched) != 1 || unmatched[0].Path != "/api/orders" {
+		t.Fatalf("expected /api/orders to be reported unmatched, got %d %+v", res.StatusCode, unmatched)
+	}
+}
+
+func TestRecorder_ServeAsConfig(t *testing.T) {
+	calls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `}`))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	record := func() {
+		recorder, err := mock.NewRecorder(mock.RecordConfig{Upstream: upstream.URL, Dir: dir})
+		if err != nil {
+			t.Fatalf("NewRecorder: %v", err)
+		}
+		w := httptest.NewRecorder()
+		recorder.ServeHTTP(w, httptest.NewRequest("GET", "/api/status", nil))
+	}
+	record()
+	record()
+	record()
+
+	// Deleting a recording must not make the next one reuse a number.
+	files, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if len(files) != 3 {
+		t.Fatalf("expected 3 recordings, got %v", files)
+	}
+	os.Remove(files[1])
+	record()
+	files, _ = filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if len(files) != 3 || !strings.HasPrefix(filepath.Base(files[2]), "0004-") {
+		t.Fatalf("expected the new recording to be numbered 0004, got %v", files)
+	}
+	saved, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(saved), "call: 1") {
+		t.Fatalf("first recording was overwritten:\n%s", saved)
+	}
+
+	cfg, err := mock.LoadConfig(files[0])
+	if err != nil || len(cfg.Endpoints) != 1 || cfg.Endpoints[0].Path != "/api/status" {
+		t.Fatalf("LoadConfig(recording) = %+v, %v", cfg, err)
+	}
+
+	server, err := mock.LoadServer(dir, "")
+	if err != nil {
+		t.Fatalf("LoadServer(dir): %v", err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// Recordings of the same request play back in order, repeating the last.
+	for _, want := range []string{`{"call":1}`, `{"call":3}`, `{"call":4}`, `{"call":4}`} {
+		res, err := http.Get(ts.URL + "/api/status")
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != 200 || string(body) != want {
+			t.Fatalf("expected %s, got %d %s", want, res.StatusCode, body)
+		}
+	}
+}
+
+func TestReplayer_UnmatchedIsBounded(t *testing.T) {
+	replayer := mock.NewReplayer(nil, mock.ReplayConfig{})
+	for i := 0; i < mock.DefaultJournalSize+5; i++ {
+		replayer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/miss/"+strconv.Itoa(i), nil))
+	}
+	unmatched := replayer.Unmatched()
+	if len(unmatched) != mock.DefaultJournalSize || replayer.Dropped() != 5 {
+		t.Fatalf("expected %d kept and 5 dropped, got %d and %d", mock.DefaultJournalSize, len(unmatched), replayer.Dropped())
+	}
+	if unmatched[0].Path != "/miss/5" || unmatched[len(unmatched)-1].Path != "/miss/1004" {
+		t.Fatalf("expected the oldest entries to be evicted, got %s..%s", unmatched[0].Path, unmatched[len(unmatched)-1].Path)
+	}
+}
@@ -0,0 +1,380 @@
+package mock
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	IDTypeInt  = "int"
+	IDTypeUUID = "uuid"
+)
+
+// Resource is a stateful REST collection mounted at Path. It serves
+// list/get/create/update/patch/delete from an in-memory store that can be
+// reset to its seed data at any time.
+type Resource struct {
+	// ID is assigned by the server and used by the admin API.
+	ID      string
+	Path    string
+	IDField string
+	IDType  string
+	Faults  *FaultProfile
+
+	mu     sync.Mutex
+	seed   []map[string]interface{}
+	items  []map[string]interface{}
+	nextID int64
+}
+
+func NewResource(path string, seed []map[string]interface{}) *Resource {
+	res := &Resource{
+		Path:    strings.TrimSuffix(path, "/"),
+		IDField: "id",
+		IDType:  IDTypeInt,
+		seed:    seed,
+	}
+	res.Reset()
+	return res
+}
+
+// LoadSeed reads a JSON array of objects used as the initial resource state.
+func LoadSeed(path string) ([]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read seed: %w", err)
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("unmarshal seed: %w", err)
+	}
+	return items, nil
+}
+
+// Reset discards all changes and restores the seed data.
+func (res *Resource) Reset() {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	res.items = make([]map[string]interface{}, 0, len(res.seed))
+	res.nextID = 1
+	for _, item := range res.seed {
+		res.items = append(res.items, deepCopy(item).(map[string]interface{}))
+		res.observeID(item[res.IDField])
+	}
+}
+
+// Items returns a snapshot of the current store contents.
+func (res *Resource) Items() []map[string]interface{} {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	out := make([]map[string]interface{}, len(res.items))
+	for i, item := range res.items {
+		out[i] = deepCopy(item).(map[string]interface{})
+	}
+	return out
+}
+
+func (res *Resource) itemID(path string) (string, bool) {
+	if path == res.Path || path == res.Path+"/" {
+		return "", true
+	}
+	id, ok := strings.CutPrefix(path, res.Path+"/")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
+
+func (res *Resource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, ok := res.itemID(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if id == "" {
+		switch r.Method {
+		case http.MethodGet:
+			res.list(w, r)
+		case http.MethodPost:
+			res.create(w, r)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		res.get(w, id)
+	case http.MethodPut:
+		res.update(w, r, id, false)
+	case http.MethodPatch:
+		res.update(w, r, id, true)
+	case http.MethodDelete:
+		res.delete(w, id)
+	default:
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (res *Resource) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := intParam(query.Get("page"), 1)
+	if err != nil || page < 1 {
+		writeError(w, http.StatusBadRequest, "invalid page")
+		return
+	}
+	limit, err := intParam(query.Get("limit"), 0)
+	if err != nil || limit < 0 {
+		writeError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	res.mu.Lock()
+	matched := make([]map[string]interface{}, 0, len(res.items))
+	for _, item := range res.items {
+		if matchesFilters(item, query) {
+			matched = append(matched, deepCopy(item).(map[string]interface{}))
+		}
+	}
+	res.mu.Unlock()
+
+	total := len(matched)
+	if limit > 0 {
+		// Compared before multiplying so huge values cannot overflow.
+		start := total
+		if page-1 <= total/limit {
+			start = min((page-1)*limit, total)
+		}
+		end := total
+		if limit < total-start {
+			end = start + limit
+		}
+		matched = matched[start:end]
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	writeJSON(w, http.StatusOK, matched)
+}
+
+func (res *Resource) get(w http.ResponseWriter, id string) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	idx := res.indexOf(id)
+	if idx < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, res.items[idx])
+}
+
+func (res *Resource) create(w http.ResponseWriter, r *http.Request) {
+	item, err := decodeObject(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	if id, ok := item[res.IDField]; ok && id != nil {
+		if res.indexOf(fmt.Sprint(id)) >= 0 {
+			writeError(w, http.StatusConflict, fmt.Sprintf("%v already exists", id))
+			return
+		}
+		res.observeID(id)
+	} else {
+		item[res.IDField] = res.generateID()
+	}
+
+	res.items = append(res.items, item)
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%v", res.Path, item[res.IDField]))
+	writeJSON(w, http.StatusCreated, item)
+}
+
+func (res *Resource) update(w http.ResponseWriter, r *http.Request, id string, merge bool) {
+	patch, err := decodeObject(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	idx := res.indexOf(id)
+	if idx < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found", id))
+		return
+	}
+
+	current := res.items[idx]
+	if newID, ok := patch[res.IDField]; ok && fmt.Sprint(newID) != id {
+		writeError(w, http.StatusConflict, fmt.Sprintf("cannot change %s from %s to %v", res.IDField, id, newID))
+		return
+	}
+
+	item := patch
+	if merge {
+		item = current
+		for k, v := range patch {
+			item[k] = v
+		}
+	}
+	item[res.IDField] = current[res.IDField]
+	res.items[idx] = item
+
+	writeJSON(w, http.StatusOK, item)
+}
+
+// decodeObject decodes a request body that must be a JSON object.
+func decodeObject(r *http.Request) (map[string]interface{}, error) {
+	var item map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		return nil, fmt.Errorf("decode body: %w", err)
+	}
+	if item == nil {
+		return nil, fmt.Errorf("decode body: expected a JSON object")
+	}
+	return item, nil
+}
+
+func (res *Resource) delete(w http.ResponseWriter, id string) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	idx := res.indexOf(id)
+	if idx < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found", id))
+		return
+	}
+
+	res.items = append(res.items[:idx], res.items[idx+1:]...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (res *Resource) indexOf(id string) int {
+	for i, item := range res.items {
+		if v, ok := item[res.IDField]; ok && fmt.Sprint(v) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (res *Resource) generateID() interface{} {
+	if res.IDType == IDTypeUUID {
+		return newUUID()
+	}
+
+	id := res.nextID
+	res.nextID++
+	return id
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// observeID keeps nextID ahead of any numeric id already in the store.
+func (res *Resource) observeID(v interface{}) {
+	var n int64
+	switch id := v.(type) {
+	case float64:
+		n = int64(id)
+	case int:
+		n = int64(id)
+	case int64:
+		n = id
+	case string:
+		parsed, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return
+		}
+		n = parsed
+	default:
+		return
+	}
+	if n >= res.nextID {
+		res.nextID = n + 1
+	}
+}
+
+func matchesFilters(item map[string]interface{}, query map[string][]string) bool {
+	for field, values := range query {
+		if field == "page" || field == "limit" {
+			continue
+		}
+		v, ok := item[field]
+		if !ok {
+			return false
+		}
+		matched := false
+		for _, want := range values {
+			if fmt.Sprint(v) == want {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func intParam(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = deepCopy(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = deepCopy(item)
+		}
+		return out
+	default:
+		return val
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
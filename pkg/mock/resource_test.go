@@ -0,0 +1,102 @@
+package mock_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+)
+
+func TestResource_CRUD(t *testing.T) {
+	srv := mock.NewServer()
+	srv.AddResource(mock.NewResource("/api/users", []map[string]interface{}{
+		{"id": float64(1), "name": "Alice", "role": "admin"},
+		{"id": float64(2), "name": "Bob", "role": "member"},
+	}))
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	do := func(method, path, body string) (*http.Response, map[string]interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer res.Body.Close()
+		var out map[string]interface{}
+		json.NewDecoder(res.Body).Decode(&out)
+		return res, out
+	}
+
+	res, created := do("POST", "/api/users", `{"name":"Carol","role":"member"}`)
+	if res.StatusCode != 201 || created["id"] != float64(3) {
+		t.Fatalf("create: status %d body %v", res.StatusCode, created)
+	}
+
+	if res, _ := do("POST", "/api/users", `{"id":1,"name":"Dup"}`); res.StatusCode != 409 {
+		t.Fatalf("expected 409 for duplicate id, got %d", res.StatusCode)
+	}
+
+	for _, method := range []string{"POST", "PUT", "PATCH"} {
+		path := "/api/users"
+		if method != "POST" {
+			path += "/2"
+		}
+		for _, body := range []string{"null", "[1]", "3"} {
+			if res, _ := do(method, path, body); res.StatusCode != 400 {
+				t.Fatalf("%s %s: expected 400 got %d", method, body, res.StatusCode)
+			}
+		}
+	}
+
+	if res, got := do("PATCH", "/api/users/3", `{"role":"admin"}`); res.StatusCode != 200 || got["name"] != "Carol" || got["role"] != "admin" {
+		t.Fatalf("patch: status %d body %v", res.StatusCode, got)
+	}
+
+	res, err := http.Get(ts.URL + "/api/users?role=admin&limit=1&page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page []map[string]interface{}
+	json.NewDecoder(res.Body).Decode(&page)
+	res.Body.Close()
+	if res.Header.Get("X-Total-Count") != "2" || len(page) != 1 || page[0]["name"] != "Carol" {
+		t.Fatalf("list: total %s page %v", res.Header.Get("X-Total-Count"), page)
+	}
+
+	// Pages far past the end are empty rather than overflowing.
+	for _, q := range []string{"page=4611686018427387905&limit=3", "page=2&limit=9223372036854775807"} {
+		res, err := http.Get(ts.URL + "/api/users?" + q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page = nil
+		json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if res.StatusCode != 200 || len(page) != 0 {
+			t.Fatalf("list %s: status %d page %v", q, res.StatusCode, page)
+		}
+	}
+
+	if res, _ := do("DELETE", "/api/users/1", ""); res.StatusCode != 204 {
+		t.Fatalf("delete: expected 204 got %d", res.StatusCode)
+	}
+	if res, _ := do("GET", "/api/users/1", ""); res.StatusCode != 404 {
+		t.Fatalf("get deleted: expected 404 got %d", res.StatusCode)
+	}
+
+	if res, _ := do("POST", mock.AdminPrefix+"/reset", ""); res.StatusCode != 204 {
+		t.Fatalf("reset: expected 204 got %d", res.StatusCode)
+	}
+	if res, got := do("GET", "/api/users/1", ""); res.StatusCode != 200 || got["name"] != "Alice" {
+		t.Fatalf("after reset: status %d body %v", res.StatusCode, got)
+	}
+	if res, _ := do("GET", "/api/users/3", ""); res.StatusCode != 404 {
+		t.Fatalf("after reset: expected created user to be gone, got %d", res.StatusCode)
+	}
+}
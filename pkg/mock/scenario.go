@@ -0,0 +1,304 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ScenarioStarted is the state every scenario begins in unless it sets
+// InitialState.
+const ScenarioStarted = "started"
+
+// Scenario is a named state machine shared by the endpoints bound to it.
+// Endpoints only match while the scenario is in their State and move it to
+// NewState after responding, so one URL can answer differently over time.
+type Scenario struct {
+	Name         string `json:"name" yaml:"name"`
+	InitialState string `json:"initialState,omitempty" yaml:"initialState,omitempty"`
+	// IsolateBy names a request header; each distinct value gets its own
+	// state so parallel clients do not interfere.
+	IsolateBy string `json:"isolateBy,omitempty" yaml:"isolateBy,omitempty"`
+
+	mu     sync.Mutex
+	states map[string]string
+	// positions tracks sequence progress per endpoint and client.
+	positions map[string]int
+}
+
+// ScenarioBinding ties an endpoint to a scenario.
+type ScenarioBinding struct {
+	Name string
+	// State is required for the endpoint to match; empty matches any state.
+	State string
+	// NewState, if set, is entered after the endpoint responds.
+	NewState string
+}
+
+func NewScenario(name string) *Scenario {
+	return &Scenario{Name: name}
+}
+
+func (sc *Scenario) initial() string {
+	if sc.InitialState != "" {
+		return sc.InitialState
+	}
+	return ScenarioStarted
+}
+
+func (sc *Scenario) clientKey(r *http.Request) string {
+	if sc.IsolateBy == "" {
+		return ""
+	}
+	return r.Header.Get(sc.IsolateBy)
+}
+
+// State returns the current state for client, the IsolateBy header value
+// or empty when the scenario is not isolated.
+func (sc *Scenario) State(client string) string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if state, ok := sc.states[client]; ok {
+		return state
+	}
+	return sc.initial()
+}
+
+func (sc *Scenario) SetState(client, state string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.states == nil {
+		sc.states = make(map[string]string)
+	}
+	sc.states[client] = state
+}
+
+// transition moves client from one state to another in a single step. It
+// fails, changing nothing, if from is set and client is in another state;
+// an empty to leaves the state as it is.
+func (sc *Scenario) transition(client, from, to string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	current, ok := sc.states[client]
+	if !ok {
+		current = sc.initial()
+	}
+	if from != "" && current != from {
+		return false
+	}
+	if to != "" {
+		if sc.states == nil {
+			sc.states = make(map[string]string)
+		}
+		sc.states[client] = to
+	}
+	return true
+}
+
+// States returns the state of every client that has left the initial state.
+func (sc *Scenario) States() map[string]string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	out := make(map[string]string, len(sc.states))
+	for client, state := range sc.states {
+		out[client] = state
+	}
+	return out
+}
+
+// Reset returns client to the initial state and restarts its sequences.
+func (sc *Scenario) Reset(client string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	delete(sc.states, client)
+	for key := range sc.positions {
+		if strings.HasSuffix(key, "\x00"+client) {
+			delete(sc.positions, key)
+		}
+	}
+}
+
+// ResetAll returns every client to the initial state.
+func (sc *Scenario) ResetAll() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.states = nil
+	sc.positions = nil
+}
+
+// next returns the sequence index to serve for endpoint id and advances it,
+// stopping at the last entry.
+func (sc *Scenario) next(id, client string, n int) int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.positions == nil {
+		sc.positions = make(map[string]int)
+	}
+	return advance(sc.positions, id+"\x00"+client, n)
+}
+
+func advance(positions map[string]int, key string, n int) int {
+	i := positions[key]
+	if i < n-1 {
+		positions[key] = i + 1
+	}
+	return min(i, n-1)
+}
+
+// AddScenario registers sc, replacing a scenario with the same name.
+// Endpoints bound to an unknown scenario create a default one.
+func (s *Server) AddScenario(sc *Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scenarios[sc.Name] = sc
+}
+
+func (s *Server) Scenario(name string) *Scenario {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.scenarios[name]
+}
+
+// Scenarios lists registered scenarios by name.
+func (s *Server) Scenarios() []*Scenario {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Scenario, 0, len(s.scenarios))
+	for _, sc := range s.scenarios {
+		out = append(out, sc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// scenarioOf returns the scenario e is bound to, if any. Caller holds s.mu.
+func (s *Server) scenarioOf(e *Endpoint) *Scenario {
+	if e.Scenario == nil {
+		return nil
+	}
+	return s.scenarios[e.Scenario.Name]
+}
+
+// inState reports whether r's client is in the state e requires. Caller
+// holds s.mu.
+func (s *Server) inState(e *Endpoint, r *http.Request) bool {
+	if e.Scenario == nil || e.Scenario.State == "" {
+		return true
+	}
+	sc := s.scenarioOf(e)
+	return sc != nil && sc.State(sc.clientKey(r)) == e.Scenario.State
+}
+
+// respond picks the response for e, advancing its sequence and moving its
+// scenario to the next state. It reports false, changing nothing, if r's
+// client has left the state e requires since e was matched.
+func (s *Server) respond(e *Endpoint, sc *Scenario, r *http.Request) (Response, bool) {
+	client := ""
+	if sc != nil {
+		client = sc.clientKey(r)
+		if !sc.transition(client, e.Scenario.State, e.Scenario.NewState) {
+			return Response{}, false
+		}
+	}
+
+	if len(e.Sequence) == 0 {
+		return e.Response, true
+	}
+	if sc != nil {
+		return e.Sequence[sc.next(e.ID, client, len(e.Sequence))], true
+	}
+
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+	return e.Sequence[advance(s.sequences, e.ID, len(e.Sequence))], true
+}
+
+type scenarioView struct {
+	Name         string            `json:"name"`
+	InitialState string            `json:"initialState"`
+	IsolateBy    string            `json:"isolateBy,omitempty"`
+	State        string            `json:"state"`
+	Clients      map[string]string `json:"clients,omitempty"`
+}
+
+func viewScenario(sc *Scenario) scenarioView {
+	v := scenarioView{
+		Name:         sc.Name,
+		InitialState: sc.initial(),
+		IsolateBy:    sc.IsolateBy,
+		State:        sc.State(""),
+	}
+	if sc.IsolateBy != "" {
+		v.Clients = sc.States()
+	}
+	return v
+}
+
+// handleScenarios serves the scenario admin routes:
+//
+//	GET  /scenarios               list scenarios and their states
+//	GET  /scenarios/{name}        one scenario; ?client= picks an isolated client
+//	PUT  /scenarios/{name}/state  {"state": "...", "client": "..."}
+//	POST /scenarios/{name}/reset  reset ?client=, or every client if omitted
+func (s *Server) handleScenarios(w http.ResponseWriter, r *http.Request, route string) {
+	if route == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		out := []scenarioView{}
+		for _, sc := range s.Scenarios() {
+			out = append(out, viewScenario(sc))
+		}
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+
+	name, action, _ := strings.Cut(route, "/")
+	sc := s.Scenario(name)
+	if sc == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("scenario %s not found", name))
+		return
+	}
+	client := r.URL.Query().Get("client")
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		v := viewScenario(sc)
+		v.State = sc.State(client)
+		writeJSON(w, http.StatusOK, v)
+	case action == "state" && r.Method == http.MethodPut:
+		var body struct {
+			State  string `json:"state"`
+			Client string `json:"client"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.State == "" {
+			writeError(w, http.StatusBadRequest, "body must be {\"state\": \"...\"}")
+			return
+		}
+		sc.SetState(body.Client, body.State)
+		writeJSON(w, http.StatusOK, viewScenario(sc))
+	case action == "reset" && r.Method == http.MethodPost:
+		if _, ok := r.URL.Query()["client"]; ok {
+			sc.Reset(client)
+		} else {
+			sc.ResetAll()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "unknown admin route")
+	}
+}
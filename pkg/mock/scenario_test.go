@@ -0,0 +1,254 @@
+package mock_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/mock"
+)
+
+const jobConfig = `
+scenarios:
+  - name: job
+    initialState: pending
+    isolateBy: X-Client-ID
+endpoints:
+  - method: POST
+    path: /jobs
+    scenario: job
+    newState: running
+    response: {status: 202}
+  - path: /jobs/1
+    scenario: job
+    state: pending
+    response: {body: pending}
+  - path: /jobs/1
+    scenario: job
+    state: running
+    sequence:
+      - {body: "running 10%"}
+      - {body: "running 90%"}
+  - method: POST
+    path: /jobs/1/finish
+    scenario: job
+    state: running
+    newState: done
+    response: {status: 204}
+  - path: /jobs/1
+    scenario: job
+    state: done
+    response: {body: done}
+  - path: /tokens
+    sequence:
+      - {status: 200, body: first}
+      - {status: 429, body: second}
+`
+
+func scenarioServer(t *testing.T) (*mock.Server, *httptest.Server) {
+	t.Helper()
+	cfg, err := mock.ParseConfig([]byte(jobConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := mock.NewServer()
+	if err := cfg.Apply(srv); err != nil {
+		t.Fatal(err)
+	}
+	return srv, httptest.NewServer(srv)
+}
+
+func call(t *testing.T, method, url, client string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, nil)
+	if client != "" {
+		req.Header.Set("X-Client-ID", client)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body)
+}
+
+func TestScenario_Transitions(t *testing.T) {
+	_, ts := scenarioServer(t)
+	defer ts.Close()
+
+	var got []string
+	_, body := call(t, "GET", ts.URL+"/jobs/1", "a")
+	got = append(got, body)
+	if status, _ := call(t, "POST", ts.URL+"/jobs", "a"); status != 202 {
+		t.Fatalf("start job: %d", status)
+	}
+	for i := 0; i < 3; i++ {
+		_, body := call(t, "GET", ts.URL+"/jobs/1", "a")
+		got = append(got, body)
+	}
+	call(t, "POST", ts.URL+"/jobs/1/finish", "a")
+	_, body = call(t, "GET", ts.URL+"/jobs/1", "a")
+	got = append(got, body)
+
+	want := []string{"pending", "running 10%", "running 90%", "running 90%", "done"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// another client has its own state
+	if _, body := call(t, "GET", ts.URL+"/jobs/1", "b"); body != "pending" {
+		t.Fatalf("client b should still be pending, got %q", body)
+	}
+}
+
+func TestScenario_SequenceRepeatsLast(t *testing.T) {
+	srv, ts := scenarioServer(t)
+	defer ts.Close()
+
+	var statuses []int
+	for i := 0; i < 3; i++ {
+		status, _ := call(t, "GET", ts.URL+"/tokens", "")
+		statuses = append(statuses, status)
+	}
+	if statuses[0] != 200 || statuses[1] != 429 || statuses[2] != 429 {
+		t.Fatalf("unexpected statuses %v", statuses)
+	}
+
+	srv.Reset()
+	if status, _ := call(t, "GET", ts.URL+"/tokens", ""); status != 200 {
+		t.Fatalf("reset should restart sequences, got %d", status)
+	}
+}
+
+func TestScenario_AdminAPI(t *testing.T) {
+	srv, ts := scenarioServer(t)
+	defer ts.Close()
+	admin := ts.URL + mock.AdminPrefix + "/scenarios/job"
+
+	req, _ := http.NewRequest("PUT", admin+"/state", strings.NewReader(`{"state":"done","client":"a"}`))
+	if res, err := http.DefaultClient.Do(req); err != nil || res.StatusCode != 200 {
+		t.Fatalf("set state: %v %v", res, err)
+	}
+	if _, body := call(t, "GET", ts.URL+"/jobs/1", "a"); body != "done" {
+		t.Fatalf("expected forced state to apply, got %q", body)
+	}
+
+	res, err := http.Get(admin + "?client=a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var view struct {
+		State   string
+		Clients map[string]string
+	}
+	json.NewDecoder(res.Body).Decode(&view)
+	res.Body.Close()
+	if view.State != "done" || view.Clients["a"] != "done" {
+		t.Fatalf("unexpected scenario view %+v", view)
+	}
+
+	http.Post(admin+"/reset?client=a", "", nil)
+	if state := srv.Scenario("job").State("a"); state != "pending" {
+		t.Fatalf("expected reset to initial state, got %s", state)
+	}
+
+	// a request in the wrong state reports the state as a near miss
+	srv.Scenario("job").SetState("c", "cancelled")
+	call(t, "GET", ts.URL+"/jobs/1", "c")
+	unmatched := srv.Journal().Unmatched()
+	if len(unmatched) != 1 || len(unmatched[0].NearMisses) == 0 || !strings.Contains(strings.Join(unmatched[0].NearMisses[0].Differences, ";"), "in state cancelled") {
+		t.Fatalf("unexpected near misses %+v", unmatched)
+	}
+}
+
+func TestScenario_TransitionsOnlyAdmittedRequests(t *testing.T) {
+	cfg, err := mock.ParseConfig([]byte(`
+scenarios:
+  - name: door
+    initialState: closed
+  - name: seat
+    initialState: free
+endpoints:
+  - method: POST
+    path: /knock
+    scenario: door
+    newState: knocked
+    faults:
+      rateLimit: {rps: 0.001, burst: 1}
+    response: {status: 204}
+  - method: POST
+    path: /seat
+    scenario: seat
+    state: free
+    newState: taken
+    delay: 20ms
+    response: {status: 201}
+  - method: POST
+    path: /seat
+    scenario: seat
+    state: taken
+    response: {status: 409}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := mock.NewServer()
+	if err := cfg.Apply(srv); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	if status, _ := call(t, "POST", ts.URL+"/knock", ""); status != 204 {
+		t.Fatalf("first knock: %d", status)
+	}
+	srv.Scenario("door").ResetAll()
+	if status, _ := call(t, "POST", ts.URL+"/knock", ""); status != 429 {
+		t.Fatalf("second knock should be rate limited, got %d", status)
+	}
+	if state := srv.Scenario("door").State(""); state != "closed" {
+		t.Fatalf("a rate limited request moved the scenario to %q", state)
+	}
+
+	// Concurrent requests all match the free state, but only one may take it.
+	statuses := make(chan int, 20)
+	for i := 0; i < cap(statuses); i++ {
+		go func() {
+			res, err := http.Post(ts.URL+"/seat", "", nil)
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			res.Body.Close()
+			statuses <- res.StatusCode
+		}()
+	}
+	counts := map[int]int{}
+	for i := 0; i < cap(statuses); i++ {
+		counts[<-statuses]++
+	}
+	if counts[201] != 1 || counts[409] != cap(statuses)-1 {
+		t.Fatalf("expected one 201 and the rest 409, got %v", counts)
+	}
+}
+
+func TestScenario_RemoveEndpoint(t *testing.T) {
+	srv, ts := scenarioServer(t)
+	defer ts.Close()
+
+	srv.RemoveEndpoint("GET", "/jobs/1")
+	if status, _ := call(t, "GET", ts.URL+"/jobs/1", "a"); status != 404 {
+		t.Fatalf("pending variant still served: %d", status)
+	}
+	call(t, "POST", ts.URL+"/jobs", "a")
+	if status, _ := call(t, "GET", ts.URL+"/jobs/1", "a"); status != 404 {
+		t.Fatalf("running variant still served: %d", status)
+	}
+	if status, _ := call(t, "POST", ts.URL+"/jobs/1/finish", "a"); status != 204 {
+		t.Fatalf("other endpoints should remain, got %d", status)
+	}
+}
@@ -0,0 +1,415 @@
+package mock
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	maxRefDepth    = 32
+	maxSchemaDepth = 8
+)
+
+// Schema is a JSON Schema object as used by OpenAPI 3.0 and 3.1.
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 SchemaType         `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	AdditionalProperties interface{}        `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	Required             []string           `yaml:"required"`
+	Enum                 []interface{}      `yaml:"enum"`
+	Example              interface{}        `yaml:"example"`
+	Default              interface{}        `yaml:"default"`
+	Nullable             bool               `yaml:"nullable"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+	Pattern              string             `yaml:"pattern"`
+	AllOf                []*Schema          `yaml:"allOf"`
+	OneOf                []*Schema          `yaml:"oneOf"`
+	AnyOf                []*Schema          `yaml:"anyOf"`
+}
+
+// SchemaType accepts both the 3.0 single type and the 3.1 list of types.
+type SchemaType []string
+
+func (t *SchemaType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var types []string
+		if err := node.Decode(&types); err != nil {
+			return err
+		}
+		*t = types
+		return nil
+	}
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	*t = SchemaType{s}
+	return nil
+}
+
+// Primary returns the first non-null type.
+func (t SchemaType) Primary() string {
+	for _, s := range t {
+		if s != "null" {
+			return s
+		}
+	}
+	return ""
+}
+
+func (t SchemaType) allows(name string) bool {
+	for _, s := range t {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+type ValidationError struct {
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+func (spec *OpenAPISpec) resolveSchema(s *Schema) *Schema {
+	for i := 0; s != nil && s.Ref != "" && i < maxRefDepth; i++ {
+		name, err := refName(s.Ref, "#/components/schemas/")
+		if err != nil {
+			return nil
+		}
+		s = spec.Components.Schemas[name]
+	}
+	return s
+}
+
+// Synthesize builds a value conforming to s, preferring declared examples,
+// defaults and enum values over generated placeholders.
+func (spec *OpenAPISpec) Synthesize(s *Schema) interface{} {
+	return spec.synthesize(s, 0)
+}
+
+func (spec *OpenAPISpec) synthesize(s *Schema, depth int) interface{} {
+	s = spec.resolveSchema(s)
+	if s == nil || depth > maxSchemaDepth {
+		return nil
+	}
+
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case len(s.AllOf) > 0:
+		merged := map[string]interface{}{}
+		for _, sub := range s.AllOf {
+			if obj, ok := spec.synthesize(sub, depth+1).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		for k, v := range spec.synthesizeProperties(s, depth) {
+			merged[k] = v
+		}
+		return merged
+	case len(s.OneOf) > 0:
+		return spec.synthesize(s.OneOf[0], depth+1)
+	case len(s.AnyOf) > 0:
+		return spec.synthesize(s.AnyOf[0], depth+1)
+	}
+
+	switch s.Type.Primary() {
+	case "string":
+		return synthesizeString(s)
+	case "integer":
+		n := 1.0
+		if s.Minimum != nil && *s.Minimum > n {
+			n = math.Ceil(*s.Minimum)
+		}
+		if s.Maximum != nil && *s.Maximum < n {
+			n = math.Floor(*s.Maximum)
+		}
+		return int64(n)
+	case "number":
+		n := 1.5
+		if s.Minimum != nil && *s.Minimum > n {
+			n = *s.Minimum
+		}
+		if s.Maximum != nil && *s.Maximum < n {
+			n = *s.Maximum
+		}
+		return n
+	case "boolean":
+		return true
+	case "array":
+		count := 1
+		if s.MinItems != nil && *s.MinItems > count {
+			count = *s.MinItems
+		}
+		if s.MaxItems != nil && *s.MaxItems < count {
+			count = *s.MaxItems
+		}
+		items := make([]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			items = append(items, spec.synthesize(s.Items, depth+1))
+		}
+		return items
+	case "object", "":
+		if s.Type.Primary() == "" && len(s.Properties) == 0 {
+			return nil
+		}
+		return spec.synthesizeProperties(s, depth)
+	}
+	return nil
+}
+
+func (spec *OpenAPISpec) synthesizeProperties(s *Schema, depth int) map[string]interface{} {
+	obj := make(map[string]interface{}, len(s.Properties))
+	for name, prop := range s.Properties {
+		obj[name] = spec.synthesize(prop, depth+1)
+	}
+	return obj
+}
+
+func synthesizeString(s *Schema) string {
+	var v string
+	switch s.Format {
+	case "date-time":
+		v = "2024-01-01T00:00:00Z"
+	case "date":
+		v = "2024-01-01"
+	case "email":
+		v = "user@example.com"
+	case "uuid":
+		v = "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "uri", "url":
+		v = "https://example.com"
+	case "hostname":
+		v = "example.com"
+	case "ipv4":
+		v = "192.0.2.1"
+	case "byte":
+		v = "c3RyaW5n"
+	default:
+		v = "string"
+	}
+	if s.MinLength != nil && len(v) < *s.MinLength {
+		v += strings.Repeat("x", *s.MinLength-len(v))
+	}
+	if s.MaxLength != nil && len(v) > *s.MaxLength {
+		v = v[:*s.MaxLength]
+	}
+	return v
+}
+
+// coerce converts raw parameter strings into the type described by s.
+func (spec *OpenAPISpec) coerce(s *Schema, raw []string) (interface{}, error) {
+	s = spec.resolveSchema(s)
+	if s == nil {
+		return raw[0], nil
+	}
+
+	if s.Type.Primary() == "array" {
+		values := raw
+		if len(raw) == 1 {
+			values = strings.Split(raw[0], ",")
+		}
+		items := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			item, err := spec.coerce(s.Items, []string{v})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	v := raw[0]
+	switch s.Type.Primary() {
+	case "integer":
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected integer, got %q", v)
+		}
+		return float64(n), nil
+	case "number":
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected number, got %q", v)
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("expected boolean, got %q", v)
+		}
+		return b, nil
+	}
+	return v, nil
+}
+
+// Validate checks a decoded JSON value against s and reports every violation.
+func (spec *OpenAPISpec) Validate(s *Schema, v interface{}, loc string) []ValidationError {
+	return spec.validate(s, v, loc, 0)
+}
+
+func (spec *OpenAPISpec) validate(s *Schema, v interface{}, loc string, depth int) []ValidationError {
+	s = spec.resolveSchema(s)
+	if s == nil || depth > maxRefDepth {
+		return nil
+	}
+	fail := func(format string, args ...interface{}) []ValidationError {
+		return []ValidationError{{Location: loc, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if v == nil {
+		if s.Nullable || s.Type.allows("null") || len(s.Type) == 0 {
+			return nil
+		}
+		return fail("must not be null")
+	}
+
+	var errs []ValidationError
+	for _, sub := range s.AllOf {
+		errs = append(errs, spec.validate(sub, v, loc, depth+1)...)
+	}
+	if alts := append(append([]*Schema{}, s.OneOf...), s.AnyOf...); len(alts) > 0 {
+		matched := false
+		for _, sub := range alts {
+			if len(spec.validate(sub, v, loc, depth+1)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fail("does not match any allowed schema")...)
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fail("must be one of %v", s.Enum)...)
+		}
+	}
+
+	switch s.Type.Primary() {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return append(errs, fail("expected string, got %s", jsonType(v))...)
+		}
+		if s.MinLength != nil && len(str) < *s.MinLength {
+			errs = append(errs, fail("length must be >= %d", *s.MinLength)...)
+		}
+		if s.MaxLength != nil && len(str) > *s.MaxLength {
+			errs = append(errs, fail("length must be <= %d", *s.MaxLength)...)
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(str) {
+				errs = append(errs, fail("must match pattern %s", s.Pattern)...)
+			}
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			return append(errs, fail("expected %s, got %s", s.Type.Primary(), jsonType(v))...)
+		}
+		if s.Type.Primary() == "integer" && n != math.Trunc(n) {
+			errs = append(errs, fail("expected integer, got %v", n)...)
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			errs = append(errs, fail("must be >= %v", *s.Minimum)...)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			errs = append(errs, fail("must be <= %v", *s.Maximum)...)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return append(errs, fail("expected boolean, got %s", jsonType(v))...)
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return append(errs, fail("expected array, got %s", jsonType(v))...)
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			errs = append(errs, fail("must have at least %d items", *s.MinItems)...)
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			errs = append(errs, fail("must have at most %d items", *s.MaxItems)...)
+		}
+		for i, item := range items {
+			errs = append(errs, spec.validate(s.Items, item, fmt.Sprintf("%s[%d]", loc, i), depth+1)...)
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return append(errs, fail("expected object, got %s", jsonType(v))...)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, ValidationError{Location: loc + "." + name, Message: "required property is missing"})
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if allowed, isBool := s.AdditionalProperties.(bool); isBool && !allowed {
+					errs = append(errs, ValidationError{Location: loc + "." + name, Message: "unknown property"})
+				}
+				continue
+			}
+			errs = append(errs, spec.validate(prop, obj[name], loc+"."+name, depth+1)...)
+		}
+	}
+
+	return errs
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -1,27 +1,62 @@
 package mock
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// AdminPrefix is reserved for control routes such as resetting resource state.
+const AdminPrefix = "/__nexus"
+
 type Server struct {
 	endpoints map[string]*Endpoint
+	resources []*Resource
+	faults    *FaultProfile
+	scenarios map[string]*Scenario
+	journal   *Journal
+	nextID    int
+	noAdmin   bool
 	mu        sync.RWMutex
+
+	// sequences tracks progress through Endpoint.Sequence for endpoints
+	// outside a scenario.
+	sequences map[string]int
+	seqMu     sync.Mutex
+
+	callbackCtx   context.Context
+	stopCallbacks context.CancelFunc
+	callbacks     sync.WaitGroup
 }
 
 type Endpoint struct {
+	// ID is assigned by the server and used by the admin API.
+	ID       string
 	Path     string
 	Method   string
 	Response Response
 	Matcher  *Matcher
 	Delay    time.Duration
+	// Faults overrides the server-wide fault profile for this endpoint.
+	Faults *FaultProfile
+	// Handler, when set, produces the response instead of Response.
+	Handler http.Handler
+	// Sequence, when set, is served in order instead of Response; the last
+	// entry repeats.
+	Sequence  []Response
+	Scenario  *ScenarioBinding
+	Callbacks []*Callback
+	// GraphQL is the config Handler was built from, kept for export.
+	GraphQL *GraphQLConfig
 }
 
 type Response struct {
@@ -36,87 +71,395 @@ type Matcher struct {
 }
 
 func NewServer() *Server {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
-		endpoints: make(map[string]*Endpoint),
+		endpoints:     make(map[string]*Endpoint),
+		scenarios:     make(map[string]*Scenario),
+		journal:       NewJournal(DefaultJournalSize),
+		sequences:     make(map[string]int),
+		callbackCtx:   ctx,
+		stopCallbacks: cancel,
 	}
 }
 
+// AddEndpoint registers e, replacing any endpoint with the same method, path
+// and scenario state. The replaced endpoint's ID is kept.
 func (s *Server) AddEndpoint(e *Endpoint) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	key := fmt.Sprintf("%s:%s", e.Method, e.Path)
+	s.addEndpoint(e)
+}
+
+func (s *Server) addEndpoint(e *Endpoint) {
+	key := endpointKey(e)
+	if existing, ok := s.endpoints[key]; ok && e.ID == "" {
+		e.ID = existing.ID
+	}
+	if e.ID == "" {
+		e.ID = s.newID()
+	}
 	s.endpoints[key] = e
+
+	if e.Scenario != nil && s.scenarios[e.Scenario.Name] == nil {
+		s.scenarios[e.Scenario.Name] = NewScenario(e.Scenario.Name)
+	}
+}
+
+func endpointKey(e *Endpoint) string {
+	key := fmt.Sprintf("%s:%s", e.Method, e.Path)
+	if e.Scenario != nil {
+		key += fmt.Sprintf("#%s:%s", e.Scenario.Name, e.Scenario.State)
+	}
+	return key
+}
+
+func idLess(a, b string) bool {
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	return x < y
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
 }
 
+// RemoveEndpoint removes the endpoints for method and path, including every
+// scenario-bound variant.
 func (s *Server) RemoveEndpoint(method, path string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	key := fmt.Sprintf("%s:%s", method, path)
-	delete(s.endpoints, key)
+	for key, e := range s.endpoints {
+		if e.Method == method && e.Path == path {
+			delete(s.endpoints, key)
+		}
+	}
 }
 
-func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// AddResource mounts a stateful resource, replacing any resource already
+// registered at the same path.
+func (s *Server) AddResource(res *Resource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addResource(res)
+}
+
+func (s *Server) addResource(res *Resource) {
+	for i, existing := range s.resources {
+		if existing.Path == res.Path {
+			if res.ID == "" {
+				res.ID = existing.ID
+			}
+			s.resources[i] = res
+			return
+		}
+	}
+	if res.ID == "" {
+		res.ID = s.newID()
+	}
+	s.resources = append(s.resources, res)
+}
+
+// SetAdmin enables or disables the admin routes under AdminPrefix. When
+// disabled those paths are matched like any other request; AdminHandler
+// still serves them.
+func (s *Server) SetAdmin(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.noAdmin = !enabled
+}
+
+// Journal returns the log of requests received by the server.
+func (s *Server) Journal() *Journal {
+	return s.journal
+}
+
+// Reset restores every resource to its seed data, returns scenarios and
+// sequences to their start and clears the journal.
+func (s *Server) Reset() {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	for _, res := range s.resources {
+		res.Reset()
+	}
+	for _, sc := range s.scenarios {
+		sc.ResetAll()
+	}
+	s.seqMu.Lock()
+	s.sequences = make(map[string]int)
+	s.seqMu.Unlock()
+	s.journal.Reset()
+}
+
+// SetFaults installs a fault profile applied to every endpoint and resource
+// that does not define its own. Pass nil to disable.
+func (s *Server) SetFaults(f *FaultProfile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = f
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	admin := !s.noAdmin
+	s.mu.RUnlock()
+	if route, ok := strings.CutPrefix(r.URL.Path, AdminPrefix); ok && admin {
+		s.serveAdmin(w, r, route)
+		return
+	}
+
+	start := time.Now()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("read body: %v", err))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	entry := newJournalEntry(r, body)
+
+	// Only the lookup happens under the lock so that delays and slow
+	// clients never hold up other requests or endpoint updates.
+	s.mu.RLock()
 	endpoint := s.findEndpoint(r)
+	var res *Resource
+	var scenario *Scenario
 	if endpoint == nil {
+		res = s.findResource(r.URL.Path)
+	} else {
+		scenario = s.scenarioOf(endpoint)
+	}
+	if endpoint == nil && res == nil {
+		entry.NearMisses = s.nearMisses(r, body)
+	}
+	faults := s.faults
+	s.mu.RUnlock()
+
+	record := func(status int) JournalEntry {
+		entry.Status = status
+		entry.Duration = time.Since(start)
+		return s.journal.Record(entry)
+	}
+
+	if endpoint == nil && res == nil {
+		record(http.StatusNotFound)
 		http.NotFound(w, r)
 		return
 	}
 
-	if endpoint.Delay > 0 {
-		time.Sleep(endpoint.Delay)
+	if endpoint != nil {
+		entry.Matched = endpoint.Method + " " + endpoint.Path
+	} else {
+		entry.Matched = "resource " + res.Path
 	}
 
-	for k, v := range endpoint.Response.Headers {
-		w.Header().Set(k, v)
+	var delay time.Duration
+	if endpoint != nil {
+		delay = endpoint.Delay
+		if endpoint.Faults != nil {
+			faults = endpoint.Faults
+		}
+	} else if res.Faults != nil {
+		faults = res.Faults
 	}
 
-	w.WriteHeader(endpoint.Response.StatusCode)
+	if ok, retryAfter := faults.allow(); !ok {
+		record(http.StatusTooManyRequests)
+		writeRateLimited(w, retryAfter)
+		return
+	}
+	if !sleepContext(r.Context(), delay+faults.delay()) {
+		record(0)
+		return
+	}
 
-	switch body := endpoint.Response.Body.(type) {
+	// Sequences and scenarios only move for admitted requests. If another
+	// request changed the scenario since the lookup, match again.
+	var response Response
+	for endpoint != nil {
+		var ok bool
+		if response, ok = s.respond(endpoint, scenario, r); ok {
+			break
+		}
+		s.mu.RLock()
+		if endpoint = s.findEndpoint(r); endpoint != nil {
+			scenario = s.scenarioOf(endpoint)
+			entry.Matched = endpoint.Method + " " + endpoint.Path
+		}
+		s.mu.RUnlock()
+		if endpoint == nil {
+			record(http.StatusNotFound)
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	resp := newBufferedResponse()
+	switch {
+	case res != nil:
+		res.ServeHTTP(resp, r)
+	case endpoint.Handler != nil:
+		endpoint.Handler.ServeHTTP(resp, r)
+	default:
+		writeBody(resp, response.StatusCode, response.Headers, response.Body)
+	}
+
+	// Record before writing so a client that has the response always finds
+	// the request in the journal; faults may still change the status.
+	status := resp.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	recorded := record(status)
+	status = faults.write(w, r, resp)
+	s.journal.finish(recorded.ID, status, time.Since(start))
+	if endpoint != nil && len(endpoint.Callbacks) > 0 && status != 0 {
+		s.fireCallbacks(endpoint, recorded.ID, newTemplateData(endpoint, r, body, resp))
+	}
+}
+
+// writeBody writes a static response. Bodies other than strings and bytes
+// are encoded as JSON.
+func writeBody(w http.ResponseWriter, status int, headers map[string]string, body interface{}) {
+	var data []byte
+	switch b := body.(type) {
+	case nil:
 	case string:
-		w.Write([]byte(body))
+		data = []byte(b)
 	case []byte:
-		w.Write(body)
+		data = b
 	default:
-		data, err := json.Marshal(body)
+		var err error
+		data, err = json.Marshal(normalizeYAML(b))
 		if err != nil {
 			slog.Error("marshal response", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
-		w.Write(data)
 	}
+
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(data)
 }
 
-func (s *Server) findEndpoint(r *http.Request) *Endpoint {
-	key := fmt.Sprintf("%s:%s", r.Method, r.URL.Path)
-	if endpoint, ok := s.endpoints[key]; ok {
-		if endpoint.Matcher == nil || s.matchesRequest(endpoint.Matcher, r) {
-			return endpoint
-		}
+// bufferedResponse captures a handler's output so faults can be applied
+// before anything reaches the client.
+type bufferedResponse struct {
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header)}
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
 	}
+}
 
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) writeTo(w http.ResponseWriter) int {
+	for k, vs := range b.header {
+		w.Header()[k] = vs
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+	return b.status
+}
+
+// findEndpoint returns the most specific endpoint matching r: one waiting
+// for the current scenario state beats one that is not, and an exact path
+// beats a pattern. Ties go to the endpoint added first.
+func (s *Server) findEndpoint(r *http.Request) *Endpoint {
+	var best *Endpoint
+	bestRank := -1
 	for _, endpoint := range s.endpoints {
-		if endpoint.Method != r.Method {
+		if endpoint.Method != r.Method || !s.pathMatches(endpoint.Path, r.URL.Path) {
+			continue
+		}
+		if endpoint.Matcher != nil && !s.matchesRequest(endpoint.Matcher, r) {
+			continue
+		}
+		if !s.inState(endpoint, r) {
 			continue
 		}
 
-		if s.pathMatches(endpoint.Path, r.URL.Path) {
-			if endpoint.Matcher == nil || s.matchesRequest(endpoint.Matcher, r) {
-				return endpoint
-			}
+		rank := 0
+		if endpoint.Scenario != nil && endpoint.Scenario.State != "" {
+			rank += 2
+		}
+		if endpoint.Path == r.URL.Path {
+			rank++
+		}
+		if rank > bestRank || (rank == bestRank && idLess(endpoint.ID, best.ID)) {
+			best, bestRank = endpoint, rank
 		}
 	}
+	return best
+}
 
+func (s *Server) findResource(path string) *Resource {
+	for _, res := range s.resources {
+		if _, ok := res.itemID(path); ok {
+			return res
+		}
+	}
 	return nil
 }
 
+func (s *Server) serveAdmin(w http.ResponseWriter, r *http.Request, route string) {
+	switch {
+	case route == "/reset":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		s.Reset()
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(route, "/requests"):
+		s.handleJournal(w, r, route)
+	case route == "/endpoints" || strings.HasPrefix(route, "/endpoints/"):
+		s.handleEndpoints(w, r, strings.TrimPrefix(strings.TrimPrefix(route, "/endpoints"), "/"))
+	case route == "/config":
+		s.handleConfig(w, r)
+	case route == "/scenarios" || strings.HasPrefix(route, "/scenarios/"):
+		s.handleScenarios(w, r, strings.TrimPrefix(strings.TrimPrefix(route, "/scenarios"), "/"))
+	default:
+		writeError(w, http.StatusNotFound, "unknown admin route")
+	}
+}
+
 func (s *Server) pathMatches(pattern, path string) bool {
+	if strings.Contains(pattern, "{") {
+		_, ok := matchTemplate(pattern, path)
+		return ok
+	}
 	if strings.Contains(pattern, "*") {
 		re := regexp.MustCompile("^" + strings.ReplaceAll(pattern, "*", ".*") + "$")
 		return re.MatchString(path)
@@ -124,6 +467,31 @@ func (s *Server) pathMatches(pattern, path string) bool {
 	return pattern == path
 }
 
+// matchTemplate matches path against a pattern with {name} segments and
+// returns the captured values.
+func matchTemplate(pattern, path string) (map[string]string, bool) {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params[part[1:len(part)-1]] = pathParts[i]
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
 func (s *Server) matchesRequest(matcher *Matcher, r *http.Request) bool {
 	for header, re := range matcher.HeaderMatchers {
 		value := r.Header.Get(header)
@@ -132,6 +500,17 @@ func (s *Server) matchesRequest(matcher *Matcher, r *http.Request) bool {
 		}
 	}
 
+	if matcher.BodyMatcher != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if !matcher.BodyMatcher.Match(body) {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -1,11 +1,14 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type GitRepository struct {
@@ -27,24 +30,15 @@ func (r *GitRepository) Init() error {
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("git init: %w", err)
 	}
-
-	gitignore := filepath.Join(r.path, ".gitignore")
-	content := `.nexus/
-.env
-*.log
-secrets/
-`
-	if err := os.WriteFile(gitignore, []byte(content), 0644); err != nil {
-		return fmt.Errorf("create gitignore: %w", err)
-	}
-
 	return nil
 }
 
+// IsRepo reports whether the path is inside a git work tree, at any depth.
 func (r *GitRepository) IsRepo() bool {
-	gitDir := filepath.Join(r.path, ".git")
-	info, err := os.Stat(gitDir)
-	return err == nil && info.IsDir()
+	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(output)) == "true"
 }
 
 func (r *GitRepository) Status() (string, error) {
@@ -76,15 +70,31 @@ func (r *GitRepository) Commit(message string) error {
 	return nil
 }
 
+const logFormat = "--pretty=format:%H|%an|%ae|%at|%s"
+
 func (r *GitRepository) Log(n int) ([]CommitInfo, error) {
-	format := "--pretty=format:%H|%an|%ae|%at|%s"
-	cmd := exec.Command("git", "log", fmt.Sprintf("-%d", n), format)
+	cmd := exec.Command("git", "log", fmt.Sprintf("-%d", n), logFormat)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	return parseLog(output), nil
+}
+
+// FileLog returns the last n commits that touched path, following it
+// across renames. path is relative to the repository's directory.
+func (r *GitRepository) FileLog(path string, n int) ([]CommitInfo, error) {
+	cmd := exec.Command("git", "log", fmt.Sprintf("-%d", n), "--follow", logFormat, "--", path)
 	cmd.Dir = r.path
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("git log: %w", err)
 	}
+	return parseLog(output), nil
+}
 
+func parseLog(output []byte) []CommitInfo {
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
 	commits := make([]CommitInfo, 0, len(lines))
 
@@ -92,19 +102,45 @@ func (r *GitRepository) Log(n int) ([]CommitInfo, error) {
 		if line == "" {
 			continue
 		}
-		parts := strings.Split(line, "|")
+		parts := strings.SplitN(line, "|", 5)
 		if len(parts) < 5 {
 			continue
 		}
-		commits = append(commits, CommitInfo{
+		commit := CommitInfo{
 			Hash:    parts[0],
 			Author:  parts[1],
 			Email:   parts[2],
 			Message: parts[4],
-		})
+		}
+		if sec, err := strconv.ParseInt(parts[3], 10, 64); err == nil {
+			commit.Date = time.Unix(sec, 0)
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits
+}
+
+// ErrUnknownRevision is returned for a revision git cannot resolve.
+var ErrUnknownRevision = errors.New("unknown revision")
+
+// Show returns path as it was at rev, or an error wrapping fs.ErrNotExist
+// if it did not exist then. path is relative to the repository's
+// directory.
+func (r *GitRepository) Show(rev, path string) ([]byte, error) {
+	verify := exec.Command("git", "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	verify.Dir = r.path
+	if err := verify.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRevision, rev)
 	}
 
-	return commits, nil
+	cmd := exec.Command("git", "show", rev+":./"+path)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s at %s: %w", path, rev, fs.ErrNotExist)
+	}
+	return output, nil
 }
 
 func (r *GitRepository) Diff(ref1, ref2 string) (string, error) {
@@ -164,8 +200,9 @@ func (r *GitRepository) Push() error {
 }
 
 type CommitInfo struct {
-	Hash    string
-	Author  string
-	Email   string
-	Message string
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
 }
@@ -0,0 +1,122 @@
+package storage_test
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/storage"
+)
+
+const usersV2 = `name: Users
+baseUrl: https://api.example.com
+requests:
+  - name: fetchUsers
+    method: get
+    url: "{{baseUrl}}/users"
+  - name: createUser
+    method: POST
+    url: "{{baseUrl}}/v2/users"
+    folder: admin
+    headers:
+      Content-Type: application/json
+    body:
+      user: {name: Ann, roles: [admin, dev]}
+    assertions:
+      - status == 201
+  - name: deleteUser
+    method: DELETE
+    url: "{{baseUrl}}/users/1"
+`
+
+func gitCommit(t *testing.T, root, message string) {
+	t.Helper()
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", message}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestCollectionHistoryAndDiff(t *testing.T) {
+	requireGit(t)
+	root := t.TempDir()
+	if err := storage.Init(root, true); err != nil {
+		t.Fatal(err)
+	}
+	// The workspace is a subdirectory of the git work tree.
+	ws := filepath.Join(root, "api")
+	writeFiles(t, ws, map[string]string{
+		"users.yaml":  usersCollection + "    body: {user: {name: Bob, roles: [admin]}}\n    assertions: [status == 200]\n",
+		"orders.yaml": "name: Orders\nrequests: []\n",
+	})
+	gitCommit(t, root, "Add collections")
+	writeFiles(t, ws, map[string]string{"users.yaml": usersV2})
+	gitCommit(t, root, "Version users API")
+	writeFiles(t, ws, map[string]string{"orders.yaml": "name: Orders\nrequests:\n  - {name: getOrder, url: /orders/1}\n"})
+
+	repo, err := storage.NewRepository(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := repo.CollectionHistory("Users", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Message != "Version users API" || history[1].Date.IsZero() {
+		t.Errorf("history = %+v", history)
+	}
+	if history, _ := repo.CollectionHistory("orders.yaml", 10); len(history) != 1 {
+		t.Errorf("orders history = %+v", history)
+	}
+
+	diff, err := repo.DiffCollection("users", "HEAD~1", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := diff.String()
+	for _, want := range []string{
+		"+ baseUrl: https://api.example.com",
+		"Requests: 1 added, 0 removed, 1 renamed, 1 changed",
+		"+ deleteUser",
+		"→ listUsers renamed to fetchUsers",
+		"~ url: {{baseUrl}}/users → {{baseUrl}}/v2/users",
+		"+ header Content-Type: application/json",
+		"~ body.user.name: Bob → Ann",
+		`~ body.user.roles: ["admin"] → ["admin","dev"]`,
+		"- assertion: status == 200",
+		"+ assertion: status == 201",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("diff lacks %q:\n%s", want, got)
+		}
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "createUser" {
+		t.Errorf("changed = %+v", diff.Changed)
+	}
+
+	// Without a second revision the working copy is compared.
+	diff, err = repo.DiffCollection("Orders", "HEAD", "")
+	if err != nil || len(diff.Added) != 1 || diff.Added[0] != "getOrder" || len(diff.Changed) != 0 {
+		t.Errorf("working copy diff = %+v, %v", diff, err)
+	}
+	if diff, err := repo.DiffCollection("users.yaml", "HEAD", ""); err != nil || !diff.Empty() {
+		t.Errorf("unchanged diff = %v, %v", diff, err)
+	}
+	// A revision before the file existed counts as empty.
+	writeFiles(t, ws, map[string]string{"new.yaml": usersCollection})
+	if diff, err := repo.DiffCollection("new.yaml", "HEAD", ""); err != nil || len(diff.Added) != 2 {
+		t.Errorf("new collection diff = %+v, %v", diff, err)
+	}
+
+	if _, err := repo.DiffCollection("users", "no-such-rev", ""); !errors.Is(err, storage.ErrUnknownRevision) {
+		t.Errorf("unknown revision: %v", err)
+	}
+	if _, err := repo.CollectionHistory("Missing", 10); !errors.Is(err, storage.ErrCollectionNotFound) {
+		t.Errorf("missing collection: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IndexFile caches what discovery found, relative to the workspace root.
+const IndexFile = ".nexus/index.json"
+
+// CollectionInfo is the index entry of one collection file.
+type CollectionInfo struct {
+	// Path is slash-separated and relative to the workspace root.
+	Path     string        `json:"path"`
+	Name     string        `json:"name"`
+	Requests []RequestInfo `json:"requests"`
+}
+
+type RequestInfo struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Folder string `json:"folder,omitempty"`
+}
+
+// indexEntry is a file as it was when last read. Collection is nil for
+// files that turned out not to hold one.
+type indexEntry struct {
+	ModTime    time.Time       `json:"modTime"`
+	Size       int64           `json:"size"`
+	Collection *CollectionInfo `json:"collection,omitempty"`
+}
+
+type indexFile struct {
+	Files map[string]*indexEntry `json:"files"`
+}
+
+// Index discovers the collections under the workspace root, reading only
+// files that changed since the cached index was written, and returns them
+// sorted by path.
+func (r *Repository) Index() ([]CollectionInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := LoadWorkspaceConfig(r.store)
+	if err != nil {
+		return nil, err
+	}
+	cached, onDisk := r.readIndex()
+	files := map[string]*indexEntry{}
+	changed := false
+	err = cfg.walk(r.store, func(rel string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if e := cached.Files[rel]; e != nil && e.ModTime.Equal(info.ModTime()) && e.Size == info.Size() {
+			files[rel] = e
+			return nil
+		}
+		e := &indexEntry{ModTime: info.ModTime(), Size: info.Size()}
+		// An unreadable file is left out rather than failing discovery.
+		if data, err := fs.ReadFile(r.store, rel); err == nil {
+			e.Collection = sniffCollection(rel, data)
+		}
+		files[rel], changed = e, true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("discover collections: %w", err)
+	}
+	if changed || len(files) != len(cached.Files) || !onDisk {
+		r.writeIndex(indexFile{Files: files})
+	}
+
+	var collections []CollectionInfo
+	for _, e := range files {
+		if e.Collection != nil {
+			collections = append(collections, *e.Collection)
+		}
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Path < collections[j].Path })
+	return collections, nil
+}
+
+// readIndex returns the cached index, from IndexFile if there is one, and
+// whether it was.
+func (r *Repository) readIndex() (indexFile, bool) {
+	data, err := fs.ReadFile(r.store, IndexFile)
+	if err != nil {
+		return r.index, false
+	}
+	var idx indexFile
+	// A corrupt index is rebuilt.
+	_ = json.Unmarshal(data, &idx)
+	return idx, true
+}
+
+// writeIndex caches the index in memory, and in IndexFile if the workspace
+// has been initialized; discovery never creates .nexus itself. Writing is
+// best-effort: a read-only workspace still gets discovery, just without
+// the cache file.
+func (r *Repository) writeIndex(idx indexFile) {
+	r.index = idx
+	if info, err := fs.Stat(r.store, path.Dir(IndexFile)); err != nil || !info.IsDir() {
+		return
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return
+	}
+	_ = r.store.WriteFile(IndexFile, data)
+}
+
+// sniffCollection returns the index entry of a file if its content looks
+// like a collection: a map with a requests list whose entries all have a
+// method or url. Other YAML and JSON files, such as mock configs and load
+// profiles, return nil.
+func sniffCollection(rel string, data []byte) *CollectionInfo {
+	// JSON is YAML, so one parser reads both.
+	var doc struct {
+		Name     string      `yaml:"name"`
+		Requests []yaml.Node `yaml:"requests"`
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	if !hasKey(root.Content[0], "requests") || root.Content[0].Decode(&doc) != nil {
+		return nil
+	}
+	info := &CollectionInfo{Path: rel, Name: doc.Name, Requests: []RequestInfo{}}
+	for _, n := range doc.Requests {
+		var req struct {
+			Name   string `yaml:"name"`
+			Method string `yaml:"method"`
+			URL    string `yaml:"url"`
+			Folder string `yaml:"folder"`
+		}
+		if n.Kind != yaml.MappingNode || n.Decode(&req) != nil || req.Method == "" && req.URL == "" {
+			return nil
+		}
+		info.Requests = append(info.Requests, RequestInfo{Name: req.Name, Method: strings.ToUpper(req.Method), URL: req.URL, Folder: req.Folder})
+	}
+	if info.Name == "" {
+		info.Name = strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	}
+	return info
+}
+
+func hasKey(m *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// gitignoreEntries keep local state and secrets out of a workspace's git
+// history. The workspace config itself is meant to be committed.
+var gitignoreEntries = []string{IndexFile, IndexFile + ".tmp", ".env", "*.log", "secrets/"}
+
+const workspaceTemplate = `# Which files under this directory hold collections. Globs are relative
+# to this directory; ** spans directories.
+include:
+  - "*.yaml"
+  - "*.yml"
+  - "*.json"
+exclude:
+  - node_modules
+`
+
+// Init sets up a workspace at root: it creates root and WorkspaceFile if
+// they are missing and adds Nexus's entries to root's .gitignore, keeping
+// what is there. With git, it also runs git init unless root is already
+// inside a work tree. Running it again changes nothing.
+func Init(root string, git bool) error {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	store, err := NewFSStorage(root)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fs.Stat(store, WorkspaceFile); errors.Is(err, fs.ErrNotExist) {
+		if err := store.WriteFile(WorkspaceFile, []byte(workspaceTemplate)); err != nil {
+			return fmt.Errorf("create workspace config: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("stat workspace config: %w", err)
+	}
+
+	if err := mergeGitignore(store, gitignoreEntries); err != nil {
+		return fmt.Errorf("update .gitignore: %w", err)
+	}
+
+	if git {
+		repo, err := NewGitRepository(store.Root())
+		if err != nil {
+			return err
+		}
+		if !repo.IsRepo() {
+			if err := repo.Init(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeGitignore appends the entries missing from the store's .gitignore,
+// creating it if need be.
+func mergeGitignore(store Storage, entries []string) error {
+	data, err := fs.ReadFile(store, ".gitignore")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	have := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		have[strings.TrimSpace(line)] = true
+	}
+	var missing []string
+	for _, e := range entries {
+		// An anchored "/x", or "x" for "x/", already covers the entry.
+		if !have[e] && !have["/"+e] && !have[strings.TrimSuffix(e, "/")] {
+			missing = append(missing, e)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	if len(data) > 0 {
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("# Nexus\n")
+	for _, e := range missing {
+		buf.WriteString(e + "\n")
+	}
+	return store.WriteFile(".gitignore", buf.Bytes())
+}
@@ -1,94 +1,152 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/nexusapi/nexus/pkg/collection"
 )
 
+// Repository is a workspace of collections kept in a Storage.
 type Repository struct {
-	basePath string
-	git      *GitRepository
-	parser   *collection.Parser
+	store  Storage
+	parser *collection.Parser
+	// mu guards the index file and index.
+	mu sync.Mutex
+	// index caches discovery in memory for workspaces without a .nexus
+	// directory to keep IndexFile in.
+	index indexFile
 }
 
+// NewRepository opens the workspace at basePath with OpenStorage. It does
+// not create or initialize anything; see Init.
 func NewRepository(basePath string) (*Repository, error) {
-	absPath, err := filepath.Abs(basePath)
+	store, err := OpenStorage(basePath)
 	if err != nil {
-		return nil, fmt.Errorf("get absolute path: %w", err)
+		return nil, err
 	}
+	return NewRepositoryWithStorage(store), nil
+}
 
-	if err := os.MkdirAll(absPath, 0755); err != nil {
-		return nil, fmt.Errorf("create directory: %w", err)
+func NewRepositoryWithStorage(store Storage) *Repository {
+	return &Repository{
+		store:  store,
+		parser: collection.NewParser(),
 	}
+}
+
+func (r *Repository) Storage() Storage {
+	return r.store
+}
 
-	git, err := NewGitRepository(absPath)
+// ErrCollectionNotFound is returned for a collection that is not in the
+// workspace.
+var ErrCollectionNotFound = errors.New("collection not found")
+
+// LoadCollection loads a collection by its path, with or without the .yaml
+// extension, or else by the name of a collection found in the workspace.
+func (r *Repository) LoadCollection(name string) (*collection.Collection, error) {
+	path, err := r.resolve(name)
 	if err != nil {
-		return nil, fmt.Errorf("init git: %w", err)
+		return nil, err
 	}
 
-	if !git.IsRepo() {
-		if err := git.Init(); err != nil {
-			return nil, fmt.Errorf("git init: %w", err)
-		}
+	data, err := fs.ReadFile(r.store, path)
+	if err != nil {
+		return nil, fmt.Errorf("read collection: %w", err)
 	}
+	return r.parser.Parse(path, data)
+}
 
-	return &Repository{
-		basePath: absPath,
-		git:      git,
-		parser:   collection.NewParser(),
-	}, nil
+// resolve returns the file of the collection LoadCollection would load.
+func (r *Repository) resolve(name string) (string, error) {
+	path := cleanName(name)
+	if _, err := fs.Stat(r.store, path); err == nil {
+		return path, nil
+	}
+	if _, err := fs.Stat(r.store, path+".yaml"); err == nil {
+		return path + ".yaml", nil
+	}
+	return r.findCollection(name)
 }
 
-func (r *Repository) LoadCollection(name string) (*collection.Collection, error) {
-	path := filepath.Join(r.basePath, name)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		path = filepath.Join(r.basePath, name+".yaml")
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			return nil, fmt.Errorf("collection not found: %s", name)
+// cleanName turns a relative OS path into a Storage name.
+func cleanName(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+func (r *Repository) findCollection(name string) (string, error) {
+	collections, err := r.Index()
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for _, c := range collections {
+		if c.Name == name {
+			found = append(found, c.Path)
 		}
 	}
-
-	return r.parser.ParseFile(path)
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("collection name %q is ambiguous: %s", name, strings.Join(found, ", "))
 }
 
 func (r *Repository) SaveCollection(coll *collection.Collection, name string) error {
-	path := filepath.Join(r.basePath, name+".yaml")
-	return r.parser.SaveFile(coll, path)
+	path := cleanName(name) + ".yaml"
+	data, err := r.parser.Marshal(coll, path)
+	if err != nil {
+		return err
+	}
+	return r.store.WriteFile(path, data)
 }
 
+// ListCollections returns the paths of the collections in the workspace;
+// see Index.
 func (r *Repository) ListCollections() ([]string, error) {
-	entries, err := os.ReadDir(r.basePath)
+	index, err := r.Index()
 	if err != nil {
-		return nil, fmt.Errorf("read directory: %w", err)
+		return nil, err
 	}
 
 	collections := []string{}
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		ext := filepath.Ext(entry.Name())
-		if ext == ".yaml" || ext == ".yml" || ext == ".json" {
-			collections = append(collections, entry.Name())
-		}
+	for _, c := range index {
+		collections = append(collections, c.Path)
 	}
 
 	return collections, nil
 }
 
+// git returns the repository's version control, or ErrNotVersioned.
+func (r *Repository) git() (*GitRepository, error) {
+	if v, ok := r.store.(Versioned); ok {
+		return v.Git(), nil
+	}
+	return nil, ErrNotVersioned
+}
+
 func (r *Repository) Commit(message string, files ...string) error {
 	if len(files) == 0 {
 		return fmt.Errorf("no files to commit")
 	}
+	git, err := r.git()
+	if err != nil {
+		return err
+	}
 
-	if err := r.git.Add(files...); err != nil {
+	if err := git.Add(files...); err != nil {
 		return fmt.Errorf("git add: %w", err)
 	}
 
-	if err := r.git.Commit(message); err != nil {
+	if err := git.Commit(message); err != nil {
 		return fmt.Errorf("git commit: %w", err)
 	}
 
@@ -96,26 +154,121 @@ func (r *Repository) Commit(message string, files ...string) error {
 }
 
 func (r *Repository) History(n int) ([]CommitInfo, error) {
-	return r.git.Log(n)
+	git, err := r.git()
+	if err != nil {
+		return nil, err
+	}
+	return git.Log(n)
+}
+
+// CollectionHistory returns the last n commits that changed a collection,
+// newest first. The collection is found as by LoadCollection, or may be a
+// path that only exists in history.
+func (r *Repository) CollectionHistory(name string, n int) ([]CommitInfo, error) {
+	git, err := r.git()
+	if err != nil {
+		return nil, err
+	}
+	path, err := r.historicPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return git.FileLog(path, n)
+}
+
+// DiffCollection compares a collection at two revisions. An empty
+// revision is the working copy; a revision without the file counts as an
+// empty collection, so everything in the other shows as added or removed.
+func (r *Repository) DiffCollection(name, from, to string) (*collection.Diff, error) {
+	path, err := r.historicPath(name)
+	if err != nil {
+		return nil, err
+	}
+	before, err := r.collectionAt(path, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := r.collectionAt(path, to)
+	if err != nil {
+		return nil, err
+	}
+	return collection.DiffCollections(before, after), nil
+}
+
+// historicPath resolves name like LoadCollection, but lets a collection
+// file that has since been deleted through.
+func (r *Repository) historicPath(name string) (string, error) {
+	path, err := r.resolve(name)
+	if errors.Is(err, ErrCollectionNotFound) && collectionExt(name) {
+		return cleanName(name), nil
+	}
+	return path, err
+}
+
+// collectionAt loads the collection in path at rev, or nil if it did not
+// exist then.
+func (r *Repository) collectionAt(path, rev string) (*collection.Collection, error) {
+	var data []byte
+	var err error
+	if rev == "" {
+		data, err = fs.ReadFile(r.store, path)
+	} else {
+		var git *GitRepository
+		if git, err = r.git(); err != nil {
+			return nil, err
+		}
+		data, err = git.Show(rev, path)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	coll, err := r.parser.Parse(path, data)
+	if err != nil {
+		if rev == "" {
+			rev = "working copy"
+		}
+		return nil, fmt.Errorf("%s at %s: %w", path, rev, err)
+	}
+	return coll, nil
 }
 
 func (r *Repository) CurrentBranch() (string, error) {
-	return r.git.Branch()
+	git, err := r.git()
+	if err != nil {
+		return "", err
+	}
+	return git.Branch()
 }
 
 func (r *Repository) SwitchBranch(name string) error {
-	return r.git.Checkout(name)
+	git, err := r.git()
+	if err != nil {
+		return err
+	}
+	return git.Checkout(name)
 }
 
 func (r *Repository) CreateBranch(name string) error {
-	return r.git.CreateBranch(name)
+	git, err := r.git()
+	if err != nil {
+		return err
+	}
+	return git.CreateBranch(name)
 }
 
 func (r *Repository) Sync() error {
-	if err := r.git.Pull(); err != nil {
+	git, err := r.git()
+	if err != nil {
+		return err
+	}
+	if err := git.Pull(); err != nil {
 		return fmt.Errorf("pull: %w", err)
 	}
-	if err := r.git.Push(); err != nil {
+	if err := git.Push(); err != nil {
 		return fmt.Errorf("push: %w", err)
 	}
 	return nil
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchResult is a request matching a search.
+type SearchResult struct {
+	// Collection is the path of the collection holding Request.
+	Collection string      `json:"collection"`
+	Request    RequestInfo `json:"request"`
+	Score      int         `json:"score"`
+}
+
+// Search fuzzy-matches query against the requests of every collection in
+// the workspace and returns the matches, best first. Each word of the
+// query must match, its letters in order though not necessarily adjacent,
+// the request's name, its method and URL, or its folder and collection
+// name.
+func (r *Repository) Search(query string) ([]SearchResult, error) {
+	collections, err := r.Index()
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	var results []SearchResult
+	for _, c := range collections {
+		for _, req := range c.Requests {
+			// Matches in the name count double.
+			fields := []struct {
+				text   string
+				weight int
+			}{
+				{req.Name, 2},
+				{req.Method + " " + req.URL, 1},
+				{req.Folder + " " + c.Name, 1},
+			}
+			total := 0
+			for _, w := range words {
+				best := -1
+				for _, f := range fields {
+					if s := fuzzyScore(w, f.text); s >= 0 {
+						best = max(best, s*f.weight)
+					}
+				}
+				if best < 0 {
+					total = -1
+					break
+				}
+				total += best
+			}
+			if total >= 0 {
+				results = append(results, SearchResult{Collection: c.Path, Request: req, Score: total})
+			}
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return len(results[i].Request.Name) < len(results[j].Request.Name)
+	})
+	return results, nil
+}
+
+// fuzzyScore scores word as a case-insensitive subsequence of s, or
+// returns -1 if it is not one. Runs of adjacent characters and matches at
+// the start of a word, including camelCase humps, score higher.
+func fuzzyScore(word, s string) int {
+	q := []rune(strings.ToLower(word))
+	text := []rune(s)
+	score, qi, run := 0, 0, 0
+	for i := 0; i < len(text) && qi < len(q); i++ {
+		if unicode.ToLower(text[i]) != q[qi] {
+			run = 0
+			continue
+		}
+		points := 1 + 2*run
+		if i == 0 || !isWordRune(text[i-1]) || unicode.IsUpper(text[i]) && unicode.IsLower(text[i-1]) {
+			points += 4
+		}
+		score += points
+		run++
+		qi++
+	}
+	if qi < len(q) {
+		return -1
+	}
+	return score
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+// Storage holds the files of a workspace. Names are slash-separated and
+// relative to the workspace root, as for fs.FS.
+type Storage interface {
+	fs.FS
+	// WriteFile creates or replaces name, and any directories above it.
+	WriteFile(name string, data []byte) error
+	Remove(name string) error
+}
+
+// Versioned is implemented by storage under version control.
+type Versioned interface {
+	Git() *GitRepository
+}
+
+// ErrNotVersioned is returned for history operations on a workspace that
+// is not under git.
+var ErrNotVersioned = errors.New("workspace is not under git; run nexus init --git")
+
+// OpenStorage opens the workspace at root: git-backed if root is inside a
+// git work tree, plain files otherwise. It never creates anything.
+func OpenStorage(root string) (Storage, error) {
+	s, err := NewFSStorage(root)
+	if err != nil {
+		return nil, err
+	}
+	if git, _ := NewGitRepository(s.root); git.IsRepo() {
+		return &GitStorage{FSStorage: s, git: git}, nil
+	}
+	return s, nil
+}
+
+// FSStorage keeps a workspace as plain files under a directory.
+type FSStorage struct {
+	fs.FS
+	root string
+}
+
+func NewFSStorage(root string) (*FSStorage, error) {
+	absPath, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("get absolute path: %w", err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("open workspace: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("open workspace: %s is not a directory", absPath)
+	}
+	return &FSStorage{FS: os.DirFS(absPath), root: absPath}, nil
+}
+
+// Root is the absolute path of the workspace.
+func (s *FSStorage) Root() string {
+	return s.root
+}
+
+// WriteFile replaces name atomically, so readers never see half a file.
+func (s *FSStorage) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	p := filepath.Join(s.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write file: %w", err)
+	}
+	return nil
+}
+
+func (s *FSStorage) Remove(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Remove(filepath.Join(s.root, filepath.FromSlash(name)))
+}
+
+// GitStorage is an FSStorage inside a git work tree.
+type GitStorage struct {
+	*FSStorage
+	git *GitRepository
+}
+
+// NewGitStorage opens a workspace inside an existing git work tree; see
+// Init to create one.
+func NewGitStorage(root string) (*GitStorage, error) {
+	s, err := NewFSStorage(root)
+	if err != nil {
+		return nil, err
+	}
+	git, err := NewGitRepository(s.root)
+	if err != nil {
+		return nil, err
+	}
+	if !git.IsRepo() {
+		return nil, fmt.Errorf("%s: %w", s.root, ErrNotVersioned)
+	}
+	return &GitStorage{FSStorage: s, git: git}, nil
+}
+
+func (s *GitStorage) Git() *GitRepository {
+	return s.git
+}
+
+// MemoryStorage keeps a workspace in memory, for tests and embedding.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	files fstest.MapFS
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{files: fstest.MapFS{}}
+}
+
+func (s *MemoryStorage) Open(name string) (fs.File, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.files.Open(name)
+}
+
+func (s *MemoryStorage) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if s.files[dir] != nil {
+			return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+		}
+	}
+	// Files are replaced rather than changed, so open ones keep their data.
+	s.files[name] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: 0644, ModTime: time.Now()}
+	return nil
+}
+
+func (s *MemoryStorage) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files[name] == nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(s.files, name)
+	return nil
+}
@@ -0,0 +1,194 @@
+package storage_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nexusapi/nexus/pkg/collection"
+	"github.com/nexusapi/nexus/pkg/storage"
+)
+
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "Nexus Test")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "test@example.com")
+	}
+}
+
+func TestNewRepositoryChangesNothing(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{".gitignore": "bin/\n"})
+
+	repo, err := storage.NewRepository(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.Storage().(storage.Versioned); ok {
+		t.Error("plain directory opened as git storage")
+	}
+	if _, err := os.Stat(filepath.Join(root, ".git")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(".git: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, ".gitignore")); string(data) != "bin/\n" {
+		t.Errorf(".gitignore = %q", data)
+	}
+	if err := repo.Commit("msg", "x.yaml"); !errors.Is(err, storage.ErrNotVersioned) {
+		t.Errorf("Commit() = %v", err)
+	}
+
+	if _, err := storage.NewRepository(filepath.Join(root, "missing")); err == nil {
+		t.Error("missing workspace opened")
+	}
+}
+
+func TestInitMergesGitignore(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":            "bin/\n/.env\nsecrets",
+		".nexus/workspace.yaml": "exclude: [fixtures]\n",
+	})
+	if err := storage.Init(root, false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "bin/\n/.env\nsecrets\n\n# Nexus\n.nexus/index.json\n.nexus/index.json.tmp\n*.log\n"
+	if string(data) != want {
+		t.Errorf(".gitignore = %q, want %q", data, want)
+	}
+	if cfg, _ := os.ReadFile(filepath.Join(root, storage.WorkspaceFile)); string(cfg) != "exclude: [fixtures]\n" {
+		t.Errorf("workspace config overwritten: %q", cfg)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".git")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf(".git: %v", err)
+	}
+
+	// A second run changes nothing.
+	if err := storage.Init(root, false); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(filepath.Join(root, ".gitignore")); string(again) != want {
+		t.Errorf(".gitignore after second init = %q", again)
+	}
+
+	fresh := filepath.Join(t.TempDir(), "new")
+	if err := storage.Init(fresh, false); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := storage.NewRepository(fresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Index(); err != nil {
+		t.Errorf("template workspace config: %v", err)
+	}
+}
+
+func TestGitStorage(t *testing.T) {
+	requireGit(t)
+	root := t.TempDir()
+	if _, err := storage.NewGitStorage(root); !errors.Is(err, storage.ErrNotVersioned) {
+		t.Errorf("NewGitStorage() outside git = %v", err)
+	}
+	if err := storage.Init(root, true); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := storage.NewRepository(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.Storage().(storage.Versioned); !ok {
+		t.Fatalf("storage = %T", repo.Storage())
+	}
+	if err := repo.SaveCollection(&collection.Collection{Name: "Users"}, "users"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Commit("Add users", "users.yaml", ".gitignore"); err != nil {
+		t.Fatal(err)
+	}
+	history, err := repo.History(5)
+	if err != nil || len(history) != 1 || history[0].Message != "Add users" {
+		t.Errorf("History() = %+v, %v", history, err)
+	}
+
+	// A workspace in a subdirectory of a git work tree uses it rather than
+	// starting a repository of its own.
+	sub := filepath.Join(root, "api")
+	if err := storage.Init(sub, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(sub, ".git")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("nested .git: %v", err)
+	}
+	if _, err := storage.NewGitStorage(sub); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	repo := storage.NewRepositoryWithStorage(store)
+	if names, err := repo.ListCollections(); err != nil || len(names) != 0 {
+		t.Fatalf("ListCollections() = %v, %v", names, err)
+	}
+
+	if err := store.WriteFile("api/users.yaml", []byte(usersCollection)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveCollection(&collection.Collection{Name: "Orders", Requests: []collection.Request{{Name: "getOrder", Method: "GET", URL: "/orders/1"}}}, "orders"); err != nil {
+		t.Fatal(err)
+	}
+	names, err := repo.ListCollections()
+	if err != nil || strings.Join(names, " ") != "api/users.yaml orders.yaml" {
+		t.Errorf("ListCollections() = %v, %v", names, err)
+	}
+	if coll, err := repo.LoadCollection("Orders"); err != nil || coll.Requests[0].Name != "getOrder" {
+		t.Errorf("LoadCollection() = %+v, %v", coll, err)
+	}
+	if results, err := repo.Search("getor"); err != nil || len(results) != 1 || results[0].Collection != "orders.yaml" {
+		t.Errorf("Search() = %+v, %v", results, err)
+	}
+	if _, err := fs.Stat(store, storage.IndexFile); err == nil {
+		t.Errorf("index written to an uninitialized workspace")
+	}
+	// Once initialized, the workspace keeps the index.
+	if err := store.WriteFile(storage.WorkspaceFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ListCollections(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(store, storage.IndexFile); err != nil {
+		t.Errorf("index not cached: %v", err)
+	}
+
+	if err := store.Remove("api/users.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := repo.ListCollections(); len(names) != 1 {
+		t.Errorf("after remove: %v", names)
+	}
+	if err := store.WriteFile("orders.yaml/x", nil); err == nil {
+		t.Error("wrote below a file")
+	}
+	if err := store.WriteFile("../x", nil); err == nil {
+		t.Error("wrote outside the store")
+	}
+	if _, err := repo.History(1); !errors.Is(err, storage.ErrNotVersioned) {
+		t.Errorf("History() = %v", err)
+	}
+}
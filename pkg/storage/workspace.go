@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WorkspaceFile configures collection discovery, relative to the
+// workspace root.
+const WorkspaceFile = ".nexus/workspace.yaml"
+
+// WorkspaceConfig chooses which files under the workspace root may hold
+// collections. Patterns are slash-separated globs relative to the root,
+// where ** matches any number of directories; a pattern without a slash
+// matches a file or directory name at any depth, and a pattern matching a
+// directory matches everything in it, as in .gitignore.
+type WorkspaceConfig struct {
+	// Include defaults to every .yaml, .yml and .json file.
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	// Exclude wins over Include; an excluded directory is not walked.
+	// .git, .nexus and node_modules are always skipped.
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+var defaultInclude = []string{"*.yaml", "*.yml", "*.json"}
+
+// skippedDirs are never walked.
+var skippedDirs = map[string]bool{".git": true, ".nexus": true, "node_modules": true}
+
+// LoadWorkspaceConfig reads the store's WorkspaceFile; without one it
+// returns the defaults.
+func LoadWorkspaceConfig(store Storage) (*WorkspaceConfig, error) {
+	cfg := &WorkspaceConfig{}
+	data, err := fs.ReadFile(store, WorkspaceFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		cfg.Include = defaultInclude
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read workspace config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", WorkspaceFile, err)
+	}
+	for _, p := range append(append([]string{}, cfg.Include...), cfg.Exclude...) {
+		if err := checkGlob(p); err != nil {
+			return nil, fmt.Errorf("%s: %w", WorkspaceFile, err)
+		}
+	}
+	if len(cfg.Include) == 0 {
+		cfg.Include = defaultInclude
+	}
+	return cfg, nil
+}
+
+// walk calls fn with the name of every file in the store that the config
+// includes and that may hold a collection.
+func (c *WorkspaceConfig) walk(store Storage, fn func(rel string, d fs.DirEntry) error) error {
+	return fs.WalkDir(store, ".", func(rel string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if d.IsDir() {
+			if skippedDirs[d.Name()] || matchAny(c.Exclude, rel) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !collectionExt(rel) || !matchAny(c.Include, rel) || matchAny(c.Exclude, rel) {
+			return nil
+		}
+		return fn(rel, d)
+	})
+}
+
+func collectionExt(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the slash-separated name matches pattern.
+func matchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		pattern = "**/" + pattern
+	}
+	return matchSegments(strings.Split(strings.TrimSuffix(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	// A matching directory matches everything in it.
+	return true
+}
+
+func checkGlob(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nexusapi/nexus/pkg/storage"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newWorkspace(t *testing.T, files map[string]string) *storage.Repository {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, files)
+	repo, err := storage.NewRepository(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+const usersCollection = `name: Users
+requests:
+  - name: listUsers
+    method: get
+    url: "{{baseUrl}}/users"
+  - name: createUser
+    method: POST
+    url: "{{baseUrl}}/users"
+    folder: admin
+`
+
+func TestDiscovery(t *testing.T) {
+	repo := newWorkspace(t, map[string]string{
+		"users.yaml":                    usersCollection,
+		"api/v2/orders.json":            `{"name": "Orders", "requests": [{"name": "getOrder", "method": "GET", "url": "/orders/1"}]}`,
+		"api/v2/empty.yml":              "name: Empty\nrequests: []\n",
+		"mocks/server.yaml":             "endpoints:\n  - path: /health\n",
+		"load/profile.yaml":             "executor: ramping-vus\nstages: []\n",
+		"data/users.json":               `[{"id": 1}]`,
+		"broken.yaml":                   "requests: [\n",
+		"notes.yaml":                    "requests:\n  - just a string\n",
+		"node_modules/pkg/package.json": `{"name": "x", "requests": [{"url": "/"}]}`,
+		"fixtures/old.yaml":             usersCollection,
+		"scratch/tmp.yaml":              usersCollection,
+		".nexus/workspace.yaml":         "exclude:\n  - fixtures\n  - scratch/*.yaml\n",
+	})
+
+	collections, err := repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range collections {
+		got = append(got, c.Path+"="+c.Name)
+	}
+	want := "api/v2/empty.yml=Empty api/v2/orders.json=Orders users.yaml=Users"
+	if strings.Join(got, " ") != want {
+		t.Errorf("collections = %v, want %s", got, want)
+	}
+	if users := collections[2]; len(users.Requests) != 2 || users.Requests[0].Method != "GET" || users.Requests[1].Folder != "admin" {
+		t.Errorf("users = %+v", users)
+	}
+
+	names, err := repo.ListCollections()
+	if err != nil || len(names) != 3 || names[1] != "api/v2/orders.json" {
+		t.Errorf("ListCollections() = %v, %v", names, err)
+	}
+}
+
+func TestWorkspaceInclude(t *testing.T) {
+	repo := newWorkspace(t, map[string]string{
+		"collections/users.yaml":      usersCollection,
+		"collections/nested/a.yaml":   usersCollection,
+		"other/users.yaml":            usersCollection,
+		".nexus/workspace.yaml":       "include:\n  - collections/**/*.yaml\n",
+		"collections/nested/b.txt":    usersCollection,
+		"collections/nested/c.yml.bk": usersCollection,
+	})
+	names, err := repo.ListCollections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, " ") != "collections/nested/a.yaml collections/users.yaml" {
+		t.Errorf("collections = %v", names)
+	}
+
+	bad := newWorkspace(t, map[string]string{".nexus/workspace.yaml": "include: ['[']\n"})
+	if _, err := bad.Index(); err == nil {
+		t.Error("bad pattern accepted")
+	}
+}
+
+func TestIndexCache(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"users.yaml": usersCollection, "notes.yaml": "title: notes\n"})
+	repo, err := storage.NewRepository(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Outside an initialized workspace the index stays in memory.
+	if collections, err := repo.Index(); err != nil || len(collections) != 1 {
+		t.Fatalf("Index() = %v, %v", collections, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".nexus")); !os.IsNotExist(err) {
+		t.Fatalf("discovery created .nexus in an uninitialized directory: %v", err)
+	}
+
+	if err := os.Mkdir(filepath.Join(root, ".nexus"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Index(); err != nil {
+		t.Fatal(err)
+	}
+	index := filepath.Join(root, storage.IndexFile)
+	cached, err := os.ReadFile(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(cached), "createUser") {
+		t.Errorf("index = %s", cached)
+	}
+
+	// A file that changed is read again; one that did not comes from the
+	// cache, so a stale entry planted there shows through.
+	planted := strings.Replace(string(cached), "listUsers", "cachedName", 1)
+	if err := os.WriteFile(index, []byte(planted), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, root, map[string]string{"orders.yaml": "name: Orders\nrequests:\n  - {name: getOrder, url: /orders/1}\n"})
+	collections, err := repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collections) != 2 || collections[1].Requests[0].Name != "cachedName" {
+		t.Fatalf("collections = %+v", collections)
+	}
+
+	later := time.Now().Add(time.Minute)
+	writeFiles(t, root, map[string]string{"users.yaml": usersCollection + "  - {name: deleteUser, method: DELETE, url: /users/1}\n"})
+	if err := os.Chtimes(filepath.Join(root, "users.yaml"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "orders.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	collections, err = repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collections) != 1 || len(collections[0].Requests) != 3 || collections[0].Requests[0].Name != "listUsers" {
+		t.Errorf("collections = %+v", collections)
+	}
+
+	// A corrupt index is rebuilt.
+	if err := os.WriteFile(index, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if collections, err := repo.Index(); err != nil || len(collections) != 1 {
+		t.Errorf("Index() = %v, %v", collections, err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	repo := newWorkspace(t, map[string]string{
+		"users.yaml":  usersCollection,
+		"orders.yaml": "name: Orders\nrequests:\n  - {name: createOrder, method: POST, url: /orders}\n  - {name: listOrderUsers, method: GET, url: /orders/1/users}\n",
+	})
+
+	search := func(query string) []string {
+		t.Helper()
+		results, err := repo.Search(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, r := range results {
+			names = append(names, r.Request.Name)
+		}
+		return names
+	}
+
+	if got := search("crus"); len(got) != 1 || got[0] != "createUser" {
+		t.Errorf("crus = %v", got)
+	}
+	if got := search("create"); len(got) != 2 {
+		t.Errorf("create = %v", got)
+	}
+	// Equal matches go to the shorter name.
+	if got := search("lu"); len(got) < 2 || got[0] != "listUsers" {
+		t.Errorf("lu = %v", got)
+	}
+	// Words can match the method, URL or collection name too.
+	if got := search("post orders"); len(got) != 1 || got[0] != "createOrder" {
+		t.Errorf("post orders = %v", got)
+	}
+	if got := search("admin"); len(got) != 1 || got[0] != "createUser" {
+		t.Errorf("admin = %v", got)
+	}
+	if got := search("xyz"); len(got) != 0 {
+		t.Errorf("xyz = %v", got)
+	}
+}
+
+func TestLoadCollectionByName(t *testing.T) {
+	repo := newWorkspace(t, map[string]string{
+		"api/users.yaml": usersCollection,
+		"a/dup.yaml":     "name: Dup\nrequests: []\n",
+		"b/dup.yaml":     "name: Dup\nrequests: []\n",
+	})
+	for _, name := range []string{"api/users.yaml", "api/users", "Users"} {
+		coll, err := repo.LoadCollection(name)
+		if err != nil || len(coll.Requests) != 2 {
+			t.Errorf("LoadCollection(%q) = %v, %v", name, coll, err)
+		}
+	}
+	if _, err := repo.LoadCollection("Dup"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Dup: %v", err)
+	}
+	if _, err := repo.LoadCollection("Missing"); err == nil {
+		t.Error("missing collection loaded")
+	}
+}
@@ -0,0 +1,344 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nexusapi/nexus/pkg/load"
+)
+
+// LoadController is the part of a running load test the dashboard drives;
+// *load.Engine implements it.
+type LoadController interface {
+	Progress() load.Progress
+	Pause()
+	Resume()
+	AddVUs(n int) int
+	Stop()
+	Abort()
+}
+
+// LoadFeed is a load.Output that passes each per-second sample to a
+// LoadModel. Samples are dropped rather than holding up the test if the
+// dashboard falls behind.
+type LoadFeed struct {
+	samples   chan load.Sample
+	closeOnce sync.Once
+}
+
+func NewLoadFeed() *LoadFeed {
+	return &LoadFeed{samples: make(chan load.Sample, 16)}
+}
+
+func (f *LoadFeed) Write(s load.Sample) error {
+	select {
+	case f.samples <- s:
+	default:
+	}
+	return nil
+}
+
+// Close ends the feed once the test has stopped writing to it.
+func (f *LoadFeed) Close() error {
+	f.closeOnce.Do(func() { close(f.samples) })
+	return nil
+}
+
+// LoadModel is a live dashboard for a load test. Add its feed to the
+// test's outputs; run starts the test and is called once the program
+// starts, and the feed is closed when it returns.
+type LoadModel struct {
+	title    string
+	engine   LoadController
+	feed     *LoadFeed
+	run      func() (*load.LoadTestResult, error)
+	keys     loadKeyMap
+	width    int
+	progress load.Progress
+	samples  []load.Sample
+	added    int
+	stopping bool
+	quitting bool
+	done     bool
+	result   *load.LoadTestResult
+	err      error
+}
+
+type loadKeyMap struct {
+	Quit   key.Binding
+	Pause  key.Binding
+	AddVU  key.Binding
+	DropVU key.Binding
+	Abort  key.Binding
+}
+
+func defaultLoadKeyMap() loadKeyMap {
+	return loadKeyMap{
+		Quit: key.NewBinding(
+			key.WithKeys("ctrl+c", "q"),
+			key.WithHelp("q", "quit"),
+		),
+		Pause: key.NewBinding(
+			key.WithKeys("p", " "),
+			key.WithHelp("p", "pause/resume"),
+		),
+		AddVU: key.NewBinding(
+			key.WithKeys("+", "="),
+			key.WithHelp("+", "add VU"),
+		),
+		DropVU: key.NewBinding(
+			key.WithKeys("-"),
+			key.WithHelp("-", "remove added VU"),
+		),
+		Abort: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "abort"),
+		),
+	}
+}
+
+// maxSamples is how many seconds the sparklines remember.
+const maxSamples = 300
+
+type loadSampleMsg load.Sample
+
+type loadDoneMsg struct {
+	result *load.LoadTestResult
+	err    error
+}
+
+func NewLoadModel(title string, engine LoadController, feed *LoadFeed, run func() (*load.LoadTestResult, error)) LoadModel {
+	return LoadModel{
+		title:  title,
+		engine: engine,
+		feed:   feed,
+		run:    run,
+		keys:   defaultLoadKeyMap(),
+	}
+}
+
+// Result is the outcome of the test once the program has exited.
+func (m LoadModel) Result() (*load.LoadTestResult, error) {
+	return m.result, m.err
+}
+
+func (m LoadModel) Init() tea.Cmd {
+	run := func() tea.Msg {
+		result, err := m.run()
+		m.feed.Close()
+		return loadDoneMsg{result, err}
+	}
+	return tea.Batch(run, m.nextSample())
+}
+
+func (m LoadModel) nextSample() tea.Cmd {
+	return func() tea.Msg {
+		s, ok := <-m.feed.samples
+		if !ok {
+			return nil
+		}
+		return loadSampleMsg(s)
+	}
+}
+
+func (m LoadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
+	case loadSampleMsg:
+		m.samples = append(m.samples, load.Sample(msg))
+		if len(m.samples) > maxSamples {
+			m.samples = m.samples[len(m.samples)-maxSamples:]
+		}
+		m.progress = m.engine.Progress()
+		if m.done {
+			return m, nil
+		}
+		return m, m.nextSample()
+
+	case loadDoneMsg:
+		m.done, m.result, m.err = true, msg.result, msg.err
+		m.progress = m.engine.Progress()
+		if m.quitting {
+			return m, tea.Quit
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Quit):
+			if m.done {
+				return m, tea.Quit
+			}
+			m.quitting, m.stopping = true, true
+			m.engine.Resume()
+			m.engine.Stop()
+
+		case m.done:
+
+		case key.Matches(msg, m.keys.Pause):
+			if m.progress.Paused {
+				m.engine.Resume()
+			} else {
+				m.engine.Pause()
+			}
+
+		case key.Matches(msg, m.keys.AddVU):
+			m.added = m.engine.AddVUs(1)
+
+		case key.Matches(msg, m.keys.DropVU):
+			m.added = m.engine.AddVUs(-1)
+
+		case key.Matches(msg, m.keys.Abort):
+			m.stopping = true
+			m.engine.Resume()
+			m.engine.Abort()
+		}
+		m.progress = m.engine.Progress()
+	}
+	return m, nil
+}
+
+var (
+	loadTitleStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("170"))
+	loadLabelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	loadChartStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("62"))
+	loadErrStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	loadBoxStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("62")).Padding(0, 1)
+)
+
+func (m LoadModel) View() string {
+	p := m.progress
+	state := "● running"
+	switch {
+	case m.done && m.err != nil:
+		state = loadErrStyle.Render("✗ " + m.err.Error())
+	case m.done:
+		state = "✓ done"
+	case m.stopping:
+		state = "■ stopping"
+	case p.Paused:
+		state = "❚❚ paused"
+	}
+	header := fmt.Sprintf("%s  %s", loadTitleStyle.Render("Load test: "+m.title), state)
+
+	vus := fmt.Sprintf("%d", p.ActiveVUs)
+	if m.added > 0 {
+		vus += fmt.Sprintf(" (+%d)", m.added)
+	}
+	var failRate float64
+	if p.Requests > 0 {
+		failRate = float64(p.Failed) / float64(p.Requests) * 100
+	}
+	totals := fmt.Sprintf("%s %s   %s %s   %s %d   %s %d (%.2f%%)",
+		loadLabelStyle.Render("Elapsed"), p.Elapsed.Truncate(time.Second),
+		loadLabelStyle.Render("VUs"), vus,
+		loadLabelStyle.Render("Requests"), p.Requests,
+		loadLabelStyle.Render("Failed"), p.Failed, failRate)
+	if p.Dropped > 0 {
+		totals += fmt.Sprintf("   %s %d", loadLabelStyle.Render("Dropped"), p.Dropped)
+	}
+
+	width := 60
+	if m.width > 40 {
+		width = min(m.width-30, maxSamples)
+	}
+	recent := m.samples[max(len(m.samples)-width, 0):]
+	series := func(f func(load.Sample) float64) []float64 {
+		out := make([]float64, len(recent))
+		for i, s := range recent {
+			out[i] = f(s)
+		}
+		return out
+	}
+	var last load.Sample
+	if len(recent) > 0 {
+		last = recent[len(recent)-1]
+	}
+	charts := []string{
+		chartLine("RPS", series(func(s load.Sample) float64 { return s.RPS }), fmt.Sprintf("%.1f", last.RPS)),
+		chartLine("p95", series(func(s load.Sample) float64 { return float64(s.P95) }), last.P95.String()),
+		chartLine("VUs", series(func(s load.Sample) float64 { return float64(s.VUs) }), fmt.Sprintf("%d", last.VUs)),
+		chartLine("Errors", series(func(s load.Sample) float64 { return float64(s.Failed) }), fmt.Sprintf("%d", last.Failed)),
+	}
+
+	latency := fmt.Sprintf("%s  p50 %v  p95 %v  p99 %v  max %v",
+		loadLabelStyle.Render("Latency (last second)"), last.P50, last.P95, last.P99, last.Max)
+
+	sections := []string{
+		header,
+		totals,
+		loadBoxStyle.Render(strings.Join(charts, "\n")),
+		latency,
+	}
+	if len(p.Statuses) > 0 {
+		sections = append(sections, m.renderCounts("Responses", p.Statuses))
+	}
+	if len(p.Errors) > 0 {
+		sections = append(sections, m.renderCounts("Errors", p.Errors))
+	}
+	sections = append(sections, m.renderHelp())
+	return strings.Join(sections, "\n\n") + "\n"
+}
+
+func chartLine(label string, values []float64, current string) string {
+	return fmt.Sprintf("%s %s %s", loadLabelStyle.Render(fmt.Sprintf("%-7s", label)), loadChartStyle.Render(sparkline(values)), current)
+}
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values scaled to their maximum.
+func sparkline(values []float64) string {
+	var top float64
+	for _, v := range values {
+		top = max(top, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if top > 0 {
+			i = int(v / top * float64(len(sparkBars)-1))
+		}
+		b.WriteRune(sparkBars[i])
+	}
+	return b.String()
+}
+
+// renderCounts lists counts by name with their share of all requests.
+// Error statuses and error kinds are highlighted.
+func (m LoadModel) renderCounts(title string, counts map[string]int64) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(loadLabelStyle.Render(title))
+	for _, name := range names {
+		n := counts[name]
+		line := fmt.Sprintf("  %-18s %10d  %6.2f%%", name, n, float64(n)/float64(max(m.progress.Requests, 1))*100)
+		if code, err := strconv.Atoi(name); title == "Errors" || name == "error" || err == nil && code >= 400 {
+			line = loadErrStyle.Render(line)
+		}
+		b.WriteString("\n" + line)
+	}
+	return b.String()
+}
+
+func (m LoadModel) renderHelp() string {
+	help := "p: pause/resume | +/-: VUs | a: abort | q: quit"
+	if m.done {
+		help = "q: quit and print the summary"
+	}
+	return loadLabelStyle.Render(help)
+}